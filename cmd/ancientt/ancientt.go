@@ -15,14 +15,21 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/galexrt/ancientt/outputs"
 	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/archive"
+	"github.com/galexrt/ancientt/pkg/assertions"
+	"github.com/galexrt/ancientt/pkg/compare"
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/runners"
 	"github.com/galexrt/ancientt/testers"
@@ -35,6 +42,12 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// outputBufferSize size of the buffered data channel of each output
+const outputBufferSize = 32
+
+// errOnlyPrintPlan returned by runTest when only the plan should be printed, which stops the test run without error
+var errOnlyPrintPlan = errors.New("only printing the test plan")
+
 var (
 	outputSeparator = aurora.Red("===================")
 	aurora          = au.NewAurora(isatty.IsTerminal(os.Stdout.Fd()))
@@ -44,6 +57,12 @@ var (
 		Short: "Ancientt is a tool to automate network testing tools, like iperf3, in dynamic environments such as Kubernetes and more to come dynamic environments.",
 		RunE:  run,
 	}
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run the tests of the testdefinitions.",
+		Args:  cobra.NoArgs,
+		RunE:  run,
+	}
 	cfg      *config.Config
 	logLevel string
 
@@ -68,6 +87,41 @@ func init() {
 	viper.SetDefault("no-cleanup", false)
 	viper.SetDefault("yes", false)
 	viper.SetDefault("testdefinition", "testdefinition.yaml")
+
+	rootCmd.PersistentFlags().MarkDeprecated("only-print-plan", "use the `plan` command instead")
+
+	planCmd.Flags().StringP("output", "o", string(testers.PlanFormatText), "Output format of the plans (text, json, yaml).")
+	viper.BindPFlag("output", planCmd.Flags().Lookup("output"))
+	viper.SetDefault("output", string(testers.PlanFormatText))
+
+	runCmd.Flags().String("plan", "", "Path to a plan file (JSON or YAML, see 'plan --output') to run instead of planning the tests again.")
+	viper.BindPFlag("plan", runCmd.Flags().Lookup("plan"))
+	viper.SetDefault("plan", "")
+
+	reportCmd.Flags().String("results", "", "Path to the results archive directory to replay (default: the results dir of the testdefinitions).")
+	viper.BindPFlag("results", reportCmd.Flags().Lookup("results"))
+	viper.SetDefault("results", "")
+	reportCmd.Flags().String("run", "", "Run (TEST_START_TIME directory name in the results archive) of the tests to replay (default: the latest run of each test).")
+	viper.BindPFlag("report-run", reportCmd.Flags().Lookup("run"))
+
+	compareCmd.Flags().StringSlice("metric", []string{}, "Metrics (columns) to compare, e.g., 'received_bits_per_second' (can be given multiple times).")
+	compareCmd.Flags().StringSlice("lower-is-better", []string{}, "Metrics for which a lower value is better, e.g., 'rtt_avg' (can be given multiple times).")
+	compareCmd.Flags().Float64("threshold", 10, "Regression threshold in percent, e.g., '10' for a 10% throughput drop.")
+	compareCmd.Flags().String("data-type", string(outputs.DataTypeSummary), "Type of the data to compare (summary, interval).")
+	compareCmd.Flags().StringToString("filter", map[string]string{}, "Only compare rows with the column value, e.g., 'kind=sum' for the IPerf3 sum rows.")
+	compareCmd.Flags().StringP("output", "o", string(compare.FormatTable), "Format of the report (table, markdown).")
+	compareCmd.Flags().String("baseline-run", "", "Run (TEST_START_TIME directory name) of the tests in the baseline results archive (default: the latest run of each test).")
+	compareCmd.Flags().String("current-run", "", "Run (TEST_START_TIME directory name) of the tests in the current results archive (default: the latest run of each test).")
+	viper.BindPFlag("compare-metric", compareCmd.Flags().Lookup("metric"))
+	viper.BindPFlag("compare-lower-is-better", compareCmd.Flags().Lookup("lower-is-better"))
+	viper.BindPFlag("compare-threshold", compareCmd.Flags().Lookup("threshold"))
+	viper.BindPFlag("compare-data-type", compareCmd.Flags().Lookup("data-type"))
+	viper.BindPFlag("compare-filter", compareCmd.Flags().Lookup("filter"))
+	viper.BindPFlag("compare-output", compareCmd.Flags().Lookup("output"))
+	viper.BindPFlag("compare-baseline-run", compareCmd.Flags().Lookup("baseline-run"))
+	viper.BindPFlag("compare-current-run", compareCmd.Flags().Lookup("current-run"))
+
+	rootCmd.AddCommand(validateCmd, planCmd, runCmd, reportCmd, compareCmd)
 }
 
 func main() {
@@ -113,9 +167,7 @@ func run(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	var err error
-	logger, err = newLogger()
-	if err != nil {
+	if err := setup(); err != nil {
 		return err
 	}
 
@@ -129,153 +181,192 @@ func run(cmd *cobra.Command, args []string) error {
 		zap.String("buildDate", version.BuildDate),
 	).Info("starting ancientt")
 
-	if err := loadConfig(); err != nil {
-		return err
-	}
+	// The context is cancelled on SIGINT / SIGTERM to abort the running test
+	ctx, stop := newSignalContext()
+	defer stop()
 
-	var runner runners.Runner
-	runnerName := strings.ToLower(cfg.Runner.Name)
-	runnerNewFunc, ok := runners.Factories[runnerName]
-	if !ok {
-		return fmt.Errorf("runner with name %s not found", runnerName)
-	}
-	runner, err = runnerNewFunc(logger, cfg)
+	runner, runnerName, err := newRunner()
 	if err != nil {
 		return err
 	}
 
+	// Approved plans from a plan file are run as is, instead of planning the tests again
+	var approvedPlans map[string]*testers.Plan
+	if planFile := viper.GetString("plan"); planFile != "" {
+		approvedPlans, err = testers.ReadPlans(planFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Tests with failed assertions, which are returned as error after all tests have been run
+	failedTests := []string{}
 	for i, test := range cfg.Tests {
 		logger.With(zap.String("runner", runnerName)).Info(fmt.Sprintf("doing test '%s', %d of %d", test.Name, i+1, len(cfg.Tests)))
 
-		logger, tester, parser, outputsAssembled, err := prepare(logger, test, runnerName)
+		assertionsFailed, err := runTest(ctx, runner, runnerName, approvedPlans, i, test)
+		if assertionsFailed {
+			failedTests = append(failedTests, test.Name)
+		}
 		if err != nil {
-			logger.Error("error preparing test run", zap.Error(err))
-			if !*test.RunOptions.ContinueOnError {
-				return err
+			if errors.Is(err, errOnlyPrintPlan) {
+				return nil
 			}
-			logger.Warn(fmt.Sprintf("skipping test %d of %d due to error in initial prepare step", i+1, len(cfg.Tests)))
-			continue
+			return err
 		}
 
-		// Get hosts for the test
-		hosts, err := runner.GetHostsForTest(test)
-		if err != nil {
-			return err
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("aborted test run. %w", err)
+		}
+	}
+
+	logger.Info("done with tests")
+
+	if len(failedTests) > 0 {
+		return fmt.Errorf("assertions failed for tests: %s", strings.Join(failedTests, ", "))
+	}
+
+	return nil
+}
+
+// runTest run the test and its outputs. The outputs are closed and the runner cleanup is run (if wanted by the user)
+// when the test returns. assertionsFailed is true when the assertions of the test failed.
+func runTest(ctx context.Context, runner runners.Runner, runnerName string, approvedPlans map[string]*testers.Plan, i int, test *config.Test) (assertionsFailed bool, err error) {
+	logger, tester, parser, outputsAssembled, err := prepare(logger, test, runnerName)
+	if err != nil {
+		logger.Error("error preparing test run", zap.Error(err))
+		if !*test.RunOptions.ContinueOnError {
+			return false, err
 		}
-		// Create testers.Environment with the hosts
-		env := &testers.Environment{
-			Hosts: hosts,
+		logger.Warn(fmt.Sprintf("skipping test %d of %d due to error in initial prepare step", i+1, len(cfg.Tests)))
+		return false, nil
+	}
+
+	// Make sure the outputs are closed when the test is aborted before the outputs are run,
+	// otherwise doOutputs() takes care of closing the outputs
+	outputsStarted := false
+	defer func() {
+		if outputsStarted {
+			return
 		}
-		// Get plan from testers.Plan()
-		plan, err := tester.Plan(env, test)
-		if err != nil {
-			return err
+		for _, err := range closeOutputs(outputsAssembled) {
+			logger.Error("error closing output", zap.Error(err))
 		}
-		// Set TestStartTime for usage in output / results later on
-		plan.TestStartTime = time.Now()
+	}()
 
-		fmt.Println(outputSeparator)
-		// Pretty print the plan of the test to the shell
-		fmt.Println("--> BEGIN PLAN")
-		plan.PrettyPrint()
-		fmt.Println("--> END PLAN")
-		if viper.GetBool("only-print-plan") {
-			return nil
-		}
-
-		if !viper.GetBool("yes") {
-			// Ask user if we can continue or not
-			if err := askUserForYes(); err != nil {
-				return err
-			}
+	var plan *testers.Plan
+	if approvedPlans != nil {
+		plan, err = getApprovedPlan(approvedPlans, test)
+	} else {
+		plan, err = getPlan(ctx, runner, tester, test)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	printPlan(plan)
+	if viper.GetBool("only-print-plan") {
+		return false, errOnlyPrintPlan
+	}
+
+	if !viper.GetBool("yes") {
+		// Ask user if we can continue or not
+		if err := askUserForYes(); err != nil {
+			return false, err
 		}
+	}
 
-		logger.Info("preparing test")
+	logger.Info("preparing test")
 
-		// Prepare the runner for the plan
-		if err = runner.Prepare(test.RunOptions, plan); err != nil {
-			return err
+	// Make sure the runner cleanup is run for the plan, even when the test is aborted
+	cleanupDone := viper.GetBool("no-cleanup")
+	defer func() {
+		if cleanupDone {
+			return
+		}
+		if err := runnerCleanup(runner, plan); err != nil {
+			logger.Error("error during runner cleanup", zap.Error(err))
 		}
+	}()
 
-		var wg sync.WaitGroup
+	// Prepare the runner for the plan, with the run options of the plan as the plan might be an approved plan
+	if err = runner.Prepare(ctx, plan.RunOptions, plan); err != nil {
+		return false, err
+	}
 
-		doneCh := make(chan struct{})
-		inCh := make(chan parsers.Input)
-		dataCh := make(chan outputs.Data)
-		errCh := make(chan error)
+	var archiver *archive.Archiver
+	if cfg.Results != nil && cfg.Results.Dir != "" {
+		if archiver, err = archive.NewArchiver(logger, cfg.Results.Dir, test.Name, plan.TestStartTime); err != nil {
+			return false, err
+		}
+	}
 
-		go func() {
-			select {
-			case erro := <-errCh:
-				logger.Error(erro.Error())
-			case <-doneCh:
-				return
-			}
-		}()
+	// Outputs can report on the plan and the status of its tasks
+	setOutputsPlan(outputsAssembled, test, plan)
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// Close dataCh as there won't be anything else coming through
-			defer close(dataCh)
-			if err := parser.Parse(doneCh, inCh, dataCh); err != nil {
-				errCh <- err
-				return
-			}
-		}()
+	// Start the parser and each output
+	outputsStarted = true
+	inCh := make(chan parsers.Input)
+	evaluator := newEvaluator(test)
+	wait := startPipeline(logger, test, parser, outputsAssembled, inCh, evaluator)
 
-		// Start each output
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := doOutputs(outputsAssembled, test, doneCh, dataCh); err != nil {
-				errCh <- err
-				return
-			}
-		}()
+	// The raw results are archived (if enabled) before they are passed on to the parser
+	runnerInCh := inCh
+	if archiver != nil {
+		runnerInCh = make(chan parsers.Input)
+		go archiveInputs(logger, archiver, runnerInCh, inCh)
+	}
 
-		logger.Info("executing test")
+	logger.Info("executing test")
 
-		// Execute the plan
-		if err := runner.Execute(plan, inCh); err != nil {
-			errCh <- err
-		}
-		logger.Debug("runner execute returned, closing inCh and wg.Wait()")
+	// Execute the plan
+	if err := runner.Execute(ctx, plan, runnerInCh); err != nil {
+		logger.Error("error during runner execute", zap.Error(err))
+	}
+	logger.Debug("runner execute returned, closing inCh and waiting for parser and outputs")
 
-		close(inCh)
+	close(runnerInCh)
 
-		if err := checkForErrors(plan); err != nil {
-			logger.Error("found error during run", zap.Error(err))
-			if !*test.RunOptions.ContinueOnError {
-				return err
-			}
-			logger.Warn("continue on error run option given for test, continuing")
+	outputsErr := wait()
+
+	// Check for errors after the parser is done, as the parser can report failed test results as well
+	if err := checkForErrors(plan); err != nil {
+		logger.Error("found error during run", zap.Error(err))
+		if !*test.RunOptions.ContinueOnError {
+			return false, err
 		}
+		logger.Warn("continue on error run option given for test, continuing")
+	}
 
-		wg.Wait()
+	if outputsErr != nil {
+		logger.Error("found error in outputs", zap.Error(outputsErr))
+		if !*test.RunOptions.ContinueOnError {
+			return false, outputsErr
+		}
+		logger.Warn("continue on error run option given for test, continuing")
+	}
 
-		close(doneCh)
+	printOutputFiles(outputsAssembled)
 
-		fmt.Println(outputSeparator)
-		fmt.Println(aurora.Magenta("Following files have been created / used:"))
-		for outName, output := range outputsAssembled {
-			for _, file := range output.OutputFiles() {
-				fmt.Printf("%s (output: %s)\n", file, outName)
-			}
+	if err := checkAssertions(evaluator); err != nil {
+		logger.Error("assertions failed", zap.Error(err))
+		if !*test.RunOptions.ContinueOnError {
+			return true, err
 		}
-		fmt.Println(outputSeparator)
+		logger.Warn("continue on error run option given for test, continuing")
+		assertionsFailed = true
+	}
 
-		// Run runners.Cleanup() func if wanted by the user
-		if !viper.GetBool("no-cleanup") {
-			if err := runnerCleanup(runner, plan); err != nil {
-				return err
-			}
+	// Run runners.Cleanup() func if wanted by the user
+	if !cleanupDone {
+		cleanupDone = true
+		if err := runnerCleanup(runner, plan); err != nil {
+			return assertionsFailed, err
 		}
 	}
 
-	logger.Info("done with tests")
+	return assertionsFailed, nil
 
-	return nil
 }
 
 func askUserForYes() error {
@@ -301,8 +392,143 @@ func askUserForYes() error {
 	return nil
 }
 
+// setup create the logger and load the config
+func setup() error {
+	var err error
+	logger, err = newLogger()
+	if err != nil {
+		return err
+	}
+
+	return loadConfig()
+}
+
+// newRunner return the runner from the config and its name
+func newRunner() (runners.Runner, string, error) {
+	runnerName := strings.ToLower(cfg.Runner.Name)
+	runnerNewFunc, ok := runners.Factories[runnerName]
+	if !ok {
+		return nil, runnerName, fmt.Errorf("runner with name %s not found", runnerName)
+	}
+	runner, err := runnerNewFunc(logger, cfg)
+	if err != nil {
+		return nil, runnerName, err
+	}
+
+	return runner, runnerName, nil
+}
+
+// newTester return the tester for the test
+func newTester(logger *zap.Logger, test *config.Test) (testers.Tester, error) {
+	testerName := strings.ToLower(test.Type)
+	testerNewFunc, ok := testers.Factories[testerName]
+	if !ok {
+		return nil, fmt.Errorf("tester with name %s not found", testerName)
+	}
+
+	return testerNewFunc(logger, cfg, test)
+}
+
+// getPlan get the hosts for the test from the runner and return the plan of the tester for them
+func getPlan(ctx context.Context, runner runners.Runner, tester testers.Tester, test *config.Test) (*testers.Plan, error) {
+	// Get hosts for the test
+	hosts, err := runner.GetHostsForTest(ctx, test)
+	if err != nil {
+		return nil, err
+	}
+	// Create testers.Environment with the hosts
+	env := &testers.Environment{
+		Hosts: hosts,
+	}
+	// Get plan from testers.Plan()
+	plan, err := tester.Plan(env, test)
+	if err != nil {
+		return nil, err
+	}
+	plan.RunOptions = test.RunOptions
+	// Set TestStartTime for usage in output / results later on
+	plan.TestStartTime = time.Now()
+
+	return plan, nil
+}
+
+// getApprovedPlan get the plan for the test from the approved plans (e.g., read from a plan file)
+func getApprovedPlan(plans map[string]*testers.Plan, test *config.Test) (*testers.Plan, error) {
+	plan, ok := plans[test.Name]
+	if !ok {
+		return nil, fmt.Errorf("no plan for test %s found in plan file", test.Name)
+	}
+	if !strings.EqualFold(plan.Tester, test.Type) {
+		return nil, fmt.Errorf("plan for test %s is for tester %s, but the test is of type %s", test.Name, plan.Tester, test.Type)
+	}
+	// The plan is run now, so set TestStartTime for usage in output / results later on
+	plan.TestStartTime = time.Now()
+
+	return plan, nil
+}
+
+// newEvaluator return an assertions evaluator for the test, nil when the test has no assertions
+func newEvaluator(test *config.Test) *assertions.Evaluator {
+	if len(test.Assertions) == 0 {
+		return nil
+	}
+	return assertions.New(test.Assertions)
+}
+
+// checkAssertions evaluate the assertions and print the results, returns an error when an assertion failed
+func checkAssertions(evaluator *assertions.Evaluator) error {
+	if evaluator == nil {
+		return nil
+	}
+
+	results := evaluator.Evaluate()
+	failed := assertions.Failed(results)
+
+	fmt.Println(outputSeparator)
+	if len(failed) > 0 {
+		fmt.Println(aurora.Yellow("-> Failed Assertions"))
+		for _, result := range failed {
+			fmt.Println(result)
+		}
+	}
+	if len(failed) != len(results) {
+		fmt.Println(aurora.Green("-> Passed Assertions"))
+		for _, result := range results {
+			if result.Passed {
+				fmt.Println(result)
+			}
+		}
+	}
+	fmt.Println(outputSeparator)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d assertions failed", len(failed), len(results))
+	}
+
+	return nil
+}
+
+// printOutputFiles print the files created / used by the outputs
+func printOutputFiles(outputsAssembled map[string]outputs.Output) {
+	fmt.Println(outputSeparator)
+	fmt.Println(aurora.Magenta("Following files have been created / used:"))
+	for outName, output := range outputsAssembled {
+		for _, file := range output.OutputFiles() {
+			fmt.Printf("%s (output: %s)\n", file, outName)
+		}
+	}
+	fmt.Println(outputSeparator)
+}
+
+// printPlan pretty print the plan of the test to the shell
+func printPlan(plan *testers.Plan) {
+	fmt.Println(outputSeparator)
+	fmt.Println("--> BEGIN PLAN")
+	plan.PrettyPrint()
+	fmt.Println("--> END PLAN")
+}
+
 func prepare(logger *zap.Logger, test *config.Test, runnerName string) (*zap.Logger, testers.Tester, parsers.Parser, map[string]outputs.Output, error) {
-	var tester testers.Tester
 	var parser parsers.Parser
 	outputsAssembled := map[string]outputs.Output{}
 
@@ -310,12 +536,8 @@ func prepare(logger *zap.Logger, test *config.Test, runnerName string) (*zap.Log
 	testerName := strings.ToLower(test.Type)
 	logger = logger.With(zap.String("tester", testerName), zap.String("parser", testerName), zap.String("runner", runnerName))
 
-	testerNewFunc, ok := testers.Factories[testerName]
-	if !ok {
-		return logger, nil, nil, nil, fmt.Errorf("tester with name %s not found", testerName)
-	}
-	var err error
-	if tester, err = testerNewFunc(logger, cfg, test); err != nil {
+	tester, err := newTester(logger, test)
+	if err != nil {
 		return logger, nil, nil, nil, err
 	}
 
@@ -345,28 +567,170 @@ func prepare(logger *zap.Logger, test *config.Test, runnerName string) (*zap.Log
 	return logger, tester, parser, outputsAssembled, err
 }
 
+// startPipeline start the parser and the outputs for the test. The returned wait func must be called after the inCh
+// has been closed, it waits for the parser and the outputs to be done and returns the outputs errors.
+// When an evaluator is given, the parsed data is added to it before it is passed on to the outputs.
+func startPipeline(logger *zap.Logger, test *config.Test, parser parsers.Parser, outputsAssembled map[string]outputs.Output, inCh <-chan parsers.Input, evaluator *assertions.Evaluator) func() error {
+	var wg sync.WaitGroup
+
+	doneCh := make(chan struct{})
+	dataCh := make(chan outputs.Data)
+
+	parserDataCh := dataCh
+	if evaluator != nil {
+		parserDataCh = make(chan outputs.Data)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(dataCh)
+			for data := range parserDataCh {
+				if err := evaluator.Add(data); err != nil {
+					logger.Error("error adding data to assertions", zap.Error(err))
+				}
+				dataCh <- data
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Close the data channel as there won't be anything else coming through
+		defer close(parserDataCh)
+		if err := parser.Parse(doneCh, inCh, parserDataCh); err != nil {
+			logger.Error("error in parser", zap.Error(err))
+		}
+	}()
+
+	var outputsErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		outputsErr = doOutputs(outputsAssembled, test, doneCh, dataCh)
+	}()
+
+	return func() error {
+		wg.Wait()
+		close(doneCh)
+		return outputsErr
+	}
+}
+
+// archiveInputs archive each input from the runner and pass it on to the parser, the parser inCh is closed
+// when the runner inCh is closed
+func archiveInputs(logger *zap.Logger, archiver *archive.Archiver, runnerInCh <-chan parsers.Input, inCh chan<- parsers.Input) {
+	defer close(inCh)
+	for input := range runnerInCh {
+		input, err := archiver.Archive(input)
+		if err != nil {
+			logger.Error("failed to archive raw result", zap.String("server", input.ServerHost), zap.String("client", input.ClientHost), zap.Error(err))
+		}
+		inCh <- input
+	}
+}
+
+// doOutputs run each output in its own goroutine with its own buffered data channel, so that a slow
+// output doesn't block the others. All outputs are closed once the dataCh is closed (or doneCh is closed),
+// the errors of all outputs are collected and returned together.
 func doOutputs(outputsAssembled map[string]outputs.Output, test *config.Test, doneCh chan struct{}, dataCh chan outputs.Data) error {
+	var wg sync.WaitGroup
+	outChs := map[string]chan outputs.Data{}
+	outErrs := make([][]error, len(test.Outputs))
+
+	for i, outputItem := range test.Outputs {
+		outputName := outputItem.Name
+		// Outputs are assembled by name, so each output must only be run once
+		if _, ok := outChs[outputName]; ok {
+			continue
+		}
+
+		outCh := make(chan outputs.Data, outputBufferSize)
+		outChs[outputName] = outCh
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for data := range outCh {
+				// Each output gets its own copy of the data for its transformations, so that the
+				// transformations of one output don't change the data the other outputs receive
+				if len(outputItem.Transformations) > 0 {
+					data = data.Copy()
+					if err := data.Data.Transform(outputItem.Transformations); err != nil {
+						outErrs[i] = append(outErrs[i], fmt.Errorf("error in output transformations. %+v", err))
+						continue
+					}
+				}
+
+				if err := outputsAssembled[outputName].Do(data); err != nil {
+					outErrs[i] = append(outErrs[i], fmt.Errorf("error in output Do() func. %+v", err))
+				}
+			}
+		}()
+	}
+
+	errs := []error{}
+
+loop:
 	for {
 		select {
 		case data, ok := <-dataCh:
 			if !ok {
 				logger.Debug("dataCh closed, in doOutputs()")
-				return nil
+				break loop
 			}
-			for _, outputItem := range test.Outputs {
-				outputName := outputItem.Name
+			data.Test = test.Name
 
-				if err := outputsAssembled[outputName].Do(data); err != nil {
-					// TODO Run all ouputs and concat errors
-					return fmt.Errorf("error in output Do() func. %+v", err)
+			// Test transformations are applied once for all outputs
+			if len(test.Transformations) > 0 {
+				if err := data.Data.Transform(test.Transformations); err != nil {
+					errs = append(errs, fmt.Errorf("error in test transformations. %+v", err))
+					continue
 				}
 			}
+
+			for _, outCh := range outChs {
+				outCh <- data
+			}
 		case <-doneCh:
-			return nil
+			break loop
+		}
+	}
+
+	for _, outCh := range outChs {
+		close(outCh)
+	}
+	wg.Wait()
+
+	for i, outputItem := range test.Outputs {
+		for _, err := range outErrs[i] {
+			errs = append(errs, fmt.Errorf("output %s: %w", outputItem.Name, err))
+		}
+	}
+	errs = append(errs, closeOutputs(outputsAssembled)...)
+
+	return errors.Join(errs...)
+}
+
+// setOutputsPlan set the test and plan on the outputs implementing the outputs.PlanOutput interface
+func setOutputsPlan(outputsAssembled map[string]outputs.Output, test *config.Test, plan *testers.Plan) {
+	for _, output := range outputsAssembled {
+		if planOutput, ok := output.(outputs.PlanOutput); ok {
+			planOutput.SetPlan(test, plan)
 		}
 	}
 }
 
+// closeOutputs close all given outputs and return the errors of each output
+func closeOutputs(outputsAssembled map[string]outputs.Output) []error {
+	errs := []error{}
+	for outName, output := range outputsAssembled {
+		if err := output.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("output %s: error in output Close() func. %+v", outName, err))
+		}
+	}
+	return errs
+}
+
 func checkForErrors(plan *testers.Plan) error {
 	errorOccured := false
 
@@ -383,7 +747,7 @@ func checkForErrors(plan *testers.Plan) error {
 				fmt.Println(aurora.Yellow("-> Failed Server Hosts"))
 				for host, count := range task.Status.FailedHosts.Servers {
 					fmt.Printf("%s - %d\n", host, count)
-					for _, err := range task.Status.Errors[host] {
+					for _, err := range task.Status.Errors.Servers[host] {
 						fmt.Println(err)
 					}
 				}
@@ -393,7 +757,7 @@ func checkForErrors(plan *testers.Plan) error {
 				fmt.Println(aurora.Yellow("-> Failed Client Hosts"))
 				for host, count := range task.Status.FailedHosts.Clients {
 					fmt.Printf("%s - %d\n", host, count)
-					for _, err := range task.Status.Errors[host] {
+					for _, err := range task.Status.Errors.Clients[host] {
 						fmt.Println(err)
 					}
 				}
@@ -427,9 +791,46 @@ func checkForErrors(plan *testers.Plan) error {
 func runnerCleanup(runner runners.Runner, plan *testers.Plan) error {
 	logger.Info("running runner cleanup func for test")
 
-	if err := runner.Cleanup(plan); err != nil {
+	// The cleanup must also be run after the test has been aborted, so it isn't using the (cancelled) signal context
+	if err := runner.Cleanup(context.Background(), plan); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// newSignalContext return a context that is cancelled on the first SIGINT / SIGTERM, so the running test is aborted,
+// the outputs are closed and the runner cleanup is run. A second signal forces the exit.
+func newSignalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCh := make(chan struct{})
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			logger.Warn("received signal, aborting test run and running cleanup (send the signal again to force exit)", zap.String("signal", sig.String()))
+			cancel()
+		case <-stopCh:
+			return
+		}
+
+		select {
+		case sig := <-sigCh:
+			logger.Error("received second signal, forcing exit", zap.String("signal", sig.String()))
+			os.Exit(1)
+		case <-stopCh:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(sigCh)
+			close(stopCh)
+			cancel()
+		})
+	}
+}
@@ -14,9 +14,22 @@ limitations under the License.
 package main
 
 import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 // TODO Add a simple but useful test to verify config loading and basic functionality
@@ -37,3 +50,208 @@ func TestAncienttRunCommand(t *testing.T) {
 	   	assert.Nil(t, err)
 	*/
 }
+
+func TestDoOutputsTransformations(t *testing.T) {
+	logger = zap.NewNop()
+
+	tempDir := t.TempDir()
+	test := &config.Test{
+		Name: "transformations",
+		Type: "iperf3",
+		Transformations: []*config.Transformation{
+			{
+				Source:         "bits_per_second",
+				Action:         config.TransformationActionReplace,
+				Destination:    "gigabits_per_second",
+				Modifier:       util.FloatPointer(1000000000),
+				ModifierAction: config.ModifierActionDivison,
+			},
+		},
+		Outputs: []config.Output{
+			{
+				Name: "csv",
+				CSV: &config.CSV{
+					FilePath: config.FilePath{
+						FilePath:    tempDir,
+						NamePattern: "transformations.csv",
+					},
+				},
+				Transformations: []*config.Transformation{
+					{
+						Source:         "gigabits_per_second",
+						Action:         config.TransformationActionAdd,
+						Destination:    "megabits_per_second",
+						Modifier:       util.FloatPointer(1000),
+						ModifierAction: config.ModifierActionMultiply,
+					},
+					{
+						Source: "round",
+						Action: config.TransformationActionDelete,
+					},
+				},
+			},
+			{
+				Name: "sqlite",
+				SQLite: &config.SQLite{
+					FilePath: config.FilePath{
+						FilePath:    tempDir,
+						NamePattern: "transformations.sqlite3",
+					},
+					TableNamePattern: "transformations",
+				},
+				Transformations: []*config.Transformation{
+					{
+						Source: "round",
+						Action: config.TransformationActionDelete,
+					},
+				},
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(test))
+
+	outputsAssembled := map[string]outputs.Output{}
+	for i := range test.Outputs {
+		out, err := outputs.Factories[test.Outputs[i].Name](logger, nil, &test.Outputs[i])
+		require.Nil(t, err)
+		outputsAssembled[test.Outputs[i].Name] = out
+	}
+
+	doneCh := make(chan struct{})
+	dataCh := make(chan outputs.Data, 1)
+	dataCh <- outputs.Data{
+		TestStartTime: time.Now(),
+		TestTime:      time.Now(),
+		Tester:        "iperf3",
+		ServerHost:    "server1",
+		ClientHost:    "client1",
+		Data: &outputs.Table{
+			Headers: []*outputs.Row{
+				{Value: "round"},
+				{Value: "bits_per_second"},
+			},
+			Rows: [][]*outputs.Row{
+				{{Value: 0}, {Value: float64(2000000000)}},
+				{{Value: 1}, {Value: float64(4000000000)}},
+			},
+		},
+	}
+	close(dataCh)
+
+	require.Nil(t, doOutputs(outputsAssembled, test, doneCh, dataCh))
+
+	// CSV output has the test and its own output transformations applied
+	file, err := os.Open(filepath.Join(tempDir, "transformations.csv"))
+	require.Nil(t, err)
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"gigabits_per_second", "megabits_per_second"},
+		{"2.000000", "2000.000000"},
+		{"4.000000", "4000.000000"},
+	}, records)
+
+	// SQLite output has the test and its own output transformations applied
+	db, err := sqlx.Connect("sqlite3", filepath.Join(tempDir, "transformations.sqlite3"))
+	require.Nil(t, err)
+	defer db.Close()
+	rows, err := db.Queryx("SELECT * FROM `transformations`")
+	require.Nil(t, err)
+	defer rows.Close()
+	columns, err := rows.Columns()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"gigabits_per_second"}, columns)
+	values := []float64{}
+	for rows.Next() {
+		var value float64
+		require.Nil(t, rows.Scan(&value))
+		values = append(values, value)
+	}
+	assert.Equal(t, []float64{2, 4}, values)
+}
+
+// mockOutput output counting the received data and returning the given errors
+type mockOutput struct {
+	outputs.Output
+	doErr    error
+	closeErr error
+	received int
+	closed   bool
+}
+
+func (m *mockOutput) Do(data outputs.Data) error {
+	m.received++
+	return m.doErr
+}
+
+func (m *mockOutput) OutputFiles() []string {
+	return []string{}
+}
+
+func (m *mockOutput) Close() error {
+	m.closed = true
+	return m.closeErr
+}
+
+func TestDoOutputsErrors(t *testing.T) {
+	logger = zap.NewNop()
+
+	test := &config.Test{
+		Name: "errors",
+		Outputs: []config.Output{
+			{Name: "failing"},
+			{Name: "working"},
+			{Name: "closing"},
+		},
+	}
+	failing := &mockOutput{doErr: fmt.Errorf("do failed")}
+	working := &mockOutput{}
+	closing := &mockOutput{closeErr: fmt.Errorf("close failed")}
+	outputsAssembled := map[string]outputs.Output{
+		"failing": failing,
+		"working": working,
+		"closing": closing,
+	}
+
+	doneCh := make(chan struct{})
+	dataCh := make(chan outputs.Data, 3)
+	for i := 0; i < 3; i++ {
+		dataCh <- outputs.Data{
+			Data: &outputs.Table{},
+		}
+	}
+	close(dataCh)
+
+	err := doOutputs(outputsAssembled, test, doneCh, dataCh)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "output failing: error in output Do() func. do failed")
+	assert.Contains(t, err.Error(), "output closing: error in output Close() func. close failed")
+	assert.NotContains(t, err.Error(), "output working")
+
+	// A failing output must neither stop the other outputs nor its own further data
+	for name, out := range map[string]*mockOutput{"failing": failing, "working": working, "closing": closing} {
+		assert.Equal(t, 3, out.received, name)
+		assert.True(t, out.closed, name)
+	}
+}
+
+func TestNewSignalContext(t *testing.T) {
+	logger = zap.NewNop()
+
+	ctx, stop := newSignalContext()
+	defer stop()
+
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context has not been cancelled by the signal")
+	}
+
+	// Stopping twice must not panic
+	stop()
+}
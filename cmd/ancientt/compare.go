@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/compare"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare BASELINE CURRENT",
+	Short: "Compare the results of two test runs (SQLite output database or raw results archive directory) and detect regressions.",
+	Long: `Compare the results of two test runs and detect regressions.
+
+The results can either be a SQLite output database file or a raw results archive directory. The rows of the results
+are matched by tester, server host, client host, round and IP family. The values of each metric are averaged per match.
+Of a raw results archive directory only the latest run of each test is compared, another run can be selected with the
+'--baseline-run' and '--current-run' flags.
+A non zero exit code is returned when at least one metric exceeds the regression threshold or is missing in the
+current results, e.g., of a failed client.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE:         compareResults,
+}
+
+func compareResults(cmd *cobra.Command, args []string) error {
+	opts := &compare.Options{
+		Metrics:       viper.GetStringSlice("compare-metric"),
+		LowerIsBetter: viper.GetStringSlice("compare-lower-is-better"),
+		Threshold:     viper.GetFloat64("compare-threshold"),
+		DataType:      outputs.DataType(viper.GetString("compare-data-type")),
+		Filters:       viper.GetStringMapString("compare-filter"),
+	}
+	if len(opts.Metrics) == 0 {
+		return fmt.Errorf("no metrics to compare given")
+	}
+	if opts.DataType != outputs.DataTypeSummary && opts.DataType != outputs.DataTypeInterval {
+		return fmt.Errorf("unknown data type %s given", opts.DataType)
+	}
+	format := compare.Format(viper.GetString("compare-output"))
+	if format != compare.FormatTable && format != compare.FormatMarkdown {
+		return fmt.Errorf("unknown compare report format %s given", format)
+	}
+
+	var err error
+	logger, err = newLogger()
+	if err != nil {
+		return err
+	}
+
+	baseline, err := compare.Load(logger, args[0], viper.GetString("compare-baseline-run"), opts)
+	if err != nil {
+		return fmt.Errorf("failed to load baseline results. %+v", err)
+	}
+	current, err := compare.Load(logger, args[1], viper.GetString("compare-current-run"), opts)
+	if err != nil {
+		return fmt.Errorf("failed to load current results. %+v", err)
+	}
+
+	deltas := compare.Compare(baseline, current, opts)
+	if err := compare.WriteReport(os.Stdout, format, deltas, opts.Threshold); err != nil {
+		return err
+	}
+
+	if regressions := compare.Regressions(deltas); regressions > 0 {
+		logger.Error("regression threshold exceeded", zap.Int("regressions", regressions), zap.Float64("threshold", opts.Threshold))
+		return fmt.Errorf("%d metrics are missing in the current results or exceed the regression threshold of %.2f%%", regressions, opts.Threshold)
+	}
+
+	return nil
+}
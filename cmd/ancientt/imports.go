@@ -22,8 +22,15 @@ import (
 	_ "github.com/galexrt/ancientt/outputs/dump"
 	_ "github.com/galexrt/ancientt/outputs/excelize"
 	_ "github.com/galexrt/ancientt/outputs/gochart"
+	_ "github.com/galexrt/ancientt/outputs/heatmap"
+	_ "github.com/galexrt/ancientt/outputs/html"
+	_ "github.com/galexrt/ancientt/outputs/influxdb"
+	_ "github.com/galexrt/ancientt/outputs/json"
+	_ "github.com/galexrt/ancientt/outputs/markdown"
 	_ "github.com/galexrt/ancientt/outputs/mysql"
+	_ "github.com/galexrt/ancientt/outputs/prometheus"
 	_ "github.com/galexrt/ancientt/outputs/sqlite"
+	_ "github.com/galexrt/ancientt/outputs/stats"
 
 	// Parsers
 	_ "github.com/galexrt/ancientt/parsers/iperf3"
@@ -32,7 +39,9 @@ import (
 	// Runners
 	_ "github.com/galexrt/ancientt/runners/ansible"
 	_ "github.com/galexrt/ancientt/runners/kubernetes"
+	_ "github.com/galexrt/ancientt/runners/local"
 	_ "github.com/galexrt/ancientt/runners/mock"
+	_ "github.com/galexrt/ancientt/runners/ssh"
 
 	// Testers
 	_ "github.com/galexrt/ancientt/testers/iperf3"
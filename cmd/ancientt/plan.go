@@ -0,0 +1,82 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/galexrt/ancientt/testers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Resolve the hosts through the runner and print the plan for each test of the testdefinitions.",
+	Args:  cobra.NoArgs,
+	RunE:  planTests,
+}
+
+func planTests(cmd *cobra.Command, args []string) error {
+	format := testers.PlanFormat(viper.GetString("output"))
+	switch format {
+	case testers.PlanFormatText, testers.PlanFormatJSON, testers.PlanFormatYAML:
+	default:
+		return fmt.Errorf("unknown plan output format %s given", format)
+	}
+
+	if err := setup(); err != nil {
+		return err
+	}
+
+	ctx, stop := newSignalContext()
+	defer stop()
+
+	runner, runnerName, err := newRunner()
+	if err != nil {
+		return err
+	}
+
+	plans := []testers.TestPlan{}
+	for i, test := range cfg.Tests {
+		logger.With(zap.String("runner", runnerName)).Info(fmt.Sprintf("planning test '%s', %d of %d", test.Name, i+1, len(cfg.Tests)))
+
+		tester, err := newTester(logger, test)
+		if err != nil {
+			return err
+		}
+
+		plan, err := getPlan(ctx, runner, tester, test)
+		if err != nil {
+			return err
+		}
+
+		if format == testers.PlanFormatText {
+			printPlan(plan)
+			continue
+		}
+		plans = append(plans, testers.TestPlan{
+			Test: test.Name,
+			Plan: plan,
+		})
+	}
+
+	if format == testers.PlanFormatText {
+		return nil
+	}
+
+	return testers.WritePlans(os.Stdout, format, plans)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/galexrt/ancientt/testers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithPlanFile(t *testing.T) {
+	dir := writeTestDefinition(t, "")
+	require.Nil(t, setup())
+
+	runner, _, err := newRunner()
+	require.Nil(t, err)
+
+	test := cfg.Tests[0]
+	tester, err := newTester(logger, test)
+	require.Nil(t, err)
+	plan, err := getPlan(context.Background(), runner, tester, test)
+	require.Nil(t, err)
+
+	planFile := filepath.Join(dir, "plan.json")
+	out, err := os.Create(planFile)
+	require.Nil(t, err)
+	require.Nil(t, testers.WritePlans(out, testers.PlanFormatJSON, []testers.TestPlan{{Test: test.Name, Plan: plan}}))
+	require.Nil(t, out.Close())
+
+	viper.Set("plan", planFile)
+	viper.Set("yes", true)
+	t.Cleanup(func() {
+		viper.Set("plan", "")
+		viper.Set("yes", false)
+	})
+
+	assert.Nil(t, run(runCmd, []string{}))
+
+	// The plan file must contain a plan for each test
+	require.Nil(t, os.WriteFile(planFile, []byte("[]"), 0640))
+	err = run(runCmd, []string{})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no plan for test iperf3-mock found in plan file")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/archive"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Replay the archived raw results of the tests through the parsers and outputs of the testdefinitions, without running the tests again.",
+	Args:  cobra.NoArgs,
+	RunE:  report,
+}
+
+func report(cmd *cobra.Command, args []string) error {
+	if err := setup(); err != nil {
+		return err
+	}
+
+	resultsDir := viper.GetString("results")
+	if resultsDir == "" && cfg.Results != nil {
+		resultsDir = cfg.Results.Dir
+	}
+	if resultsDir == "" {
+		return fmt.Errorf("no results directory given, either use the results flag or set the results dir in the testdefinitions")
+	}
+
+	metadatas, err := archive.Load(resultsDir)
+	if err != nil {
+		return err
+	}
+	// Only a single run of each test is replayed, as the results of different runs can't be mixed
+	results := map[string][]*archive.Metadata{}
+	for _, metadata := range archive.FilterRun(metadatas, viper.GetString("report-run")) {
+		results[metadata.Test] = append(results[metadata.Test], metadata)
+	}
+
+	runnerName := strings.ToLower(cfg.Runner.Name)
+	// Tests with failed assertions, which are returned as error after all tests have been reported
+	failedTests := []string{}
+	for i, test := range cfg.Tests {
+		if _, ok := results[test.Name]; !ok {
+			logger.Warn(fmt.Sprintf("no archived results found for test '%s', skipping it", test.Name))
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("reporting test '%s', %d of %d", test.Name, i+1, len(cfg.Tests)), zap.String("run", results[test.Name][0].Run()))
+
+		logger, _, parser, outputsAssembled, err := prepare(logger, test, runnerName)
+		if err != nil {
+			return err
+		}
+
+		// The replayed results are reported to a single status, as the tasks of the plan aren't available
+		status := testers.NewStatus()
+		plan := &testers.Plan{
+			TestStartTime: results[test.Name][0].TestStartTime,
+			Tester:        test.Type,
+			Commands:      [][]*testers.Task{{{Status: status}}},
+			RunOptions:    test.RunOptions,
+		}
+		setOutputsPlan(outputsAssembled, test, plan)
+
+		inCh := make(chan parsers.Input)
+		evaluator := newEvaluator(test)
+		wait := startPipeline(logger, test, parser, outputsAssembled, inCh, evaluator)
+
+		for _, metadata := range results[test.Name] {
+			input, err := metadata.Input()
+			if err != nil {
+				logger.Error("failed to open archived raw result", zap.String("server", metadata.ServerHost), zap.String("client", metadata.ClientHost), zap.Error(err))
+				continue
+			}
+			input.Status = status
+			inCh <- input
+		}
+		close(inCh)
+
+		outputsErr := wait()
+
+		if err := checkForErrors(plan); err != nil {
+			logger.Error("found error in archived results", zap.Error(err))
+		}
+		if outputsErr != nil {
+			return outputsErr
+		}
+
+		printOutputFiles(outputsAssembled)
+
+		if err := checkAssertions(evaluator); err != nil {
+			logger.Error("assertions failed", zap.Error(err))
+			failedTests = append(failedTests, test.Name)
+		}
+	}
+
+	logger.Info("done with reports")
+
+	if len(failedTests) > 0 {
+		return fmt.Errorf("assertions failed for tests: %s", strings.Join(failedTests, ", "))
+	}
+
+	return nil
+}
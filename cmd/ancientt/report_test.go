@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/archive"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const iperf3Result = `{
+	"start": {"test_start": {"protocol": "TCP", "num_streams": 1}},
+	"intervals": [
+		{
+			"streams": [{"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800}],
+			"sum": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800}
+		}
+	],
+	"end": {
+		"sum_sent": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800},
+		"sum_received": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800}
+	}
+}`
+
+func TestReportCommand(t *testing.T) {
+	dir := writeTestDefinition(t, "")
+	resultsDir := filepath.Join(dir, "results")
+
+	viper.Set("results", resultsDir)
+	t.Cleanup(func() {
+		viper.Set("results", "")
+	})
+
+	// The results archive doesn't exist yet
+	assert.NotNil(t, report(reportCmd, []string{}))
+
+	testStartTime := time.Now()
+	previousTestStartTime := testStartTime.Add(-time.Hour)
+	for _, startTime := range []time.Time{previousTestStartTime, testStartTime} {
+		archiver, err := archive.NewArchiver(zap.NewNop(), resultsDir, "iperf3-mock", startTime)
+		require.Nil(t, err)
+		_, err = archiver.Archive(parsers.Input{
+			TestStartTime: startTime,
+			TestTime:      startTime,
+			Round:         1,
+			Data:          []byte(iperf3Result),
+			Tester:        "iperf3",
+			ServerHost:    "servers-1",
+			ClientHost:    "servers-2",
+		})
+		require.Nil(t, err)
+	}
+
+	// Only the latest run is reported by default
+	require.Nil(t, report(reportCmd, []string{}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "ancientt-*-iperf3.txt"))
+	require.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	// A specific run can be selected
+	viper.Set("report-run", previousTestStartTime.Format(util.TimeDateFormat))
+	t.Cleanup(func() {
+		viper.Set("report-run", "")
+	})
+	require.Nil(t, report(reportCmd, []string{}))
+
+	files, err = filepath.Glob(filepath.Join(dir, "ancientt-*-iperf3.txt"))
+	require.Nil(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestReportCommandAssertions(t *testing.T) {
+	dir := writeTestDefinition(t, `  assertions:
+  - column: bits_per_second
+    operator: '>='
+    value: 1000
+`)
+	resultsDir := filepath.Join(dir, "results")
+
+	viper.Set("results", resultsDir)
+	t.Cleanup(func() {
+		viper.Set("results", "")
+	})
+
+	testStartTime := time.Now()
+	archiver, err := archive.NewArchiver(zap.NewNop(), resultsDir, "iperf3-mock", testStartTime)
+	require.Nil(t, err)
+	_, err = archiver.Archive(parsers.Input{
+		TestStartTime: testStartTime,
+		TestTime:      testStartTime,
+		Round:         1,
+		Data:          []byte(iperf3Result),
+		Tester:        "iperf3",
+		ServerHost:    "servers-1",
+		ClientHost:    "servers-2",
+	})
+	require.Nil(t, err)
+
+	// The mean of the bits_per_second column is 800
+	err = report(reportCmd, []string{})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "assertions failed for tests: iperf3-mock")
+}
+
+func TestReportCommandHTML(t *testing.T) {
+	dir := writeTestDefinition(t, "")
+	resultsDir := filepath.Join(dir, "results")
+
+	// Add the html output to the outputs of the test
+	path := viper.GetString("testdefinition")
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	content = []byte(strings.Replace(string(content), "  outputs:\n", fmt.Sprintf(`  outputs:
+  - name: html
+    html:
+      filePath: %s
+      columns:
+      - bits_per_second
+`, dir), 1))
+	require.Nil(t, os.WriteFile(path, content, 0640))
+
+	viper.Set("results", resultsDir)
+	t.Cleanup(func() {
+		viper.Set("results", "")
+	})
+
+	testStartTime := time.Now()
+	archiver, err := archive.NewArchiver(zap.NewNop(), resultsDir, "iperf3-mock", testStartTime)
+	require.Nil(t, err)
+	_, err = archiver.Archive(parsers.Input{
+		TestStartTime: testStartTime,
+		TestTime:      testStartTime,
+		Round:         1,
+		Data:          []byte(iperf3Result),
+		Tester:        "iperf3",
+		ServerHost:    "servers-1",
+		ClientHost:    "servers-2",
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, report(reportCmd, []string{}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "ancientt-*-iperf3-report.html"))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+	html, err := os.ReadFile(files[0])
+	require.Nil(t, err)
+	assert.Contains(t, string(html), "<tr><td>servers-1</td><td>servers-2</td>")
+	// The test definition is only in the report when the test has been set on the output
+	assert.Contains(t, string(html), "name: iperf3-mock")
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/runners"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the testdefinitions without running anything, e.g., no runner (cluster) access is needed.",
+	Args:  cobra.NoArgs,
+	RunE:  validate,
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	// The config is validated against the config schema during load
+	if err := setup(); err != nil {
+		return err
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+
+	fmt.Println(aurora.Green(fmt.Sprintf("testdefinition %s is valid", viper.GetString("testdefinition"))))
+	return nil
+}
+
+// validateConfig check that the runner, tester, parser and output names of the config exist
+func validateConfig(cfg *config.Config) error {
+	errs := []error{}
+
+	runnerName := strings.ToLower(cfg.Runner.Name)
+	if _, ok := runners.Factories[runnerName]; !ok {
+		errs = append(errs, fmt.Errorf("runner with name %s not found", runnerName))
+	}
+
+	for _, test := range cfg.Tests {
+		testerName := strings.ToLower(test.Type)
+		if _, ok := testers.Factories[testerName]; !ok {
+			errs = append(errs, fmt.Errorf("test %s: tester with name %s not found", test.Name, testerName))
+		}
+		if _, ok := parsers.Factories[testerName]; !ok {
+			errs = append(errs, fmt.Errorf("test %s: parser with name %s not found", test.Name, testerName))
+		}
+
+		for _, output := range test.Outputs {
+			if _, ok := outputs.Factories[output.Name]; !ok {
+				errs = append(errs, fmt.Errorf("test %s: output with name %s not found", test.Name, output.Name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDefinition = `version: '0'
+runner:
+  name: mock
+tests:
+- name: iperf3-mock
+  type: iperf3
+  outputs:
+  - name: dump
+    dump:
+      filePath: %s
+      namePattern: 'ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.txt'
+  runOptions:
+    rounds: 1
+  hosts:
+    clients:
+    - name: all
+      all: true
+    servers:
+    - name: all
+      all: true
+  iperf3:
+    udp: false
+`
+
+// writeTestDefinition write the test definition (with the extra options for the test appended) to a temp dir and
+// set it as the testdefinition, returns the temp dir
+func writeTestDefinition(t *testing.T, extra string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "testdefinition.yaml")
+	require.Nil(t, os.WriteFile(path, []byte(fmt.Sprintf(testDefinition, dir)+extra), 0640))
+
+	viper.Set("testdefinition", path)
+	t.Cleanup(func() {
+		viper.Set("testdefinition", "")
+	})
+
+	return dir
+}
+
+func TestValidateConfig(t *testing.T) {
+	cfg := &config.Config{
+		Runner: config.Runner{
+			Name: "mock",
+		},
+		Tests: []*config.Test{
+			{
+				Name: "test",
+				Type: "iperf3",
+				Outputs: []config.Output{
+					{Name: "csv"},
+				},
+			},
+		},
+	}
+	assert.Nil(t, validateConfig(cfg))
+
+	cfg.Runner.Name = "doesnotexist"
+	cfg.Tests[0].Type = "doesnotexist"
+	cfg.Tests[0].Outputs[0].Name = "doesnotexist"
+	err := validateConfig(cfg)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "runner with name doesnotexist not found")
+	assert.Contains(t, err.Error(), "test test: tester with name doesnotexist not found")
+	assert.Contains(t, err.Error(), "test test: parser with name doesnotexist not found")
+	assert.Contains(t, err.Error(), "test test: output with name doesnotexist not found")
+}
+
+func TestValidateAndPlanCommands(t *testing.T) {
+	writeTestDefinition(t, "")
+
+	assert.Nil(t, validate(validateCmd, []string{}))
+	assert.Nil(t, planTests(planCmd, []string{}))
+}
+
+func TestValidateCommandWithoutNamePattern(t *testing.T) {
+	writeTestDefinition(t, "")
+
+	// The outputs without a name pattern must get the default name pattern before the validation
+	path := viper.GetString("testdefinition")
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	content = []byte(strings.Replace(string(content), "  outputs:\n", `  outputs:
+  - name: csv
+    csv:
+      filePath: .
+  - name: excelize
+    excelize:
+      filePath: .
+  - name: sqlite
+    sqlite:
+      filePath: .
+`, 1))
+	require.Nil(t, os.WriteFile(path, content, 0640))
+
+	assert.Nil(t, validate(validateCmd, []string{}))
+}
+
+func TestValidateCommandInvalidConfigs(t *testing.T) {
+	for name, test := range map[string]struct {
+		outputs string
+		extra   string
+		err     string
+	}{
+		"heatmap-without-column": {
+			outputs: `  - name: heatmap
+    heatmap:
+      filePath: .
+`,
+			err: "'Column' failed on the 'required' tag",
+		},
+		"html-without-columns": {
+			outputs: `  - name: html
+    html:
+      filePath: .
+`,
+			err: "'Columns' failed on the 'required' tag",
+		},
+		"unknown-topology": {
+			extra: "  topology: bogus\n",
+			err:   "'Topology' failed on the 'oneof' tag",
+		},
+		"unknown-ip-family": {
+			extra: "  ipFamily: IPv6\n",
+			err:   "'IPFamily' failed on the 'oneof' tag",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeTestDefinition(t, test.extra)
+
+			// Add the output to the outputs of the test
+			path := viper.GetString("testdefinition")
+			content, err := os.ReadFile(path)
+			require.Nil(t, err)
+			content = []byte(strings.Replace(string(content), "  outputs:\n", "  outputs:\n"+test.outputs, 1))
+			require.Nil(t, os.WriteFile(path, content, 0640))
+
+			err = validate(validateCmd, []string{})
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
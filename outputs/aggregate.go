@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outputs
+
+import (
+	"github.com/galexrt/ancientt/pkg/config"
+)
+
+// Aggregate running min, max and mean of values, e.g., of a column per server and client host pair
+type Aggregate struct {
+	sum   float64
+	count int
+	min   float64
+	max   float64
+}
+
+// Add add the value to the aggregate
+func (a *Aggregate) Add(val float64) {
+	if a.count == 0 || val < a.min {
+		a.min = val
+	}
+	if a.count == 0 || val > a.max {
+		a.max = val
+	}
+	a.sum += val
+	a.count++
+}
+
+// Count return the count of added values
+func (a *Aggregate) Count() int {
+	return a.count
+}
+
+// Min return the minimum of the added values
+func (a *Aggregate) Min() float64 {
+	return a.min
+}
+
+// Max return the maximum of the added values
+func (a *Aggregate) Max() float64 {
+	return a.max
+}
+
+// Mean return the mean of the added values
+func (a *Aggregate) Mean() float64 {
+	return a.sum / float64(a.count)
+}
+
+// Value return the aggregated value for the aggregation, the mean is returned by default
+func (a *Aggregate) Value(aggregation config.Aggregation) float64 {
+	switch aggregation {
+	case config.AggregationMin:
+		return a.Min()
+	case config.AggregationMax:
+		return a.Max()
+	}
+	return a.Mean()
+}
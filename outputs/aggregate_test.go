@@ -0,0 +1,37 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	a := &Aggregate{}
+	for _, val := range []float64{30, 10, 20} {
+		a.Add(val)
+	}
+
+	assert.Equal(t, 3, a.Count())
+	for aggregation, expected := range map[config.Aggregation]float64{
+		config.AggregationMean: 20,
+		config.AggregationMin:  10,
+		config.AggregationMax:  30,
+	} {
+		assert.Equal(t, expected, a.Value(aggregation), aggregation)
+	}
+}
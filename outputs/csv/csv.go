@@ -49,9 +49,6 @@ func NewCSVOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output)
 		files:   map[string]*os.File{},
 		writers: map[string]*csv.Writer{},
 	}
-	if c.config.FilePath.NamePattern == "" {
-		c.config.FilePath.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.csv"
-	}
 	return c, nil
 }
 
@@ -66,6 +63,9 @@ func (c CSV) Do(data outputs.Data) error {
 	if err != nil {
 		return err
 	}
+	if data.IsSummary() {
+		filename = outputs.GetSummaryFilename(filename)
+	}
 
 	var writeHeaders bool
 
@@ -89,15 +89,18 @@ func (c CSV) Do(data outputs.Data) error {
 
 	defer writer.Flush()
 
-	if writeHeaders {
-		// Iterate over header columns
-		headers := []string{}
-		for _, r := range dataTable.Headers {
-			if r == nil {
-				continue
-			}
-			headers = append(headers, util.CastToString(r.Value))
+	// Cells of deleted columns (nil headers) are skipped, nil cells of existing columns are written empty
+	headers := []string{}
+	indexes := []int{}
+	for i, r := range dataTable.Headers {
+		if r == nil {
+			continue
 		}
+		headers = append(headers, util.CastToString(r.Value))
+		indexes = append(indexes, i)
+	}
+
+	if writeHeaders {
 		if err := writer.Write(headers); err != nil {
 			return err
 		}
@@ -105,12 +108,13 @@ func (c CSV) Do(data outputs.Data) error {
 
 	// Iterate over data columns
 	for _, row := range dataTable.Rows {
-		cells := []string{}
-		for _, r := range row {
-			if r == nil {
+		cells := make([]string, 0, len(indexes))
+		for _, index := range indexes {
+			if len(row) <= index || row[index] == nil {
+				cells = append(cells, "")
 				continue
 			}
-			cells = append(cells, util.CastToString(r.Value))
+			cells = append(cells, util.CastToString(row[index].Value))
 		}
 		if len(cells) == 0 {
 			continue
@@ -15,26 +15,59 @@ package outputs
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+)
+
+// DataType type of the parsed data
+type DataType string
+
+const (
+	// DataTypeInterval data for each interval / entry of a test run (default)
+	DataTypeInterval DataType = "interval"
+	// DataTypeSummary summary data of a test run, e.g., the IPerf3 end results
+	DataTypeSummary DataType = "summary"
 )
 
 // Data structured parsed data
 type Data struct {
+	// Test name of the test the data is from
+	Test           string
 	TestStartTime  time.Time
 	TestTime       time.Time
 	Tester         string
 	ServerHost     string
 	ClientHost     string
+	IPFamily       config.IPFamily
 	AdditionalInfo string
+	Type           DataType
 	Data           DataFormat
 }
 
+// IsSummary return true if the Data contains summary data
+func (d Data) IsSummary() bool {
+	return d.Type == DataTypeSummary
+}
+
 // DataFormat DataFormat interface that must be implemented by data formats, e.g., Table.
 type DataFormat interface {
 	// Transform run transformations on the `Data`.
 	Transform(ts []*config.Transformation) error
+	// Copy return a deep copy of the data format.
+	Copy() DataFormat
+}
+
+// Copy return a copy of the Data with a deep copy of the underlying DataFormat, so the copy can
+// be transformed without affecting the original Data (e.g., for output specific transformations).
+func (d Data) Copy() Data {
+	out := d
+	if d.Data != nil {
+		out.Data = d.Data.Copy()
+	}
+	return out
 }
 
 // Table Data format for data in Table form
@@ -58,7 +91,7 @@ func (d *Table) Transform(ts []*config.Transformation) error {
 			return err
 		}
 		if index == -1 {
-			return nil
+			continue
 		}
 
 		switch t.Action {
@@ -77,7 +110,11 @@ func (d *Table) Transform(ts []*config.Transformation) error {
 		}
 
 		for row := range d.Rows {
-			if len(d.Rows[row]) < index {
+			if len(d.Rows[row]) <= index || d.Rows[row][index] == nil {
+				// Keep the added column aligned with its header for rows without a value
+				if t.Action == config.TransformationActionAdd {
+					d.Rows[row] = append(d.Rows[row], nil)
+				}
 				continue
 			}
 
@@ -89,7 +126,10 @@ func (d *Table) Transform(ts []*config.Transformation) error {
 			case config.TransformationActionDelete:
 				d.Rows[row][index] = nil
 			case config.TransformationActionReplace:
-				d.Rows[row][index].Value = d.modifyValue(d.Rows[row][index].Value, t)
+				// Replace the cell instead of changing its value, as cells might be shared between rows
+				d.Rows[row][index] = &Row{
+					Value: d.modifyValue(d.Rows[row][index].Value, t),
+				}
 			}
 		}
 	}
@@ -98,6 +138,10 @@ func (d *Table) Transform(ts []*config.Transformation) error {
 }
 
 func (d *Table) modifyValue(in interface{}, t *config.Transformation) interface{} {
+	if t.Modifier == nil {
+		return in
+	}
+
 	value, ok := in.(float64)
 	if !ok {
 		valInt, ok := in.(int64)
@@ -121,6 +165,34 @@ func (d *Table) modifyValue(in interface{}, t *config.Transformation) interface{
 	return in
 }
 
+// Copy return a deep copy of the Table
+func (d *Table) Copy() DataFormat {
+	out := &Table{
+		Headers: copyRows(d.Headers),
+		Rows:    make([][]*Row, len(d.Rows)),
+	}
+	for i := range d.Rows {
+		out.Rows[i] = copyRows(d.Rows[i])
+	}
+	return out
+}
+
+func copyRows(in []*Row) []*Row {
+	if in == nil {
+		return nil
+	}
+	out := make([]*Row, len(in))
+	for i, r := range in {
+		if r == nil {
+			continue
+		}
+		out[i] = &Row{
+			Value: r.Value,
+		}
+	}
+	return out
+}
+
 // CheckIfHeaderExists check if a header exists by name in the Table
 func (d *Table) CheckIfHeaderExists(name interface{}) (int, bool) {
 	for k, c := range d.Headers {
@@ -151,3 +223,86 @@ func (d *Table) GetHeaderIndexByName(name string) (int, error) {
 	}
 	return -1, nil
 }
+
+// FilterRows return the rows which match the filters (column name and value).
+// Filters on columns which don't exist in the Table are ignored, e.g., the `kind` column only exists for the IPerf3 tester.
+func (d *Table) FilterRows(filters map[string]string) ([][]*Row, error) {
+	filterIndexes := map[int]string{}
+	for column, value := range filters {
+		index, err := d.GetHeaderIndexByName(column)
+		if err != nil {
+			return nil, err
+		}
+		if index == -1 {
+			continue
+		}
+		filterIndexes[index] = value
+	}
+
+	rows := [][]*Row{}
+rows:
+	for _, row := range d.Rows {
+		for index, value := range filterIndexes {
+			if len(row) <= index || row[index] == nil || util.CastToString(row[index].Value) != value {
+				continue rows
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ColumnValues return the numeric values of the columns of the rows which match the filters (column name and value).
+// Columns which don't exist in the Table are skipped, NaN and infinite values are skipped.
+func (d *Table) ColumnValues(columns []string, filters map[string]string) (map[string][]float64, error) {
+	values := map[string][]float64{}
+
+	indexes := map[string]int{}
+	for _, column := range columns {
+		index, err := d.GetHeaderIndexByName(column)
+		if err != nil {
+			return nil, err
+		}
+		if index == -1 {
+			continue
+		}
+		indexes[column] = index
+	}
+
+	rows, err := d.FilterRows(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for column, index := range indexes {
+			val, ok, err := FloatValue(row, index)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get value of column %s. %+v", column, err)
+			}
+			if !ok {
+				continue
+			}
+			values[column] = append(values[column], val)
+		}
+	}
+
+	return values, nil
+}
+
+// FloatValue return the numeric value of the cell at the index of the row, false is returned when the cell is empty
+// or the value is NaN or infinite
+func FloatValue(row []*Row, index int) (float64, bool, error) {
+	if index < 0 || len(row) <= index || row[index] == nil {
+		return 0, false, nil
+	}
+	val, err := util.CastNumberToFloat64(row[index].Value)
+	if err != nil {
+		return 0, false, err
+	}
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return 0, false, nil
+	}
+	return val, true, nil
+}
@@ -15,12 +15,14 @@ package outputs
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/pkg/util"
 	"github.com/k0kubun/pp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDataTableTransform(t *testing.T) {
@@ -79,3 +81,106 @@ func TestDataTableTransform(t *testing.T) {
 	fmt.Println("===\nAFTER TRANSFORMATION:")
 	pp.Println(dataTable)
 }
+
+func TestDataTableTransformEmptyCells(t *testing.T) {
+	dataTable := Table{
+		Headers: []*Row{
+			{Value: "socket"},
+			{Value: "bits_per_second"},
+		},
+		Rows: [][]*Row{
+			{{Value: int64(5)}, {Value: float64(1000)}},
+			// Sum row without a socket
+			{nil, {Value: float64(2000)}},
+		},
+	}
+
+	err := dataTable.Transform([]*config.Transformation{
+		{
+			Action:      config.TransformationActionAdd,
+			Source:      "socket",
+			Destination: "socket_copy",
+		},
+		{
+			Action:         config.TransformationActionAdd,
+			Source:         "bits_per_second",
+			Destination:    "kilobits_per_second",
+			Modifier:       util.FloatPointer(float64(1000)),
+			ModifierAction: config.ModifierActionDivison,
+		},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, dataTable.Headers, 4)
+	for _, row := range dataTable.Rows {
+		assert.Len(t, row, len(dataTable.Headers))
+	}
+	assert.Nil(t, dataTable.Rows[1][2])
+	assert.Equal(t, float64(2), dataTable.Rows[1][3].Value)
+}
+
+func TestDataCopy(t *testing.T) {
+	data := Data{
+		Tester: "iperf3",
+		Data: &Table{
+			Headers: []*Row{
+				{Value: "bits_per_second"},
+			},
+			Rows: [][]*Row{
+				{{Value: float64(1000)}},
+			},
+		},
+	}
+
+	cp := data.Copy()
+	assert.Equal(t, data, cp)
+
+	err := cp.Data.Transform([]*config.Transformation{
+		{
+			Action:         config.TransformationActionReplace,
+			Source:         "bits_per_second",
+			Destination:    "kilobits_per_second",
+			Modifier:       util.FloatPointer(float64(1000)),
+			ModifierAction: config.ModifierActionDivison,
+		},
+	})
+	assert.Nil(t, err)
+
+	// The original data must not be changed by transformations on the copy
+	orig := data.Data.(*Table)
+	assert.Equal(t, "bits_per_second", orig.Headers[0].Value)
+	assert.Equal(t, float64(1000), orig.Rows[0][0].Value)
+
+	copied := cp.Data.(*Table)
+	assert.Equal(t, "kilobits_per_second", copied.Headers[0].Value)
+	assert.Equal(t, float64(1), copied.Rows[0][0].Value)
+}
+
+func TestTableColumnValues(t *testing.T) {
+	dataTable := &Table{
+		Headers: []*Row{
+			{Value: "kind"},
+			{Value: "bits_per_second"},
+			{Value: "retransmits"},
+		},
+		Rows: [][]*Row{
+			{{Value: "sum"}, {Value: float64(100)}, {Value: int64(1)}},
+			{{Value: "stream"}, {Value: float64(50)}, {Value: int64(2)}},
+			{{Value: "sum"}, {Value: math.NaN()}, {Value: int64(3)}},
+		},
+	}
+
+	values, err := dataTable.ColumnValues([]string{"bits_per_second", "retransmits", "doesnotexist"}, map[string]string{"kind": "sum"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]float64{
+		"bits_per_second": {100},
+		"retransmits":     {1, 3},
+	}, values)
+
+	// Filters on columns which don't exist are ignored
+	values, err = dataTable.ColumnValues([]string{"bits_per_second"}, map[string]string{"doesnotexist": "sum"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]float64{
+		"bits_per_second": {100, 50},
+	}, values)
+}
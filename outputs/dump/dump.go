@@ -46,9 +46,6 @@ func NewDumpOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output
 		config: outCfg.Dump,
 		files:  map[string]*os.File{},
 	}
-	if dump.config.FilePath.NamePattern == "" {
-		dump.config.FilePath.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.txt"
-	}
 	return dump, nil
 }
 
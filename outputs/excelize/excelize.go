@@ -26,8 +26,13 @@ import (
 	"github.com/galexrt/ancientt/pkg/util"
 )
 
-// NameExcelize Excelize output name
-const NameExcelize = "excelize"
+const (
+	// NameExcelize Excelize output name
+	NameExcelize = "excelize"
+
+	dataSheetName    = "Sheet1"
+	summarySheetName = "Summary"
+)
 
 func init() {
 	outputs.Factories[NameExcelize] = NewExcelizeOutput
@@ -43,7 +48,8 @@ type Excelize struct {
 
 type fileState struct {
 	file *excelize.File
-	row  int
+	// rows current row per sheet name
+	rows map[string]int
 }
 
 // NewExcelizeOutput return a new Excelize tester instance
@@ -53,9 +59,6 @@ func NewExcelizeOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Ou
 		config: outCfg.Excelize,
 		files:  map[string]*fileState{},
 	}
-	if excelize.config.FilePath.NamePattern == "" {
-		excelize.config.FilePath.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.xlsx"
-	}
 	if excelize.config.SaveAfterRows == 0 {
 		excelize.config.SaveAfterRows = 200
 	}
@@ -88,7 +91,9 @@ func (e Excelize) Do(data outputs.Data) error {
 		// at the first row again
 		state := &fileState{
 			file: excelFile,
-			row:  1,
+			rows: map[string]int{
+				dataSheetName: 1,
+			},
 		}
 		fState = state
 		e.files[filePath] = state
@@ -101,12 +106,23 @@ func (e Excelize) Do(data outputs.Data) error {
 		return err
 	}
 
-	if fState.row == 1 {
-		if err := e.inputData(fState.row, [][]*outputs.Row{dataTable.Headers}, fState); err != nil {
+	sheet := dataSheetName
+	if data.IsSummary() {
+		sheet = summarySheetName
+		if _, ok := fState.rows[sheet]; !ok {
+			if _, err := fState.file.NewSheet(sheet); err != nil {
+				return err
+			}
+			fState.rows[sheet] = 1
+		}
+	}
+
+	if fState.rows[sheet] == 1 {
+		if err := e.inputData(sheet, [][]*outputs.Row{dataTable.Headers}, fState); err != nil {
 			return err
 		}
 	}
-	if err := e.inputData(fState.row, dataTable.Rows, fState); err != nil {
+	if err := e.inputData(sheet, dataTable.Rows, fState); err != nil {
 		return err
 	}
 
@@ -118,10 +134,11 @@ func (e Excelize) Do(data outputs.Data) error {
 	return nil
 }
 
-func (e Excelize) inputData(startRow int, rows [][]*outputs.Row, fState *fileState) error {
+func (e Excelize) inputData(sheet string, rows [][]*outputs.Row, fState *fileState) error {
+	startRow := fState.rows[sheet]
 	// Iterate over data columns to get the first row of data.
 	for i, row := range rows {
-		fState.row++
+		fState.rows[sheet]++
 
 		// Set each cell value
 		for j, r := range row {
@@ -129,7 +146,7 @@ func (e Excelize) inputData(startRow int, rows [][]*outputs.Row, fState *fileSta
 				continue
 			}
 
-			if err := fState.file.SetCellValue("Sheet1", fmt.Sprintf("%s%d", util.IntToChar(j+1), startRow+i), r.Value); err != nil {
+			if err := fState.file.SetCellValue(sheet, fmt.Sprintf("%s%d", util.IntToChar(j+1), startRow+i), r.Value); err != nil {
 				// TODO Return a final concated error after the whole data has been written
 				e.logger.Error("unable to set cell value in excelize file", zap.String("filepath", fState.file.Path), zap.Error(err))
 			}
@@ -47,9 +47,6 @@ func NewGoChartOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Out
 		config: outCfg.GoChart,
 		files:  map[string]struct{}{},
 	}
-	if goChart.config.NamePattern == "" {
-		goChart.config.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}-{{ .Data.ServerHost }}_{{ .Data.ClientHost }}-{{ .Extra.Axises }}.png"
-	}
 	return goChart, nil
 }
 
@@ -58,6 +55,10 @@ func (gc GoChart) Do(data outputs.Data) error {
 	if _, ok := data.Data.(*outputs.Table); !ok {
 		return fmt.Errorf("data not in data table format for gochart output")
 	}
+	// Summary data has no time / interval column to draw a chart from
+	if data.IsSummary() {
+		return nil
+	}
 
 	// Iterate over wanted graph types
 	for _, graph := range gc.config.Graphs {
@@ -111,6 +112,11 @@ func (gc *GoChart) drawAxisChart(chartOpts *config.GoChartGraph, data outputs.Da
 	}
 	outPath := filepath.Join(gc.config.FilePath.FilePath, filename)
 
+	rows, err := withoutSumRows(dataTable)
+	if err != nil {
+		return err
+	}
+
 	vals := map[string][]float64{}
 	for _, search := range []string{chartOpts.TimeColumn, chartOpts.RightY, chartOpts.LeftY} {
 		headIndex, err := dataTable.GetHeaderIndexByName(search)
@@ -118,7 +124,7 @@ func (gc *GoChart) drawAxisChart(chartOpts *config.GoChartGraph, data outputs.Da
 			return err
 		}
 
-		for _, r := range dataTable.Rows {
+		for _, r := range rows {
 			// Skip empty rows
 			if len(r) == 0 {
 				continue
@@ -182,6 +188,27 @@ func (gc *GoChart) drawAxisChart(chartOpts *config.GoChartGraph, data outputs.Da
 	return nil
 }
 
+// withoutSumRows return the rows of the data table without the sum rows (`kind` column value `sum`, e.g., of the
+// IPerf3 tester), as the sum of the streams would otherwise be drawn as a value of the same series as the streams
+func withoutSumRows(dataTable *outputs.Table) ([][]*outputs.Row, error) {
+	kindIndex, err := dataTable.GetHeaderIndexByName(config.ColumnKind)
+	if err != nil {
+		return nil, err
+	}
+	if kindIndex == -1 {
+		return dataTable.Rows, nil
+	}
+
+	rows := [][]*outputs.Row{}
+	for _, r := range dataTable.Rows {
+		if len(r) > kindIndex && r[kindIndex] != nil && util.CastToString(r[kindIndex].Value) == config.KindSum {
+			continue
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
 func (gc *GoChart) additionalSeries(chartOpts *config.GoChartGraph, graph *chart.Chart, series *chart.ContinuousSeries) {
 	graph.Series = append(graph.Series, chart.LastValueAnnotationSeries(series), chart.LastValueAnnotationSeries(series))
 
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
 	"github.com/galexrt/ancientt/outputs/tests"
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/pkg/util"
@@ -63,3 +64,27 @@ func TestDo(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, fInfo)
 }
+
+func TestWithoutSumRows(t *testing.T) {
+	table := &outputs.Table{
+		Headers: []*outputs.Row{
+			{Value: "kind"},
+			{Value: "bits_per_second"},
+		},
+		Rows: [][]*outputs.Row{
+			{{Value: "stream"}, {Value: float64(100)}},
+			{{Value: "stream"}, {Value: float64(200)}},
+			{{Value: "sum"}, {Value: float64(300)}},
+		},
+	}
+
+	rows, err := withoutSumRows(table)
+	require.Nil(t, err)
+	assert.Equal(t, table.Rows[:2], rows)
+
+	// Tables without a kind column are used as is
+	table.Headers[0].Value = "target"
+	rows, err = withoutSumRows(table)
+	require.Nil(t, err)
+	assert.Equal(t, table.Rows, rows)
+}
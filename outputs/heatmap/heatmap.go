@@ -0,0 +1,358 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heatmap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+	"go.uber.org/zap"
+)
+
+// NameHeatmap Heatmap output name
+const NameHeatmap = "heatmap"
+
+const (
+	cellSize       = 64
+	margin         = 16
+	titleHeight    = 32
+	legendHeight   = 32
+	labelFontSize  = 10.0
+	valueFontSize  = 9.0
+	emptyCellLabel = "-"
+)
+
+var (
+	colorBad   = drawing.Color{R: 215, G: 48, B: 39, A: 255}
+	colorMid   = drawing.Color{R: 254, G: 224, B: 139, A: 255}
+	colorGood  = drawing.Color{R: 26, G: 152, B: 80, A: 255}
+	colorEmpty = drawing.Color{R: 230, G: 230, B: 230, A: 255}
+)
+
+func init() {
+	outputs.Factories[NameHeatmap] = NewHeatmapOutput
+}
+
+// Heatmap Heatmap output structure
+type Heatmap struct {
+	outputs.Output
+	logger *zap.Logger
+	config *config.Heatmap
+	// data first received data, used for templating the file names
+	data  *outputs.Data
+	cells map[string]map[string]*outputs.Aggregate
+	files map[string]struct{}
+}
+
+// NewHeatmapOutput return a new Heatmap output instance
+func NewHeatmapOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	h := &Heatmap{
+		logger: logger.With(zap.String("output", NameHeatmap)),
+		config: outCfg.Heatmap,
+		cells:  map[string]map[string]*outputs.Aggregate{},
+		files:  map[string]struct{}{},
+	}
+	return h, nil
+}
+
+// Do aggregate the configured column of the data per server and client host pair
+func (h *Heatmap) Do(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for heatmap output")
+	}
+	if data.IsSummary() != (h.config.DataType == string(outputs.DataTypeSummary)) {
+		return nil
+	}
+
+	if h.data == nil {
+		info := data
+		info.Data = nil
+		h.data = &info
+	}
+
+	values, err := dataTable.ColumnValues([]string{h.config.Column}, h.config.Filters)
+	if err != nil {
+		return err
+	}
+	if len(values[h.config.Column]) == 0 {
+		return nil
+	}
+
+	if _, ok := h.cells[data.ServerHost]; !ok {
+		h.cells[data.ServerHost] = map[string]*outputs.Aggregate{}
+	}
+	c, ok := h.cells[data.ServerHost][data.ClientHost]
+	if !ok {
+		c = &outputs.Aggregate{}
+		h.cells[data.ServerHost][data.ClientHost] = c
+	}
+	for _, val := range values[h.config.Column] {
+		c.Add(val)
+	}
+
+	return nil
+}
+
+// matrix return the sorted server and client host names and the aggregated values,
+// the value is NaN when there is no data for a server and client host pair.
+func (h *Heatmap) matrix() ([]string, []string, [][]float64) {
+	servers := []string{}
+	clientsSet := map[string]struct{}{}
+	for server, clients := range h.cells {
+		servers = append(servers, server)
+		for client := range clients {
+			clientsSet[client] = struct{}{}
+		}
+	}
+	clients := []string{}
+	for client := range clientsSet {
+		clients = append(clients, client)
+	}
+	sort.Strings(servers)
+	sort.Strings(clients)
+
+	values := make([][]float64, len(servers))
+	for i, server := range servers {
+		values[i] = make([]float64, len(clients))
+		for j, client := range clients {
+			if c, ok := h.cells[server][client]; ok {
+				values[i][j] = c.Value(h.config.Aggregation)
+			} else {
+				values[i][j] = math.NaN()
+			}
+		}
+	}
+
+	return servers, clients, values
+}
+
+func (h *Heatmap) writeCSV(outPath string, servers []string, clients []string, values [][]float64) error {
+	buffer := bytes.NewBuffer([]byte{})
+	writer := csv.NewWriter(buffer)
+	writer.Comma = *h.config.Separator
+
+	if err := writer.Write(append([]string{"server_host\\client_host"}, clients...)); err != nil {
+		return err
+	}
+	for i, server := range servers {
+		record := []string{server}
+		for j := range clients {
+			if math.IsNaN(values[i][j]) {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, strconv.FormatFloat(values[i][j], 'f', -1, 64))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return util.WriteNewTruncFile(outPath, buffer.Bytes())
+}
+
+func (h *Heatmap) drawPNG(outPath string, servers []string, clients []string, values [][]float64) error {
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return err
+	}
+
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	for i := range values {
+		for _, val := range values[i] {
+			if math.IsNaN(val) {
+				continue
+			}
+			minVal = math.Min(minVal, val)
+			maxVal = math.Max(maxVal, val)
+		}
+	}
+
+	// Measure the host labels to know how much space is needed for them
+	measure, err := chart.PNG(1, 1)
+	if err != nil {
+		return err
+	}
+	measure.SetFont(font)
+	measure.SetFontSize(labelFontSize)
+	serverLabelWidth, clientLabelWidth := 0, 0
+	for _, server := range servers {
+		serverLabelWidth = max(serverLabelWidth, measure.MeasureText(server).Width())
+	}
+	for _, client := range clients {
+		clientLabelWidth = max(clientLabelWidth, measure.MeasureText(client).Width())
+	}
+
+	title := fmt.Sprintf("%s %s (%s) - rows: server, columns: client", h.config.Aggregation, h.config.Column, h.data.Tester)
+	measure.SetFontSize(labelFontSize + 2)
+	titleWidth := measure.MeasureText(title).Width()
+
+	gridLeft := margin + serverLabelWidth + margin
+	gridTop := titleHeight + clientLabelWidth + margin
+	width := max(gridLeft+len(clients)*cellSize, margin+titleWidth) + margin
+	height := gridTop + len(servers)*cellSize + legendHeight
+
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return err
+	}
+	r.SetFont(font)
+	r.SetFontColor(drawing.ColorBlack)
+
+	drawRect(r, 0, 0, width, height, drawing.ColorWhite)
+
+	r.SetFontSize(labelFontSize + 2)
+	r.Text(title, margin, titleHeight-margin/2)
+
+	r.SetFontSize(labelFontSize)
+	for i, server := range servers {
+		box := r.MeasureText(server)
+		r.Text(server, gridLeft-margin-box.Width(), gridTop+i*cellSize+(cellSize+box.Height())/2)
+	}
+	for j, client := range clients {
+		// The rotation is applied relative to the text position, so it must be reset for each label
+		r.SetTextRotation(chart.DegreesToRadians(270))
+		r.Text(client, gridLeft+j*cellSize+(cellSize+int(labelFontSize))/2, gridTop-margin/2)
+		r.ClearTextRotation()
+	}
+
+	r.SetFontSize(valueFontSize)
+	for i := range servers {
+		for j := range clients {
+			x, y := gridLeft+j*cellSize, gridTop+i*cellSize
+			label := emptyCellLabel
+			color := colorEmpty
+			if !math.IsNaN(values[i][j]) {
+				label = formatValue(values[i][j])
+				color = h.getColor(values[i][j], minVal, maxVal)
+			}
+			drawRect(r, x, y, cellSize, cellSize, color)
+			box := r.MeasureText(label)
+			r.Text(label, x+(cellSize-box.Width())/2, y+(cellSize+box.Height())/2)
+		}
+	}
+
+	if !math.IsInf(minVal, 1) {
+		r.SetFontSize(labelFontSize)
+		r.Text(fmt.Sprintf("min: %s, max: %s", formatValue(minVal), formatValue(maxVal)), margin, height-legendHeight/2+int(labelFontSize)/2)
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	if err := r.Save(buffer); err != nil {
+		return fmt.Errorf("failed to render heatmap to PNG file. %+v", err)
+	}
+
+	return util.WriteNewTruncFile(outPath, buffer.Bytes())
+}
+
+// getColor return the colour for the value on a red (bad) to green (good) scale
+func (h *Heatmap) getColor(val float64, minVal float64, maxVal float64) drawing.Color {
+	scale := 1.0
+	if maxVal > minVal {
+		scale = (val - minVal) / (maxVal - minVal)
+	}
+	if *h.config.LowerIsBetter {
+		scale = 1 - scale
+	}
+
+	if scale < 0.5 {
+		return blendColors(colorBad, colorMid, scale*2)
+	}
+	return blendColors(colorMid, colorGood, (scale-0.5)*2)
+}
+
+func blendColors(from drawing.Color, to drawing.Color, t float64) drawing.Color {
+	blend := func(a uint8, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return drawing.Color{
+		R: blend(from.R, to.R),
+		G: blend(from.G, to.G),
+		B: blend(from.B, to.B),
+		A: 255,
+	}
+}
+
+func drawRect(r chart.Renderer, x int, y int, width int, height int, color drawing.Color) {
+	r.SetFillColor(color)
+	r.SetStrokeColor(drawing.ColorWhite)
+	r.SetStrokeWidth(1)
+	r.MoveTo(x, y)
+	r.LineTo(x+width, y)
+	r.LineTo(x+width, y+height)
+	r.LineTo(x, y+height)
+	r.LineTo(x, y)
+	r.Close()
+	r.FillStroke()
+}
+
+func formatValue(val float64) string {
+	return strconv.FormatFloat(val, 'g', 4, 64)
+}
+
+// OutputFiles return a list of output files
+func (h *Heatmap) OutputFiles() []string {
+	list := []string{}
+	for file := range h.files {
+		list = append(list, file)
+	}
+	return list
+}
+
+// Close write the heatmap PNG and matrix CSV file from the aggregated data
+func (h *Heatmap) Close() error {
+	if h.data == nil || len(h.cells) == 0 {
+		h.logger.Warn("no data received for heatmap, no files written")
+		return nil
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(h.config.NamePattern, "", *h.data, map[string]interface{}{
+		"Column": h.config.Column,
+	})
+	if err != nil {
+		return err
+	}
+	pngPath := filepath.Join(h.config.FilePath.FilePath, filename)
+	csvPath := strings.TrimSuffix(pngPath, filepath.Ext(pngPath)) + ".csv"
+
+	servers, clients, values := h.matrix()
+
+	if err := h.writeCSV(csvPath, servers, clients, values); err != nil {
+		return err
+	}
+	h.files[csvPath] = struct{}{}
+
+	if err := h.drawPNG(pngPath, servers, clients, values); err != nil {
+		return err
+	}
+	h.files[pngPath] = struct{}{}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heatmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/tests"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHeatmap(t *testing.T) {
+	tempDir := t.TempDir()
+
+	outCfg := &config.Output{
+		Heatmap: &config.Heatmap{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			Column: "bits_per_second",
+			DataFilter: config.DataFilter{
+				RowFilter: config.RowFilter{
+					Filters: map[string]string{
+						"kind": "sum",
+					},
+				},
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	h, err := NewHeatmapOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 200)))
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 300)))
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node2", "node1", outputs.DataTypeInterval, 50)))
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "node3", outputs.DataTypeInterval, 400)))
+	// Summary data must be ignored with the default data type
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node2", "node3", outputs.DataTypeSummary, 1000)))
+	require.Nil(t, h.Close())
+
+	files := h.OutputFiles()
+	require.Len(t, files, 2)
+
+	pngPath := filepath.Join(tempDir, "ancientt-1000-iperf3-heatmap-bits_per_second.png")
+	assert.Contains(t, files, pngPath)
+	fInfo, err := os.Stat(pngPath)
+	require.Nil(t, err)
+	assert.NotZero(t, fInfo.Size())
+
+	csvPath := filepath.Join(tempDir, "ancientt-1000-iperf3-heatmap-bits_per_second.csv")
+	assert.Contains(t, files, csvPath)
+	out, err := os.ReadFile(csvPath)
+	require.Nil(t, err)
+	assert.Equal(t, `server_host\client_host;node1;node2;node3
+node1;;200;400
+node2;50;;
+`, string(out))
+}
+
+func TestHeatmapGetColor(t *testing.T) {
+	h := &Heatmap{
+		config: &config.Heatmap{
+			LowerIsBetter: util.BoolFalsePointer(),
+		},
+	}
+	assert.Equal(t, colorBad, h.getColor(0, 0, 10))
+	assert.Equal(t, colorMid, h.getColor(5, 0, 10))
+	assert.Equal(t, colorGood, h.getColor(10, 0, 10))
+
+	h.config.LowerIsBetter = util.BoolTruePointer()
+	assert.Equal(t, colorGood, h.getColor(0, 0, 10))
+	assert.Equal(t, colorBad, h.getColor(10, 0, 10))
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/stats"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/testers"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// NameHTML HTML output name
+const NameHTML = "html"
+
+// redacted value the secrets in the test definition are replaced with
+const redacted = "REDACTED"
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+func init() {
+	outputs.Factories[NameHTML] = NewHTMLOutput
+}
+
+// HTML HTML output structure
+type HTML struct {
+	outputs.Output
+	*outputs.Report
+	logger *zap.Logger
+	config *config.HTML
+	runner string
+	files  []string
+}
+
+// NewHTMLOutput return a new HTML output instance
+func NewHTMLOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	h := &HTML{
+		Report: outputs.NewReport(),
+		logger: logger.With(zap.String("output", NameHTML)),
+		config: outCfg.HTML,
+		files:  []string{},
+	}
+	if cfg != nil {
+		h.runner = cfg.Runner.Name
+	}
+	return h, nil
+}
+
+// Do collect the values of the configured columns per server and client host pair
+func (h *HTML) Do(data outputs.Data) error {
+	return h.Add(data, h.config.Columns, h.config.DataFilter)
+}
+
+// report data for the report template
+type report struct {
+	Test            string
+	Tester          string
+	Runner          string
+	TestStartTime   string
+	GeneratedAt     string
+	DataType        string
+	FailedHosts     []testers.HostStatus
+	SuccessfulHosts []testers.HostStatus
+	StatsHeaders    []string
+	Columns         []columnReport
+	AffectedServers []string
+	Plan            []planRow
+	TestDefinition  string
+}
+
+type columnReport struct {
+	Name  string
+	Chart template.HTML
+	Rows  []columnRow
+}
+
+type columnRow struct {
+	Server string
+	Client string
+	Stats  []string
+}
+
+type planRow struct {
+	Round         int
+	Server        string
+	ServerCommand string
+	Client        string
+	ClientCommand string
+}
+
+// OutputFiles return a list of output files
+func (h *HTML) OutputFiles() []string {
+	return h.files
+}
+
+// Close write the HTML report file
+func (h *HTML) Close() error {
+	data := h.Data()
+	if data == nil {
+		h.logger.Warn("no data and no plan received for html, no report written")
+		return nil
+	}
+
+	r := report{
+		Test:         data.Test,
+		Tester:       data.Tester,
+		Runner:       h.runner,
+		GeneratedAt:  time.Now().Format(util.TimeDateFormat),
+		DataType:     h.config.DataType,
+		StatsHeaders: stats.Headers,
+	}
+	if !data.TestStartTime.IsZero() {
+		r.TestStartTime = data.TestStartTime.Format(util.TimeDateFormat)
+	}
+	if h.Plan != nil {
+		r.FailedHosts, r.SuccessfulHosts = h.Plan.HostsStatus()
+	}
+	r.Columns = h.columns()
+	r.AffectedServers, r.Plan = h.planRows()
+
+	if h.Test != nil {
+		definition, err := testDefinition(h.Test)
+		if err != nil {
+			return fmt.Errorf("failed to marshal test definition. %+v", err)
+		}
+		r.TestDefinition = definition
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := reportTemplate.Execute(buffer, r); err != nil {
+		return fmt.Errorf("failed to render html report. %+v", err)
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(h.config.NamePattern, "", *data, nil)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(h.config.FilePath.FilePath, filename)
+	if err := util.WriteNewTruncFile(outPath, buffer.Bytes()); err != nil {
+		return err
+	}
+	h.files = append(h.files, outPath)
+
+	return nil
+}
+
+// columns return the statistics per host pair and a chart of the mean per host pair for each column
+func (h *HTML) columns() []columnReport {
+	pairs := h.HostPairs()
+
+	columns := []columnReport{}
+	for _, column := range h.config.Columns {
+		colReport := columnReport{Name: column}
+		bars := []chart.Value{}
+		for _, pair := range pairs {
+			values := h.Values(pair, column)
+			if len(values) == 0 {
+				continue
+			}
+
+			result := stats.Calculate(values)
+			row := columnRow{Server: pair.Server, Client: pair.Client}
+			for _, val := range result {
+				row.Stats = append(row.Stats, strconv.FormatFloat(val, 'g', 6, 64))
+			}
+			colReport.Rows = append(colReport.Rows, row)
+
+			bars = append(bars, chart.Value{
+				// The chart library doesn't escape the text in the SVG
+				Label: template.HTMLEscapeString(pair.Server + " > " + pair.Client),
+				// Index 3 is the mean, see stats.Headers
+				Value: result[3],
+			})
+		}
+		if len(colReport.Rows) == 0 {
+			continue
+		}
+
+		svg, err := barChart(column, bars)
+		if err != nil {
+			h.logger.Warn("failed to render chart, skipping chart", zap.String("column", column), zap.Error(err))
+		}
+		colReport.Chart = svg
+
+		columns = append(columns, colReport)
+	}
+
+	return columns
+}
+
+// barChart return a SVG bar chart of the values
+func barChart(column string, bars []chart.Value) (template.HTML, error) {
+	minVal, maxVal := 0.0, 0.0
+	for _, bar := range bars {
+		minVal = math.Min(minVal, bar.Value)
+		maxVal = math.Max(maxVal, bar.Value)
+	}
+	// The value range of the chart must not be zero
+	if minVal == maxVal {
+		maxVal = 1
+	}
+
+	graph := chart.BarChart{
+		// The chart library doesn't escape the text in the SVG
+		Title: template.HTMLEscapeString(column + " (mean)"),
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Bottom: 80},
+		},
+		Width:    max(640, len(bars)*60+120),
+		Height:   480,
+		BarWidth: 40,
+		XAxis: chart.Style{
+			TextRotationDegrees: 45,
+		},
+		YAxis: chart.YAxis{
+			Range: &chart.ContinuousRange{Min: minVal, Max: maxVal},
+		},
+		Bars: bars,
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := graph.Render(chart.SVG, buffer); err != nil {
+		return "", err
+	}
+	// The title and labels are escaped, so the SVG is safe to embed as is
+	return template.HTML(buffer.String()), nil
+}
+
+// planRows return the affected servers and a row per server and client task of the plan
+func (h *HTML) planRows() ([]string, []planRow) {
+	if h.Plan == nil {
+		return nil, nil
+	}
+
+	servers := []string{}
+	for _, server := range h.Plan.AffectedServers {
+		servers = append(servers, server.Name)
+	}
+	sort.Strings(servers)
+
+	rows := []planRow{}
+	for k, command := range h.Plan.Commands {
+		for _, task := range command {
+			if task == nil {
+				continue
+			}
+			if task.Sleep != 0 {
+				rows = append(rows, planRow{Round: k + 1, ServerCommand: fmt.Sprintf("sleep %s", task.Sleep)})
+				continue
+			}
+			// Tasks without host, e.g., of replayed results, aren't part of the plan
+			if task.Host == nil {
+				continue
+			}
+
+			row := planRow{
+				Round:         k + 1,
+				Server:        task.Host.Name,
+				ServerCommand: taskCommand(task),
+			}
+			if len(task.SubTasks) == 0 {
+				rows = append(rows, row)
+				continue
+			}
+			for _, subTask := range task.SubTasks {
+				if subTask == nil || subTask.Host == nil {
+					continue
+				}
+				row.Client = subTask.Host.Name
+				row.ClientCommand = taskCommand(subTask)
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	return servers, rows
+}
+
+func taskCommand(task *testers.Task) string {
+	return strings.TrimSpace(task.Command + " " + strings.Join(task.Args, " "))
+}
+
+// testDefinition return the test as YAML, with the secrets of the outputs redacted
+func testDefinition(test *config.Test) (string, error) {
+	redactedTest := *test
+	redactedTest.Outputs = make([]config.Output, len(test.Outputs))
+	for i, out := range test.Outputs {
+		if out.MySQL != nil && out.MySQL.DSN != "" {
+			mysql := *out.MySQL
+			mysql.DSN = redacted
+			out.MySQL = &mysql
+		}
+		if out.InfluxDB != nil && out.InfluxDB.Token != "" {
+			influxDB := *out.InfluxDB
+			influxDB.Token = redacted
+			out.InfluxDB = &influxDB
+		}
+		redactedTest.Outputs[i] = out
+	}
+
+	// The test is marshalled to a node first, to remove the options which aren't set (`null`)
+	node := &yaml.Node{}
+	if err := node.Encode(redactedTest); err != nil {
+		return "", err
+	}
+	removeNulls(node)
+
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// removeNulls remove the keys with a `null` value from the mappings of the node and its children
+func removeNulls(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		content := []*yaml.Node{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1].Tag == "!!null" {
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+	}
+	for _, child := range node.Content {
+		removeNulls(child)
+	}
+}
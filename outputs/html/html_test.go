@@ -0,0 +1,172 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package html
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/tests"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"go.uber.org/zap"
+)
+
+func generatePlan() *testers.Plan {
+	status := testers.NewStatus()
+	server := &testers.Host{Name: "node1"}
+	status.AddSuccessfulServer(server)
+	status.AddSuccessfulClient(&testers.Host{Name: "node2"})
+	status.AddFailedClient(&testers.Host{Name: "node3"}, fmt.Errorf("connection refused"))
+
+	return &testers.Plan{
+		TestStartTime: time.Unix(1000, 0),
+		Tester:        "iperf3",
+		AffectedServers: map[string]*testers.Host{
+			"node1": server,
+		},
+		Commands: [][]*testers.Task{
+			{
+				{
+					Host:    server,
+					Command: "iperf3",
+					Args:    []string{"--server"},
+					Status:  status,
+					SubTasks: []*testers.Task{
+						{Host: &testers.Host{Name: "node2"}, Command: "iperf3", Args: []string{"--client", "node1"}},
+						{Host: &testers.Host{Name: "node3"}, Command: "iperf3", Args: []string{"--client", "node1"}},
+					},
+				},
+				{Sleep: 5 * time.Second},
+			},
+		},
+	}
+}
+
+func newHTMLOutput(t *testing.T, tempDir string) outputs.Output {
+	outCfg := &config.Output{
+		HTML: &config.HTML{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			Columns: []string{"bits_per_second", "doesnotexist"},
+			DataFilter: config.DataFilter{
+				RowFilter: config.RowFilter{
+					Filters: map[string]string{
+						"kind": "sum",
+					},
+				},
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	h, err := NewHTMLOutput(zap.NewNop(), &config.Config{
+		Runner: config.Runner{Name: "mock"},
+	}, outCfg)
+	require.Nil(t, err)
+	return h
+}
+
+func TestHTML(t *testing.T) {
+	tempDir := t.TempDir()
+	h := newHTMLOutput(t, tempDir)
+
+	test := &config.Test{
+		Name: "iperf3-test",
+		Type: "iperf3",
+		Outputs: []config.Output{
+			{
+				Name: "mysql",
+				MySQL: &config.MySQL{
+					DSN: "user:secret@tcp(localhost:3306)/ancientt",
+				},
+			},
+		},
+	}
+	h.(outputs.PlanOutput).SetPlan(test, generatePlan())
+
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 200)))
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "<node3>", outputs.DataTypeInterval, 300)))
+	// Summary data must be ignored with the default data type
+	require.Nil(t, h.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeSummary, 1000)))
+	require.Nil(t, h.Close())
+
+	outPath := filepath.Join(tempDir, "ancientt-1000-iperf3-report.html")
+	assert.Equal(t, []string{outPath}, h.OutputFiles())
+	content, err := os.ReadFile(outPath)
+	require.Nil(t, err)
+	report := string(content)
+
+	for _, expected := range []string{
+		"<title>ancientt report - iperf3-test</title>",
+		"<tr><th>Runner</th><td>mock</td></tr>",
+		// Hosts status
+		"<tr><td>client</td><td>node3</td><td class=\"number\">1</td><td><code>connection refused</code><br></td></tr>",
+		"<tr><td>server</td><td>node1</td><td class=\"number\">1</td></tr>",
+		"<tr><td>client</td><td>node2</td><td class=\"number\">1</td></tr>",
+		// Results
+		"<h3>bits_per_second</h3>",
+		"<svg",
+		"<tr><td>node1</td><td>node2</td><td class=\"number\">2</td><td class=\"number\">100</td><td class=\"number\">200</td><td class=\"number\">150</td>",
+		"<tr><td>node1</td><td>&lt;node3&gt;</td><td class=\"number\">1</td>",
+		// Plan
+		"Affected servers: node1",
+		"<td>node1</td><td><code>iperf3 --server</code></td><td>node3</td><td><code>iperf3 --client node1</code></td>",
+		"<code>sleep 5s</code>",
+		// Test definition
+		"name: iperf3-test",
+		"dsn: REDACTED",
+	} {
+		assert.Contains(t, report, expected)
+	}
+	assert.NotContains(t, report, "doesnotexist")
+	assert.NotContains(t, report, "secret")
+	assert.NotContains(t, report, "<node3>")
+	// The secret must only be redacted in the report, not in the test itself
+	assert.Equal(t, "user:secret@tcp(localhost:3306)/ancientt", test.Outputs[0].MySQL.DSN)
+}
+
+func TestHTMLWithoutData(t *testing.T) {
+	tempDir := t.TempDir()
+	h := newHTMLOutput(t, tempDir)
+
+	// Without data and plan there is nothing to report
+	require.Nil(t, h.Close())
+	assert.Empty(t, h.OutputFiles())
+
+	// The report is written with the plan alone, e.g., when all hosts have failed
+	h.(outputs.PlanOutput).SetPlan(&config.Test{Name: "iperf3-test", Type: "iperf3"}, generatePlan())
+	require.Nil(t, h.Close())
+	require.Len(t, h.OutputFiles(), 1)
+	content, err := os.ReadFile(h.OutputFiles()[0])
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "<p>No results.</p>")
+	assert.Contains(t, string(content), "connection refused")
+}
+
+func TestBarChartEscapesTitle(t *testing.T) {
+	svg, err := barChart("<script>alert(1)</script>", []chart.Value{{Label: "node1 &gt; node2", Value: 1}})
+	require.Nil(t, err)
+	assert.NotContains(t, string(svg), "<script>")
+	assert.Contains(t, string(svg), "&lt;script&gt;")
+}
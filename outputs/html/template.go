@@ -0,0 +1,112 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package html
+
+// reportHTML template of the report, all styles are inlined so the report is a single self-contained file
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ancientt report - {{ .Test }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1, h2, h3 { color: #1f3a5f; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #eef2f7; }
+td.number { text-align: right; font-family: monospace; }
+.failed { color: #b00020; }
+.successful { color: #1b7f3b; }
+.chart { overflow-x: auto; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; }
+code { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>ancientt report - {{ .Test }}</h1>
+<table>
+<tr><th>Test</th><td>{{ .Test }}</td></tr>
+<tr><th>Tester</th><td>{{ .Tester }}</td></tr>
+<tr><th>Runner</th><td>{{ .Runner }}</td></tr>
+<tr><th>Test Start Time</th><td>{{ .TestStartTime }}</td></tr>
+<tr><th>Generated At</th><td>{{ .GeneratedAt }}</td></tr>
+</table>
+
+<h2>Hosts Status</h2>
+{{- if .FailedHosts }}
+<h3 class="failed">Failed Hosts</h3>
+<table>
+<tr><th>Role</th><th>Host</th><th>Count</th><th>Errors</th></tr>
+{{- range .FailedHosts }}
+<tr><td>{{ .Role }}</td><td>{{ .Host }}</td><td class="number">{{ .Count }}</td><td>{{ range .Errors }}<code>{{ . }}</code><br>{{ end }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p class="successful">No failed hosts.</p>
+{{- end }}
+{{- if .SuccessfulHosts }}
+<h3 class="successful">Successful Hosts</h3>
+<table>
+<tr><th>Role</th><th>Host</th><th>Count</th></tr>
+{{- range .SuccessfulHosts }}
+<tr><td>{{ .Role }}</td><td>{{ .Host }}</td><td class="number">{{ .Count }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h2>Results ({{ .DataType }} data)</h2>
+{{- if not .Columns }}
+<p>No results.</p>
+{{- end }}
+{{- $headers := .StatsHeaders }}
+{{- range .Columns }}
+<h3>{{ .Name }}</h3>
+{{- if .Chart }}
+<div class="chart">{{ .Chart }}</div>
+{{- end }}
+<table>
+<tr><th>Server</th><th>Client</th>{{ range $headers }}<th>{{ . }}</th>{{ end }}</tr>
+{{- range .Rows }}
+<tr><td>{{ .Server }}</td><td>{{ .Client }}</td>{{ range .Stats }}<td class="number">{{ . }}</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h2>Plan</h2>
+{{- if .AffectedServers }}
+<p>Affected servers: {{ range $i, $server := .AffectedServers }}{{ if $i }}, {{ end }}{{ $server }}{{ end }}</p>
+{{- end }}
+{{- if .Plan }}
+<details>
+<summary>Commands</summary>
+<table>
+<tr><th>Round</th><th>Server</th><th>Server Command</th><th>Client</th><th>Client Command</th></tr>
+{{- range .Plan }}
+<tr><td class="number">{{ .Round }}</td><td>{{ .Server }}</td><td><code>{{ .ServerCommand }}</code></td><td>{{ .Client }}</td><td><code>{{ .ClientCommand }}</code></td></tr>
+{{- end }}
+</table>
+</details>
+{{- else }}
+<p>No plan.</p>
+{{- end }}
+
+<h2>Test Definition</h2>
+{{- if .TestDefinition }}
+<pre>{{ .TestDefinition }}</pre>
+{{- else }}
+<p>No test definition.</p>
+{{- end }}
+</body>
+</html>
+`
@@ -0,0 +1,335 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package influxdb
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"go.uber.org/zap"
+)
+
+// NameInfluxDB InfluxDB output name
+const NameInfluxDB = "influxdb"
+
+const (
+	// timeColumn column the timestamp of the lines is taken from, the data test time is used when it is missing
+	timeColumn = "test_time"
+	// offsetColumn column with the offset (in seconds) of the interval to the test time, which is added to the timestamp
+	offsetColumn = "start"
+
+	writeTimeout = 30 * time.Second
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+func init() {
+	outputs.Factories[NameInfluxDB] = NewInfluxDBOutput
+}
+
+// InfluxDB InfluxDB output structure
+type InfluxDB struct {
+	outputs.Output
+	logger *zap.Logger
+	config *config.InfluxDB
+	client *http.Client
+	// retryInterval interval before the first retry of a failed write request, doubled with each further retry
+	retryInterval time.Duration
+	exclude       map[string]struct{}
+	tags          map[string]struct{}
+	files         map[string]*os.File
+	// batch lines which haven't been written to the InfluxDB yet
+	batch []string
+}
+
+// NewInfluxDBOutput return a new InfluxDB output instance
+func NewInfluxDBOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	i := &InfluxDB{
+		logger:        logger.With(zap.String("output", NameInfluxDB)),
+		config:        outCfg.InfluxDB,
+		client:        &http.Client{Timeout: writeTimeout},
+		retryInterval: time.Second,
+		exclude:       map[string]struct{}{},
+		tags:          map[string]struct{}{},
+		files:         map[string]*os.File{},
+		batch:         []string{},
+	}
+	if i.config.FilePath.FilePath == "" && i.config.URL == "" {
+		return nil, fmt.Errorf("either filePath or url must be set for influxdb output")
+	}
+	for _, column := range i.config.ExcludeColumns {
+		i.exclude[column] = struct{}{}
+	}
+	for _, column := range i.config.TagColumns {
+		i.tags[column] = struct{}{}
+	}
+
+	return i, nil
+}
+
+// Do convert the rows to line protocol and write them to the file and / or InfluxDB
+func (i *InfluxDB) Do(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for influxdb output")
+	}
+
+	measurement, err := outputs.GetFilenameFromPattern(i.config.MeasurementPattern, "", data, nil)
+	if err != nil {
+		return err
+	}
+	if data.IsSummary() {
+		measurement = outputs.GetSummaryTableName(measurement)
+	}
+
+	lines := i.lines(measurement, data, dataTable)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if i.config.FilePath.FilePath != "" {
+		if err := i.writeFile(data, lines); err != nil {
+			return err
+		}
+	}
+
+	if i.config.URL != "" {
+		i.batch = append(i.batch, lines...)
+		for len(i.batch) >= i.config.BatchSize {
+			if err := i.write(i.batch[:i.config.BatchSize]); err != nil {
+				return err
+			}
+			i.batch = i.batch[i.config.BatchSize:]
+		}
+	}
+
+	return nil
+}
+
+// lines convert each row of the table to a line, rows without any (numeric or boolean) field are skipped
+func (i *InfluxDB) lines(measurement string, data outputs.Data, dataTable *outputs.Table) []string {
+	headers := make([]string, len(dataTable.Headers))
+	for index, header := range dataTable.Headers {
+		if header != nil {
+			headers[index] = util.CastToString(header.Value)
+		}
+	}
+
+	lines := []string{}
+	for _, row := range dataTable.Rows {
+		timestamp := data.TestTime
+		offset := 0.0
+		tags := map[string]string{}
+		if data.Test != "" {
+			tags["test"] = data.Test
+		}
+		fields := []string{}
+
+		for index, r := range row {
+			if r == nil || r.Value == nil || index >= len(headers) || headers[index] == "" {
+				continue
+			}
+			name := headers[index]
+
+			switch name {
+			case timeColumn:
+				if parsed, err := time.Parse(util.TimeDateFormat, util.CastToString(r.Value)); err == nil {
+					timestamp = parsed
+				}
+				continue
+			case offsetColumn:
+				if val, err := util.CastNumberToFloat64(r.Value); err == nil {
+					offset = val
+				}
+			}
+
+			if _, ok := i.exclude[name]; ok {
+				continue
+			}
+			// Tag columns identify the series, e.g., the `socket` of the IPerf3 streams of the same interval
+			if _, ok := i.tags[name]; ok {
+				if val := util.CastToString(r.Value); val != "" {
+					tags[name] = val
+				}
+				continue
+			}
+
+			switch val := r.Value.(type) {
+			case string:
+				// Empty tag values aren't allowed in the line protocol
+				if val != "" {
+					tags[name] = val
+				}
+			case bool:
+				fields = append(fields, keyEscaper.Replace(name)+"="+strconv.FormatBool(val))
+			case int, int8, int16, int32, int64:
+				fields = append(fields, fmt.Sprintf("%s=%di", keyEscaper.Replace(name), val))
+			case uint, uint8, uint16, uint32, uint64:
+				fields = append(fields, fmt.Sprintf("%s=%du", keyEscaper.Replace(name), val))
+			case float32, float64:
+				f, _ := util.CastNumberToFloat64(val)
+				// NaN and infinite values can't be represented in the line protocol
+				if math.IsNaN(f) || math.IsInf(f, 0) {
+					continue
+				}
+				fields = append(fields, keyEscaper.Replace(name)+"="+strconv.FormatFloat(f, 'f', -1, 64))
+			default:
+				fields = append(fields, fmt.Sprintf(`%s="%s"`, keyEscaper.Replace(name), stringEscaper.Replace(util.CastToString(val))))
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		line := measurementEscaper.Replace(measurement)
+		for _, key := range sortedKeys(tags) {
+			line += "," + keyEscaper.Replace(key) + "=" + keyEscaper.Replace(tags[key])
+		}
+		timestamp = timestamp.Add(time.Duration(offset * float64(time.Second)))
+		line += " " + strings.Join(fields, ",") + " " + strconv.FormatInt(timestamp.UnixNano(), 10)
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// sortedKeys return the keys of the tags sorted, as recommended for the performance of the InfluxDB
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (i *InfluxDB) writeFile(data outputs.Data, lines []string) error {
+	filename, err := outputs.GetFilenameFromPattern(i.config.FilePath.NamePattern, "", data, nil)
+	if err != nil {
+		return err
+	}
+
+	outPath := filepath.Join(i.config.FilePath.FilePath, filename)
+	file, ok := i.files[outPath]
+	if !ok {
+		file, err = os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		i.files[outPath] = file
+	}
+
+	_, err = file.WriteString(strings.Join(lines, "\n") + "\n")
+	return err
+}
+
+// write write the lines to the InfluxDB v2 write API, retrying on connection errors and server side errors
+func (i *InfluxDB) write(lines []string) error {
+	params := url.Values{}
+	params.Set("org", i.config.Org)
+	params.Set("bucket", i.config.Bucket)
+	params.Set("precision", "ns")
+	writeURL := strings.TrimSuffix(i.config.URL, "/") + "/api/v2/write?" + params.Encode()
+	body := strings.Join(lines, "\n")
+
+	var err error
+	for attempt := 0; attempt <= *i.config.Retries; attempt++ {
+		if attempt > 0 {
+			i.logger.Warn("failed to write to influxdb, retrying", zap.Int("attempt", attempt), zap.Error(err))
+			time.Sleep(i.retryInterval * time.Duration(1<<(attempt-1)))
+		}
+
+		var retry bool
+		retry, err = i.post(writeURL, body)
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %d lines to influxdb. %+v", len(lines), err)
+	}
+
+	return nil
+}
+
+// post post the body to the write URL, returns if the request should be retried on error
+func (i *InfluxDB) post(writeURL string, body string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, writeURL, strings.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.config.Token)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("influxdb write API returned status code %d. %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// OutputFiles return a list of output files
+func (i *InfluxDB) OutputFiles() []string {
+	list := []string{}
+	for file := range i.files {
+		list = append(list, file)
+	}
+	return list
+}
+
+// Close write the remaining lines to the InfluxDB and close the files
+func (i *InfluxDB) Close() error {
+	var err error
+	if i.config.URL != "" && len(i.batch) > 0 {
+		err = i.write(i.batch)
+		i.batch = []string{}
+	}
+
+	for name, file := range i.files {
+		i.logger.With(zap.String("filepath", name)).Debug("closing file")
+		if err := file.Close(); err != nil {
+			i.logger.With(zap.String("filepath", name)).Error("error closing file", zap.Error(err))
+		}
+	}
+
+	return err
+}
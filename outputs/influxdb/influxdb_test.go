@@ -0,0 +1,189 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package influxdb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func generateData(dataType outputs.DataType) outputs.Data {
+	return outputs.Data{
+		Test:          "iperf3 test",
+		TestStartTime: testTime,
+		TestTime:      testTime,
+		Tester:        "iperf3",
+		ServerHost:    "node1",
+		ClientHost:    "node2",
+		Type:          dataType,
+		Data: &outputs.Table{
+			Headers: []*outputs.Row{
+				{Value: "test_time"},
+				{Value: "round"},
+				{Value: "server_host"},
+				{Value: "kind"},
+				{Value: "socket"},
+				{Value: "start"},
+				{Value: "bits_per_second"},
+				{Value: "omitted"},
+				{Value: "system_info"},
+			},
+			Rows: [][]*outputs.Row{
+				{{Value: testTime.Format(util.TimeDateFormat)}, {Value: 0}, {Value: "node1"}, {Value: "stream"}, {Value: int64(5)}, {Value: float64(0)}, {Value: float64(100.5)}, {Value: false}, {Value: "Linux node1"}},
+				{{Value: testTime.Format(util.TimeDateFormat)}, {Value: 0}, {Value: "node1"}, {Value: "sum,all"}, nil, {Value: float64(1.5)}, {Value: float64(200)}, {Value: true}, {Value: "Linux node1"}},
+			},
+		},
+	}
+}
+
+func TestInfluxDBFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	outCfg := &config.Output{
+		InfluxDB: &config.InfluxDB{
+			FilePath: config.FilePath{
+				FilePath:    tempDir,
+				NamePattern: "influxdb.lp",
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	i, err := NewInfluxDBOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+
+	require.Nil(t, i.Do(generateData(outputs.DataTypeInterval)))
+	require.Nil(t, i.Do(generateData(outputs.DataTypeSummary)))
+	require.Nil(t, i.Close())
+
+	outPath := filepath.Join(tempDir, "influxdb.lp")
+	assert.Equal(t, []string{outPath}, i.OutputFiles())
+	content, err := os.ReadFile(outPath)
+	require.Nil(t, err)
+	// The socket is written as a tag and the system_info is excluded by default
+	assert.Equal(t, `ancientt_iperf3,kind=stream,server_host=node1,socket=5,test=iperf3\ test round=0i,start=0,bits_per_second=100.5,omitted=false 1577934245000000000
+ancientt_iperf3,kind=sum\,all,server_host=node1,test=iperf3\ test round=0i,start=1.5,bits_per_second=200,omitted=true 1577934246500000000
+ancientt_iperf3_summary,kind=stream,server_host=node1,socket=5,test=iperf3\ test round=0i,start=0,bits_per_second=100.5,omitted=false 1577934245000000000
+ancientt_iperf3_summary,kind=sum\,all,server_host=node1,test=iperf3\ test round=0i,start=1.5,bits_per_second=200,omitted=true 1577934246500000000
+`, string(content))
+}
+
+func TestInfluxDBWrite(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	bodies := []string{}
+	influxdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// The first request fails to test the retries
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "/api/v2/write", r.URL.Path)
+		assert.Equal(t, "my-org", r.URL.Query().Get("org"))
+		assert.Equal(t, "my-bucket", r.URL.Query().Get("bucket"))
+		assert.Equal(t, "ns", r.URL.Query().Get("precision"))
+		assert.Equal(t, "Token my-token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer influxdb.Close()
+
+	outCfg := &config.Output{
+		InfluxDB: &config.InfluxDB{
+			URL:       influxdb.URL,
+			Org:       "my-org",
+			Bucket:    "my-bucket",
+			Token:     "my-token",
+			BatchSize: 3,
+			// Don't exclude any columns
+			ExcludeColumns: []string{},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	i, err := NewInfluxDBOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+	i.(*InfluxDB).retryInterval = time.Millisecond
+
+	require.Nil(t, i.Do(generateData(outputs.DataTypeInterval)))
+	require.Nil(t, i.Do(generateData(outputs.DataTypeInterval)))
+	require.Nil(t, i.Close())
+
+	assert.Empty(t, i.OutputFiles())
+	// One failed request, one full batch and the remaining line on close
+	assert.Equal(t, 3, requests)
+	require.Len(t, bodies, 2)
+	assert.Len(t, strings.Split(bodies[0], "\n"), 3)
+	assert.Len(t, strings.Split(bodies[1], "\n"), 1)
+	assert.Contains(t, bodies[0], "system_info=Linux\\ node1")
+}
+
+func TestInfluxDBWriteError(t *testing.T) {
+	requests := 0
+	influxdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"invalid","message":"unable to parse"}`))
+	}))
+	defer influxdb.Close()
+
+	outCfg := &config.Output{
+		InfluxDB: &config.InfluxDB{
+			URL: influxdb.URL,
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	i, err := NewInfluxDBOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+
+	require.Nil(t, i.Do(generateData(outputs.DataTypeInterval)))
+	err = i.Close()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status code 400")
+	assert.Contains(t, err.Error(), "unable to parse")
+	// Client errors are not retried
+	assert.Equal(t, 1, requests)
+}
+
+func TestNewInfluxDBOutputNoTarget(t *testing.T) {
+	outCfg := &config.Output{
+		InfluxDB: &config.InfluxDB{},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	_, err := NewInfluxDBOutput(zap.NewNop(), nil, outCfg)
+	assert.NotNil(t, err)
+}
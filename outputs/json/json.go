@@ -0,0 +1,193 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"go.uber.org/zap"
+)
+
+// NameJSON JSON output name
+const NameJSON = "json"
+
+func init() {
+	outputs.Factories[NameJSON] = NewJSONOutput
+}
+
+// Record a row of the data with the metadata of the data
+type Record struct {
+	Test          string                 `json:"test"`
+	Runner        string                 `json:"runner"`
+	TestStartTime time.Time              `json:"test_start_time"`
+	TestTime      time.Time              `json:"test_time"`
+	Tester        string                 `json:"tester"`
+	ServerHost    string                 `json:"server_host"`
+	ClientHost    string                 `json:"client_host"`
+	IPFamily      string                 `json:"ip_family,omitempty"`
+	DataType      outputs.DataType       `json:"data_type"`
+	Row           map[string]interface{} `json:"row"`
+}
+
+// JSON JSON output structure
+type JSON struct {
+	outputs.Output
+	logger *zap.Logger
+	config *config.JSON
+	runner string
+	files  map[string]*os.File
+	// records records per file, which are written on close in the document mode
+	records map[string][]*Record
+}
+
+// NewJSONOutput return a new JSON output instance
+func NewJSONOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	j := JSON{
+		logger:  logger.With(zap.String("output", NameJSON)),
+		config:  outCfg.JSON,
+		files:   map[string]*os.File{},
+		records: map[string][]*Record{},
+	}
+	if cfg != nil {
+		j.runner = cfg.Runner.Name
+	}
+	return j, nil
+}
+
+// Do make JSON outputs
+func (j JSON) Do(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for json output")
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(j.config.FilePath.NamePattern, "", data, nil)
+	if err != nil {
+		return err
+	}
+
+	outPath := filepath.Join(j.config.FilePath.FilePath, filename)
+	file, ok := j.files[outPath]
+	if !ok {
+		file, err = os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		j.files[outPath] = file
+	}
+
+	records := j.toRecords(data, dataTable)
+
+	if j.config.Mode == config.JSONModeDocument {
+		j.records[outPath] = append(j.records[outPath], records...)
+		return nil
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toRecords return a record for each row of the data table, keyed by the header names
+func (j JSON) toRecords(data outputs.Data, dataTable *outputs.Table) []*Record {
+	records := []*Record{}
+	for _, row := range dataTable.Rows {
+		values := map[string]interface{}{}
+		for i, r := range row {
+			if r == nil || i >= len(dataTable.Headers) || dataTable.Headers[i] == nil {
+				continue
+			}
+			values[util.CastToString(dataTable.Headers[i].Value)] = jsonValue(r.Value)
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		records = append(records, &Record{
+			Test:          data.Test,
+			Runner:        j.runner,
+			TestStartTime: data.TestStartTime,
+			TestTime:      data.TestTime,
+			Tester:        data.Tester,
+			ServerHost:    data.ServerHost,
+			ClientHost:    data.ClientHost,
+			IPFamily:      string(data.IPFamily),
+			DataType:      data.Type,
+			Row:           values,
+		})
+	}
+	return records
+}
+
+// jsonValue return the value as is, except for NaN and infinite values which can't be represented in JSON
+func jsonValue(in interface{}) interface{} {
+	switch val := in.(type) {
+	case float32:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			return nil
+		}
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return nil
+		}
+	}
+	return in
+}
+
+// OutputFiles return a list of output files
+func (j JSON) OutputFiles() []string {
+	list := []string{}
+	for file := range j.files {
+		list = append(list, file)
+	}
+	return list
+}
+
+// Close write the records in the document mode and close all files
+func (j JSON) Close() error {
+	var err error
+	for name, file := range j.files {
+		if j.config.Mode == config.JSONModeDocument {
+			records := j.records[name]
+			if records == nil {
+				records = []*Record{}
+			}
+			encoder := json.NewEncoder(file)
+			encoder.SetIndent("", "  ")
+			if encodeErr := encoder.Encode(records); encodeErr != nil {
+				err = fmt.Errorf("failed to write json document %s. %+v", name, encodeErr)
+			}
+		}
+
+		j.logger.With(zap.String("filepath", name)).Debug("closing file")
+		if err := file.Close(); err != nil {
+			j.logger.With(zap.String("filepath", name)).Error("error closing file", zap.Error(err))
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func generateData(dataType outputs.DataType) outputs.Data {
+	return outputs.Data{
+		Test:          "iperf3-test",
+		TestStartTime: time.Unix(1000, 0).UTC(),
+		TestTime:      time.Unix(1010, 0).UTC(),
+		Tester:        "iperf3",
+		ServerHost:    "node1",
+		ClientHost:    "node2",
+		IPFamily:      config.IPFamilyIPv4,
+		Type:          dataType,
+		Data: &outputs.Table{
+			Headers: []*outputs.Row{
+				{Value: "round"},
+				{Value: "kind"},
+				{Value: "bits_per_second"},
+				{Value: "omitted"},
+			},
+			Rows: [][]*outputs.Row{
+				{{Value: 0}, {Value: "sum"}, {Value: float64(100.5)}, {Value: false}},
+				{{Value: 1}, {Value: "sum"}, {Value: math.NaN()}, {Value: true}},
+			},
+		},
+	}
+}
+
+func newJSONOutput(t *testing.T, tempDir string, mode config.JSONMode) outputs.Output {
+	outCfg := &config.Output{
+		JSON: &config.JSON{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			Mode: mode,
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	j, err := NewJSONOutput(zap.NewNop(), &config.Config{
+		Runner: config.Runner{Name: "mock"},
+	}, outCfg)
+	require.Nil(t, err)
+	return j
+}
+
+func TestJSONNDJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	j := newJSONOutput(t, tempDir, "")
+
+	require.Nil(t, j.Do(generateData(outputs.DataTypeInterval)))
+	require.Nil(t, j.Do(generateData(outputs.DataTypeSummary)))
+	require.Nil(t, j.Close())
+
+	outPath := filepath.Join(tempDir, "ancientt-1000-iperf3.ndjson")
+	assert.Equal(t, []string{outPath}, j.OutputFiles())
+
+	file, err := os.Open(outPath)
+	require.Nil(t, err)
+	defer file.Close()
+
+	lines := []map[string]interface{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Nil(t, scanner.Err())
+	require.Len(t, lines, 4)
+
+	assert.Equal(t, map[string]interface{}{
+		"test":            "iperf3-test",
+		"runner":          "mock",
+		"test_start_time": "1970-01-01T00:16:40Z",
+		"test_time":       "1970-01-01T00:16:50Z",
+		"tester":          "iperf3",
+		"server_host":     "node1",
+		"client_host":     "node2",
+		"ip_family":       "ipv4",
+		"data_type":       "interval",
+		"row": map[string]interface{}{
+			"round":           float64(0),
+			"kind":            "sum",
+			"bits_per_second": 100.5,
+			"omitted":         false,
+		},
+	}, lines[0])
+	// NaN values are written as null
+	assert.Nil(t, lines[1]["row"].(map[string]interface{})["bits_per_second"])
+	assert.Equal(t, "summary", lines[3]["data_type"])
+}
+
+func TestJSONDocument(t *testing.T) {
+	tempDir := t.TempDir()
+	j := newJSONOutput(t, tempDir, config.JSONModeDocument)
+
+	require.Nil(t, j.Do(generateData(outputs.DataTypeInterval)))
+	require.Nil(t, j.Do(generateData(outputs.DataTypeSummary)))
+	require.Nil(t, j.Close())
+
+	outPath := filepath.Join(tempDir, "ancientt-1000-iperf3.json")
+	assert.Equal(t, []string{outPath}, j.OutputFiles())
+
+	content, err := os.ReadFile(outPath)
+	require.Nil(t, err)
+	records := []*Record{}
+	require.Nil(t, json.Unmarshal(content, &records))
+	require.Len(t, records, 4)
+	assert.Equal(t, "iperf3-test", records[0].Test)
+	assert.Equal(t, "mock", records[0].Runner)
+	assert.Equal(t, outputs.DataTypeSummary, records[2].DataType)
+	assert.Equal(t, 100.5, records[2].Row["bits_per_second"])
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/testers"
+	"go.uber.org/zap"
+)
+
+// NameMarkdown Markdown output name
+const NameMarkdown = "markdown"
+
+// DefaultTemplate template used when no template is set in the output config
+const DefaultTemplate = `## ancientt results: {{ .Test }}
+
+| Tester | Runner | Test Start Time | Data |
+| --- | --- | --- | --- |
+| {{ .Tester }} | {{ .Runner }} | {{ .TestStartTime.Format "2006-01-02 15:04:05 MST" }} | {{ .DataType }} |
+
+### Summary
+
+{{ if .HostPairs -}}
+| Server | Client |{{ range .Columns }} {{ . }} mean | {{ . }} min | {{ . }} max |{{ end }}
+| --- | --- |{{ range .Columns }} ---: | ---: | ---: |{{ end }}
+{{ range $pair := .HostPairs -}}
+| {{ escape $pair.Server }} | {{ escape $pair.Client }} |{{ range $.Columns }}{{ with index $pair.Values . }} {{ number .Mean }} | {{ number .Min }} | {{ number .Max }} |{{ else }} - | - | - |{{ end }}{{ end }}
+{{ end -}}
+{{ else -}}
+No results.
+{{ end }}
+### Failures
+
+{{ if .FailedHosts -}}
+| Role | Host | Count | Errors |
+| --- | --- | ---: | --- |
+{{ range .FailedHosts -}}
+| {{ .Role }} | {{ escape .Host }} | {{ .Count }} | {{ escape (join .Errors "<br>") }} |
+{{ end -}}
+{{ else -}}
+No failures.
+{{ end -}}
+`
+
+// templateFuncs functions available in the templates
+var templateFuncs = template.FuncMap{
+	// escape escape the text for usage in a table cell
+	"escape": func(in string) string {
+		return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(in)
+	},
+	"join": strings.Join,
+	// number format the number with up to 6 significant digits
+	"number": func(in float64) string {
+		return strconv.FormatFloat(in, 'g', 6, 64)
+	},
+}
+
+func init() {
+	outputs.Factories[NameMarkdown] = NewMarkdownOutput
+}
+
+// Summary data the template is rendered with
+type Summary struct {
+	Test          string
+	Tester        string
+	Runner        string
+	TestStartTime time.Time
+	DataType      string
+	Columns       []string
+	// HostPairs sorted by server and client host
+	HostPairs       []*HostPair
+	FailedHosts     []testers.HostStatus
+	SuccessfulHosts []testers.HostStatus
+}
+
+// HostPair aggregated values of the columns of a server and client host pair
+type HostPair struct {
+	outputs.HostPair
+	// Values per column, columns without values aren't in the map
+	Values map[string]*outputs.Aggregate
+}
+
+// Markdown Markdown output structure
+type Markdown struct {
+	outputs.Output
+	*outputs.Report
+	logger   *zap.Logger
+	config   *config.Markdown
+	template *template.Template
+	runner   string
+	files    []string
+}
+
+// NewMarkdownOutput return a new Markdown output instance
+func NewMarkdownOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	m := &Markdown{
+		Report: outputs.NewReport(),
+		logger: logger.With(zap.String("output", NameMarkdown)),
+		config: outCfg.Markdown,
+		files:  []string{},
+	}
+	if cfg != nil {
+		m.runner = cfg.Runner.Name
+	}
+	tmpl := m.config.Template
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	var err error
+	m.template, err = template.New(NameMarkdown).Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown template. %+v", err)
+	}
+
+	return m, nil
+}
+
+// Do collect the values of the configured columns per server and client host pair
+func (m *Markdown) Do(data outputs.Data) error {
+	return m.Add(data, m.config.Columns, m.config.DataFilter)
+}
+
+// hostPairs return the aggregated values per host pair, sorted by server and client host
+func (m *Markdown) hostPairs() []*HostPair {
+	pairs := []*HostPair{}
+	for _, pair := range m.HostPairs() {
+		hostPair := &HostPair{
+			HostPair: pair,
+			Values:   map[string]*outputs.Aggregate{},
+		}
+		for _, column := range m.config.Columns {
+			values := m.Values(pair, column)
+			if len(values) == 0 {
+				continue
+			}
+			agg := &outputs.Aggregate{}
+			for _, val := range values {
+				agg.Add(val)
+			}
+			hostPair.Values[column] = agg
+		}
+		if len(hostPair.Values) == 0 {
+			continue
+		}
+		pairs = append(pairs, hostPair)
+	}
+
+	return pairs
+}
+
+// OutputFiles return a list of output files
+func (m *Markdown) OutputFiles() []string {
+	return m.files
+}
+
+// Close render the template and write the Markdown file
+func (m *Markdown) Close() error {
+	data := m.Data()
+	if data == nil {
+		m.logger.Warn("no data and no plan received for markdown, no file written")
+		return nil
+	}
+
+	summary := Summary{
+		Test:          data.Test,
+		Tester:        data.Tester,
+		Runner:        m.runner,
+		TestStartTime: data.TestStartTime,
+		DataType:      m.config.DataType,
+		Columns:       m.config.Columns,
+		HostPairs:     m.hostPairs(),
+	}
+	if m.Plan != nil {
+		summary.FailedHosts, summary.SuccessfulHosts = m.Plan.HostsStatus()
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := m.template.Execute(buffer, summary); err != nil {
+		return fmt.Errorf("failed to render markdown template. %+v", err)
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(m.config.NamePattern, "", *data, nil)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(m.config.FilePath.FilePath, filename)
+	if err := util.WriteNewTruncFile(outPath, buffer.Bytes()); err != nil {
+		return err
+	}
+	m.files = append(m.files, outPath)
+
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/tests"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newMarkdownOutput(t *testing.T, tempDir string, tmpl string) (outputs.Output, error) {
+	outCfg := &config.Output{
+		Markdown: &config.Markdown{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			Columns: []string{"bits_per_second", "retransmits"},
+			DataFilter: config.DataFilter{
+				RowFilter: config.RowFilter{
+					Filters: map[string]string{
+						"kind": "sum",
+					},
+				},
+			},
+			Template: tmpl,
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	return NewMarkdownOutput(zap.NewNop(), &config.Config{
+		Runner: config.Runner{Name: "mock"},
+	}, outCfg)
+}
+
+func TestMarkdown(t *testing.T) {
+	tempDir := t.TempDir()
+	m, err := newMarkdownOutput(t, tempDir, "")
+	require.Nil(t, err)
+
+	status := testers.NewStatus()
+	status.AddSuccessfulServer(&testers.Host{Name: "node1"})
+	status.AddFailedClient(&testers.Host{Name: "node|3"}, fmt.Errorf("connection\nrefused"))
+	m.(outputs.PlanOutput).SetPlan(&config.Test{Name: "iperf3-test"}, &testers.Plan{
+		Commands: [][]*testers.Task{{{Status: status}}},
+	})
+
+	require.Nil(t, m.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 300)))
+	require.Nil(t, m.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 200)))
+	require.Nil(t, m.Do(tests.GenerateMockIPerf3Data("node2", "node1", outputs.DataTypeInterval, 50)))
+	// Summary data must be ignored with the default data type
+	require.Nil(t, m.Do(tests.GenerateMockIPerf3Data("node2", "node3", outputs.DataTypeSummary, 1000)))
+	require.Nil(t, m.Close())
+
+	outPath := filepath.Join(tempDir, "ancientt-1000-iperf3.md")
+	assert.Equal(t, []string{outPath}, m.OutputFiles())
+	content, err := os.ReadFile(outPath)
+	require.Nil(t, err)
+	assert.Equal(t, `## ancientt results: iperf3-test
+
+| Tester | Runner | Test Start Time | Data |
+| --- | --- | --- | --- |
+| iperf3 | mock | 1970-01-01 00:16:40 UTC | interval |
+
+### Summary
+
+| Server | Client | bits_per_second mean | bits_per_second min | bits_per_second max | retransmits mean | retransmits min | retransmits max |
+| --- | --- | ---: | ---: | ---: | ---: | ---: | ---: |
+| node1 | node2 | 200 | 100 | 300 | - | - | - |
+| node2 | node1 | 50 | 50 | 50 | - | - | - |
+
+### Failures
+
+| Role | Host | Count | Errors |
+| --- | --- | ---: | --- |
+| client | node\|3 | 1 | connection refused |
+`, string(content))
+}
+
+func TestMarkdownCustomTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	m, err := newMarkdownOutput(t, tempDir, `{{ range .HostPairs }}{{ .Server }} -> {{ .Client }}: {{ number (index .Values "bits_per_second").Mean }}
+{{ end }}{{ if not .FailedHosts }}No failures.{{ end }}`)
+	require.Nil(t, err)
+
+	require.Nil(t, m.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 200)))
+	require.Nil(t, m.Close())
+
+	require.Len(t, m.OutputFiles(), 1)
+	content, err := os.ReadFile(m.OutputFiles()[0])
+	require.Nil(t, err)
+	assert.Equal(t, "node1 -> node2: 150\nNo failures.", string(content))
+}
+
+func TestMarkdownInvalidTemplate(t *testing.T) {
+	_, err := newMarkdownOutput(t, t.TempDir(), "{{ .Test ")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to parse markdown template")
+}
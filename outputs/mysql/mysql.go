@@ -90,6 +90,9 @@ func (m MySQL) Do(data outputs.Data) error {
 	if err != nil {
 		return err
 	}
+	if data.IsSummary() {
+		tableName = outputs.GetSummaryTableName(tableName)
+	}
 
 	dbPath := fmt.Sprintf("%s-%s", m.config.DSN, tableName)
 
@@ -103,18 +106,30 @@ func (m MySQL) Do(data outputs.Data) error {
 		m.dbCons[dbPath] = db
 	}
 
-	if err := m.createTable(db, dataTable, tableName); err != nil {
+	// Cells of deleted columns (nil headers) are skipped, nil cells of existing columns are written as NULL
+	headers := []string{}
+	indexes := []int{}
+	for i, r := range dataTable.Headers {
+		if r == nil {
+			continue
+		}
+		headers = append(headers, util.CastToString(r.Value))
+		indexes = append(indexes, i)
+	}
+
+	if err := m.createTable(db, dataTable, tableName, headers, indexes); err != nil {
 		return err
 	}
 
 	// Iterate over data columns
 	for _, row := range dataTable.Rows {
-		cells := []interface{}{}
-		for _, r := range row {
-			if r == nil {
+		cells := make([]interface{}, 0, len(indexes))
+		for _, index := range indexes {
+			if len(row) <= index || row[index] == nil {
+				cells = append(cells, nil)
 				continue
 			}
-			cells = append(cells, r.Value)
+			cells = append(cells, row[index].Value)
 		}
 		if len(cells) == 0 {
 			continue
@@ -129,31 +144,18 @@ func (m MySQL) Do(data outputs.Data) error {
 	return nil
 }
 
-func (m MySQL) createTable(db *sqlx.DB, dataTable *outputs.Table, tableName string) error {
-	// Iterate over headers
-	headers := []string{}
-	for _, r := range dataTable.Headers {
-		if r == nil {
-			continue
-		}
-		headers = append(headers, util.CastToString(r.Value))
-	}
-
-	// Iterate over data row to get the first row of data.
-	// The first row of data is needed to set the types on the to be created MySQL table
-	cells := []interface{}{}
-	for _, row := range dataTable.Rows {
-		for _, r := range row {
-			if r == nil {
+func (m MySQL) createTable(db *sqlx.DB, dataTable *outputs.Table, tableName string, headers []string, indexes []int) error {
+	// Iterate over data rows to get the first value of each column.
+	// The first values are needed to set the types on the to be created MySQL table
+	cells := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		for _, row := range dataTable.Rows {
+			if len(row) <= index || row[index] == nil || row[index].Value == nil {
 				continue
 			}
-			cells = append(cells, r.Value)
-		}
-		if len(cells) == 0 {
-			continue
+			cells[i] = row[index].Value
+			break
 		}
-		// Break after first round as we only need the first row!
-		break
 	}
 
 	// The error should not return an error when the table exists, try to create the database
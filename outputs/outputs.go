@@ -16,8 +16,11 @@ package outputs
 import (
 	"bytes"
 	"html/template"
+	"path/filepath"
+	"strings"
 
 	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
 	"go.uber.org/zap"
 )
 
@@ -35,6 +38,13 @@ type Output interface {
 	Close() error
 }
 
+// PlanOutput is the interface an output can implement to get the test and its plan before the test is run, e.g.,
+// to report the plan and the status of the tasks on Close().
+type PlanOutput interface {
+	// SetPlan set the test and the plan of the test
+	SetPlan(test *config.Test, plan *testers.Plan)
+}
+
 // GetFilenameFromPattern get filename from given pattern, data and extra data for templating.
 func GetFilenameFromPattern(pattern string, role string, data Data, extra map[string]interface{}) (string, error) {
 	t, err := template.New("main").Parse(pattern)
@@ -62,3 +72,17 @@ func GetFilenameFromPattern(pattern string, role string, data Data, extra map[st
 	}
 	return out.String(), nil
 }
+
+// GetSummaryFilename return the filename for summary data based on the filename of the (interval) data,
+// e.g., `ancientt-123-iperf3.csv` will become `ancientt-123-iperf3-summary.csv`.
+// Summary data is written to its own file, as the columns differ from the interval data.
+func GetSummaryFilename(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-" + string(DataTypeSummary) + ext
+}
+
+// GetSummaryTableName return the table name for summary data based on the table name of the (interval) data.
+// Summary data is written to its own table (or measurement), as the columns differ from the interval data.
+func GetSummaryTableName(tableName string) string {
+	return tableName + "_" + string(DataTypeSummary)
+}
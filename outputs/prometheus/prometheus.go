@@ -0,0 +1,241 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+// NamePrometheus Prometheus output name
+const NamePrometheus = "prometheus"
+
+// pushTimeout timeout for pushing the metrics to the Pushgateway
+const pushTimeout = 30 * time.Second
+
+// labelNames labels of all metrics
+var labelNames = []string{"test", "tester", "server_host", "client_host", "ip_family", "round"}
+
+// summaryObjectives quantiles (with their allowed error) of the summaries
+var summaryObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
+func init() {
+	outputs.Factories[NamePrometheus] = NewPrometheusOutput
+}
+
+// Prometheus Prometheus output structure
+type Prometheus struct {
+	outputs.Output
+	logger   *zap.Logger
+	config   *config.Prometheus
+	registry *prom.Registry
+	// gauges per column for the values of the summary data
+	gauges map[string]*prom.GaugeVec
+	// summaries per column for the values of the interval data
+	summaries map[string]*prom.SummaryVec
+	// data first received data, used for templating the file name and the Pushgateway grouping
+	data  *outputs.Data
+	files []string
+}
+
+// NewPrometheusOutput return a new Prometheus output instance
+func NewPrometheusOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	p := &Prometheus{
+		logger:    logger.With(zap.String("output", NamePrometheus)),
+		config:    outCfg.Prometheus,
+		registry:  prom.NewRegistry(),
+		gauges:    map[string]*prom.GaugeVec{},
+		summaries: map[string]*prom.SummaryVec{},
+		files:     []string{},
+	}
+
+	for _, column := range p.config.Columns {
+		name := metricName(p.config.MetricPrefix, column)
+
+		gauge := prom.NewGaugeVec(prom.GaugeOpts{
+			Name: name,
+			Help: fmt.Sprintf("Value of the %s column of the summary data.", column),
+		}, labelNames)
+		summary := prom.NewSummaryVec(prom.SummaryOpts{
+			Name:       name + "_intervals",
+			Help:       fmt.Sprintf("Values of the %s column of the interval data.", column),
+			Objectives: summaryObjectives,
+		}, labelNames)
+
+		for _, collector := range []prom.Collector{gauge, summary} {
+			if err := p.registry.Register(collector); err != nil {
+				return nil, fmt.Errorf("failed to register metric for column %s. %+v", column, err)
+			}
+		}
+		p.gauges[column] = gauge
+		p.summaries[column] = summary
+	}
+
+	return p, nil
+}
+
+// metricName return the metric name for the column, characters not allowed in metric names are replaced by `_`
+func metricName(prefix string, column string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, prefix+column)
+}
+
+// Do set the gauges to the values of the summary data and observe the values of the interval data in the summaries
+func (p *Prometheus) Do(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for prometheus output")
+	}
+
+	if p.data == nil {
+		info := data
+		info.Data = nil
+		p.data = &info
+	}
+
+	roundIndex, err := dataTable.GetHeaderIndexByName("round")
+	if err != nil {
+		return err
+	}
+
+	columns := map[string]int{}
+	for _, column := range p.config.Columns {
+		index, err := dataTable.GetHeaderIndexByName(column)
+		if err != nil {
+			return err
+		}
+		if index == -1 {
+			p.logger.Debug("column not found in data table, skipping column", zap.String("column", column))
+			continue
+		}
+		columns[column] = index
+	}
+
+	rows, err := dataTable.FilterRows(p.config.Filters)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		round := ""
+		if roundIndex != -1 && len(row) > roundIndex && row[roundIndex] != nil {
+			round = util.CastToString(row[roundIndex].Value)
+		}
+		labels := prom.Labels{
+			"test":        data.Test,
+			"tester":      data.Tester,
+			"server_host": data.ServerHost,
+			"client_host": data.ClientHost,
+			// The IP families of a test run as client tasks of the same round
+			"ip_family": string(data.IPFamily),
+			"round":     round,
+		}
+
+		for column, index := range columns {
+			val, ok, err := outputs.FloatValue(row, index)
+			if err != nil {
+				return fmt.Errorf("failed to get value of column %s. %+v", column, err)
+			}
+			if !ok {
+				continue
+			}
+
+			if data.IsSummary() {
+				p.gauges[column].With(labels).Set(val)
+			} else {
+				p.summaries[column].With(labels).Observe(val)
+			}
+		}
+	}
+
+	return nil
+}
+
+// OutputFiles return a list of output files
+func (p *Prometheus) OutputFiles() []string {
+	return p.files
+}
+
+// Close write the metrics to the textfile collector file and push them to the Pushgateway
+func (p *Prometheus) Close() error {
+	if p.data == nil {
+		p.logger.Warn("no data received for prometheus, no metrics written")
+		return nil
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(p.config.NamePattern, "", *p.data, nil)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(p.config.FilePath.FilePath, filename)
+
+	// The file is written to a temporary file first and renamed, so the textfile collector never reads a partial file
+	if err := prom.WriteToTextfile(outPath, p.registry); err != nil {
+		return fmt.Errorf("failed to write metrics textfile. %+v", err)
+	}
+	p.files = append(p.files, outPath)
+
+	if p.config.Pushgateway == nil {
+		return nil
+	}
+
+	// The metrics are grouped by the test, so pushing the metrics of a test doesn't replace the metrics of other tests
+	if err := push.New(p.config.Pushgateway.URL, p.config.Pushgateway.Job).
+		Gatherer(prom.GathererFunc(p.gatherWithoutTestLabel)).
+		Grouping("test", p.data.Test).
+		Format(expfmt.NewFormat(expfmt.TypeTextPlain)).
+		Client(&http.Client{Timeout: pushTimeout}).
+		Push(); err != nil {
+		return fmt.Errorf("failed to push metrics to pushgateway %s. %+v", p.config.Pushgateway.URL, err)
+	}
+
+	return nil
+}
+
+// gatherWithoutTestLabel gather the metrics without the `test` label, as the Pushgateway doesn't allow pushed metrics
+// to contain the grouping labels (the Pushgateway adds them to the metrics itself)
+func (p *Prometheus) gatherWithoutTestLabel() ([]*dto.MetricFamily, error) {
+	families, err := p.registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			labels := make([]*dto.LabelPair, 0, len(metric.Label))
+			for _, label := range metric.Label {
+				if label.GetName() != "test" {
+					labels = append(labels, label)
+				}
+			}
+			metric.Label = labels
+		}
+	}
+	return families, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/tests"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPrometheus(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var pushedPath string
+	var pushedMethod string
+	var pushedBody []byte
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushedPath = r.URL.Path
+		pushedMethod = r.Method
+		pushedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+
+	outCfg := &config.Output{
+		Prometheus: &config.Prometheus{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			// The default filters only use the sum rows, otherwise the gauges would be set to the stream values
+			Columns: []string{"bits_per_second", "doesnotexist"},
+			Pushgateway: &config.PrometheusPushgateway{
+				URL: pushgateway.URL,
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	p, err := NewPrometheusOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+
+	for _, data := range []outputs.Data{
+		tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 200, 300),
+		tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeSummary, 200),
+	} {
+		data.IPFamily = config.IPFamilyIPv4
+		require.Nil(t, p.Do(data))
+	}
+	// The values of the other IP family must not overwrite the gauges
+	data := tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeSummary, 150)
+	data.IPFamily = config.IPFamilyIPv6
+	require.Nil(t, p.Do(data))
+	require.Nil(t, p.Close())
+
+	outPath := filepath.Join(tempDir, "ancientt-iperf3-test.prom")
+	assert.Equal(t, []string{outPath}, p.OutputFiles())
+	content, err := os.ReadFile(outPath)
+	require.Nil(t, err)
+
+	labels := `client_host="node2",ip_family="ipv4",round="0",server_host="node1",test="iperf3-test",tester="iperf3"`
+	for _, line := range []string{
+		"# TYPE ancientt_bits_per_second gauge",
+		"ancientt_bits_per_second{" + labels + "} 200",
+		`ancientt_bits_per_second{client_host="node2",ip_family="ipv6",round="0",server_host="node1",test="iperf3-test",tester="iperf3"} 150`,
+		"# TYPE ancientt_bits_per_second_intervals summary",
+		"ancientt_bits_per_second_intervals{" + labels + `,quantile="0.5"} 200`,
+		"ancientt_bits_per_second_intervals_sum{" + labels + "} 600",
+		"ancientt_bits_per_second_intervals_count{" + labels + "} 3",
+	} {
+		assert.Contains(t, string(content), line+"\n")
+	}
+	assert.NotContains(t, string(content), "doesnotexist")
+
+	assert.Equal(t, http.MethodPut, pushedMethod)
+	assert.Equal(t, "/metrics/job/ancientt/test/iperf3-test", pushedPath)
+	assert.Contains(t, string(pushedBody), `ancientt_bits_per_second{client_host="node2",ip_family="ipv4",round="0",server_host="node1",tester="iperf3"} 200`+"\n")
+	assert.NotContains(t, string(pushedBody), "test=")
+}
+
+func TestPrometheusPushError(t *testing.T) {
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer pushgateway.Close()
+
+	outCfg := &config.Output{
+		Prometheus: &config.Prometheus{
+			FilePath: config.FilePath{
+				FilePath: t.TempDir(),
+			},
+			Columns: []string{"bits_per_second"},
+			Pushgateway: &config.PrometheusPushgateway{
+				URL: pushgateway.URL,
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	p, err := NewPrometheusOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+	data := tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeSummary, 200)
+	data.IPFamily = config.IPFamilyIPv4
+	require.Nil(t, p.Do(data))
+
+	err = p.Close()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to push metrics to pushgateway")
+	// The textfile is written regardless of the Pushgateway
+	assert.Len(t, p.OutputFiles(), 1)
+}
+
+func TestMetricName(t *testing.T) {
+	assert.Equal(t, "ancientt_bits_per_second", metricName("ancientt_", "bits_per_second"))
+	assert.Equal(t, "ancientt_cpu_host_total_", metricName("ancientt_", "cpu-host.total%"))
+}
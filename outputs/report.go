@@ -0,0 +1,126 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outputs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+)
+
+// HostPair server and client host pair
+type HostPair struct {
+	Server string
+	Client string
+}
+
+// Report collects the test, plan and column values per server and client host pair for outputs which
+// write a report on Close(), e.g., the HTML and Markdown output.
+type Report struct {
+	Test *config.Test
+	Plan *testers.Plan
+	// data first received data, used for templating the file name
+	data *Data
+	// values values of the columns per server and client host pair
+	values map[HostPair]map[string][]float64
+}
+
+// NewReport return a new Report
+func NewReport() *Report {
+	return &Report{
+		values: map[HostPair]map[string][]float64{},
+	}
+}
+
+// SetPlan set the test and the plan of the test, implements the PlanOutput interface
+func (r *Report) SetPlan(test *config.Test, plan *testers.Plan) {
+	r.Test = test
+	r.Plan = plan
+}
+
+// Add collect the values of the columns of the data per server and client host pair,
+// data of another type than the DataFilter data type is ignored
+func (r *Report) Add(data Data, columns []string, filter config.DataFilter) error {
+	dataTable, ok := data.Data.(*Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for report")
+	}
+
+	if r.data == nil {
+		info := data
+		info.Data = nil
+		r.data = &info
+	}
+
+	if data.IsSummary() != (filter.DataType == string(DataTypeSummary)) {
+		return nil
+	}
+
+	values, err := dataTable.ColumnValues(columns, filter.Filters)
+	if err != nil {
+		return err
+	}
+
+	pair := HostPair{Server: data.ServerHost, Client: data.ClientHost}
+	if _, ok := r.values[pair]; !ok {
+		r.values[pair] = map[string][]float64{}
+	}
+	for column, vals := range values {
+		r.values[pair][column] = append(r.values[pair][column], vals...)
+	}
+
+	return nil
+}
+
+// HostPairs return the host pairs with values, sorted by server and client host
+func (r *Report) HostPairs() []HostPair {
+	pairs := make([]HostPair, 0, len(r.values))
+	for pair := range r.values {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Server != pairs[j].Server {
+			return pairs[i].Server < pairs[j].Server
+		}
+		return pairs[i].Client < pairs[j].Client
+	})
+	return pairs
+}
+
+// Values return the values of the column of the host pair
+func (r *Report) Values(pair HostPair, column string) []float64 {
+	return r.values[pair][column]
+}
+
+// Data return the first received data (without the data itself), without data but with a plan the test start time,
+// tester and test name are taken from the plan, as the report is also written for tests which failed on all hosts.
+// Nil is returned when neither data nor a plan has been received.
+func (r *Report) Data() *Data {
+	if r.data != nil {
+		return r.data
+	}
+	if r.Plan == nil {
+		return nil
+	}
+	data := &Data{
+		TestStartTime: r.Plan.TestStartTime,
+		Tester:        r.Plan.Tester,
+	}
+	if r.Test != nil {
+		data.Test = r.Test.Name
+	}
+	return data
+}
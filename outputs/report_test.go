@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	assert.Nil(t, r.Data())
+
+	r.SetPlan(&config.Test{Name: "iperf3-test"}, &testers.Plan{
+		TestStartTime: time.Unix(1000, 0),
+		Tester:        "iperf3",
+	})
+	require.NotNil(t, r.Data())
+	assert.Equal(t, "iperf3-test", r.Data().Test)
+	assert.Equal(t, "iperf3", r.Data().Tester)
+
+	filter := config.DataFilter{
+		RowFilter: config.RowFilter{
+			Filters: map[string]string{"kind": "sum"},
+		},
+	}
+	for _, pair := range []HostPair{{"node2", "node1"}, {"node1", "node2"}} {
+		require.Nil(t, r.Add(Data{
+			Test:       "iperf3-test",
+			ServerHost: pair.Server,
+			ClientHost: pair.Client,
+			Data: &Table{
+				Headers: []*Row{{Value: "kind"}, {Value: "bits_per_second"}},
+				Rows: [][]*Row{
+					{{Value: "sum"}, {Value: float64(100)}},
+					{{Value: "stream"}, {Value: float64(1)}},
+				},
+			},
+		}, []string{"bits_per_second"}, filter))
+	}
+	// Summary data must be ignored with the default data type
+	require.Nil(t, r.Add(Data{
+		ServerHost: "node3",
+		ClientHost: "node1",
+		Type:       DataTypeSummary,
+		Data:       &Table{},
+	}, []string{"bits_per_second"}, filter))
+
+	assert.Equal(t, []HostPair{{"node1", "node2"}, {"node2", "node1"}}, r.HostPairs())
+	assert.Equal(t, []float64{100}, r.Values(HostPair{"node1", "node2"}, "bits_per_second"))
+	assert.Nil(t, r.Data().Data)
+	assert.Equal(t, "node2", r.Data().ServerHost)
+}
@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/pkg/util"
 	"github.com/jmoiron/sqlx"
@@ -24,8 +26,6 @@ import (
 
 	// Include sqlite driver for sqlite output
 	_ "github.com/mattn/go-sqlite3"
-
-	"github.com/galexrt/ancientt/outputs"
 )
 
 // NameSQLite SQLite output name
@@ -36,9 +36,6 @@ const (
 	SQLiteFloatType = "FLOAT"
 	SQLiteBoolType  = "BOOLEAN"
 
-	defaultNamePattern      = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.sqlite3"
-	defaultTableNamePattern = "ancientt{{ .TestStartTime }}{{ .Data.Tester }}{{ .Data.ServerHost }}{{ .Data.ClientHost }}"
-
 	createTableBeginQuery = "CREATE TABLE IF NOT EXISTS `%s` (\n"
 	createTableEndQuery   = `);`
 	insertDataBeginQuery  = "INSERT INTO %s VALUES ("
@@ -55,6 +52,7 @@ type SQLite struct {
 	logger *zap.Logger
 	config *config.SQLite
 	dbCons map[string]*sqlx.DB
+	tables map[string]struct{}
 }
 
 // NewSQLiteOutput return a new SQLite tester instance
@@ -63,17 +61,15 @@ func NewSQLiteOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Outp
 		outCfg = &config.Output{
 			SQLite: &config.SQLite{},
 		}
+		if err := defaults.Set(outCfg); err != nil {
+			return nil, err
+		}
 	}
 	s := SQLite{
 		logger: logger.With(zap.String("output", NameSQLite)),
 		config: outCfg.SQLite,
 		dbCons: map[string]*sqlx.DB{},
-	}
-	if s.config.NamePattern == "" {
-		s.config.NamePattern = defaultNamePattern
-	}
-	if s.config.TableNamePattern == "" {
-		s.config.TableNamePattern = defaultTableNamePattern
+		tables: map[string]struct{}{},
 	}
 
 	return s, nil
@@ -95,8 +91,9 @@ func (s SQLite) Do(data outputs.Data) error {
 	if err != nil {
 		return err
 	}
-
-	var createTable bool
+	if data.IsSummary() {
+		tableName = outputs.GetSummaryTableName(tableName)
+	}
 
 	outPath := filepath.Join(s.config.FilePath.FilePath, filename)
 	db, ok := s.dbCons[outPath]
@@ -107,57 +104,63 @@ func (s SQLite) Do(data outputs.Data) error {
 		}
 
 		s.dbCons[outPath] = db
-		createTable = true
 	}
 
-	if createTable {
-		// Iterate over headers
-		headers := []string{}
-		for _, r := range dataTable.Headers {
-			if r == nil {
-				continue
-			}
-			headers = append(headers, util.CastToString(r.Value))
+	// Cells of deleted columns (nil headers) are skipped, nil cells of existing columns are written as NULL
+	headers := []string{}
+	indexes := []int{}
+	for i, r := range dataTable.Headers {
+		if r == nil {
+			continue
 		}
+		headers = append(headers, util.CastToString(r.Value))
+		indexes = append(indexes, i)
+	}
 
-		// Iterate over data columns to get the first row of data.
-		// The first row of data is needed to set the types on the to be created SQLite table
-		dataRows := []interface{}{}
-		for _, row := range dataTable.Rows {
-			for _, r := range row {
-				if r == nil {
+	// Tables are tracked per database file, as multiple tables can end up in the same file
+	tableKey := outPath + "/" + tableName
+	if _, ok := s.tables[tableKey]; !ok {
+		s.tables[tableKey] = struct{}{}
+
+		// Iterate over data columns to get the first value of each column.
+		// The first values are needed to set the types on the to be created SQLite table
+		firstRow := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			for _, row := range dataTable.Rows {
+				if len(row) <= index || row[index] == nil || row[index].Value == nil {
 					continue
 				}
-				dataRows = append(dataRows, r.Value)
+				firstRow[i] = row[index].Value
+				break
 			}
-			if len(dataRows) == 0 {
-				continue
-			}
-			// Break after first round as we only need the first row!
-			break
 		}
 
 		tx, err := db.Begin()
 		if err != nil {
 			return fmt.Errorf("couldn't begin transaction in sqlite database. %+v", err)
 		}
-		tx.Exec(s.buildCreateTableQuery(tableName, headers, dataRows))
+		tx.Exec(s.buildCreateTableQuery(tableName, headers, firstRow))
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("couldn't create table in sqlite database. %+v", err)
 		}
 	}
 
+	if len(indexes) == 0 {
+		return nil
+	}
+
 	// Iterate over data columns
+	query := s.buildInsertQuery(tableName, len(indexes))
 	for _, row := range dataTable.Rows {
-		dataRows := []interface{}{}
-		for _, r := range row {
-			dataRows = append(dataRows, r.Value)
-		}
-		if len(dataRows) == 0 {
-			continue
+		dataRows := make([]interface{}, 0, len(indexes))
+		for _, index := range indexes {
+			if len(row) <= index || row[index] == nil {
+				dataRows = append(dataRows, nil)
+				continue
+			}
+			dataRows = append(dataRows, row[index].Value)
 		}
 
-		query := s.buildInsertQuery(tableName, len(dataRows))
 		if _, err := db.Exec(query, dataRows...); err != nil {
 			return fmt.Errorf("couldn't insert data in sqlite database. %+v", err)
 		}
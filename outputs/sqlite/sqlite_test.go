@@ -56,8 +56,11 @@ func TestSQLite(t *testing.T) {
 	tableName, err := outputs.GetFilenameFromPattern(outCfg.SQLite.TableNamePattern, "", data, nil)
 	require.Nil(t, err)
 
-	// Because the db driver already exists, the "CREATE TABLE" query is not triggered
-	// Match the two inserts
+	// The table hasn't been created yet, so the "CREATE TABLE" query is triggered
+	// before the inserts are run
+	mock.ExpectBegin()
+	mock.ExpectExec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`", tableName))
+	mock.ExpectCommit()
 	mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", tableName))
 	mock.ExpectClose()
 
@@ -0,0 +1,314 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"go.uber.org/zap"
+)
+
+// NameStats Stats output name
+const NameStats = "stats"
+
+// Headers names of the statistics returned by Calculate, in the same order
+var Headers = []string{"count", "min", "max", "mean", "stddev", "p50", "p90", "p99"}
+
+func init() {
+	outputs.Factories[NameStats] = NewStatsOutput
+}
+
+// Stats Stats output structure
+type Stats struct {
+	outputs.Output
+	logger *zap.Logger
+	config *config.Stats
+	// out where the statistics table is printed to
+	out io.Writer
+	// data first received data, used for templating the file name
+	data   *outputs.Data
+	groups map[string]*group
+	files  map[string]struct{}
+}
+
+// group values of the columns of the rows with the same key column values
+type group struct {
+	keys   []string
+	values map[string][]float64
+}
+
+// NewStatsOutput return a new Stats output instance
+func NewStatsOutput(logger *zap.Logger, cfg *config.Config, outCfg *config.Output) (outputs.Output, error) {
+	s := &Stats{
+		logger: logger.With(zap.String("output", NameStats)),
+		config: outCfg.Stats,
+		out:    os.Stdout,
+		groups: map[string]*group{},
+		files:  map[string]struct{}{},
+	}
+	return s, nil
+}
+
+// Do collect the values of the configured columns grouped by the key columns
+func (s *Stats) Do(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for stats output")
+	}
+	if data.IsSummary() != (s.config.DataType == string(outputs.DataTypeSummary)) {
+		return nil
+	}
+
+	if s.data == nil {
+		info := data
+		info.Data = nil
+		s.data = &info
+	}
+
+	keyIndexes := make([]int, len(s.config.KeyColumns))
+	for i, column := range s.config.KeyColumns {
+		index, err := dataTable.GetHeaderIndexByName(column)
+		if err != nil {
+			return err
+		}
+		keyIndexes[i] = index
+	}
+
+	columns := map[string]int{}
+	for _, column := range s.config.Columns {
+		index, err := dataTable.GetHeaderIndexByName(column)
+		if err != nil {
+			return err
+		}
+		if index == -1 {
+			s.logger.Debug("column not found in data table, skipping column", zap.String("column", column))
+			continue
+		}
+		columns[column] = index
+	}
+
+	rows, err := dataTable.FilterRows(s.config.Filters)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		keys := make([]string, len(keyIndexes))
+		for i, index := range keyIndexes {
+			if index != -1 && len(row) > index && row[index] != nil {
+				keys[i] = util.CastToString(row[index].Value)
+			}
+		}
+		groupKey := strings.Join(keys, "\x00")
+		g, ok := s.groups[groupKey]
+		if !ok {
+			g = &group{
+				keys:   keys,
+				values: map[string][]float64{},
+			}
+			s.groups[groupKey] = g
+		}
+
+		for column, index := range columns {
+			// Empty cells and NaN / infinite values are skipped
+			val, ok, err := outputs.FloatValue(row, index)
+			if err != nil {
+				return fmt.Errorf("failed to get value of column %s. %+v", column, err)
+			}
+			if !ok {
+				continue
+			}
+			g.values[column] = append(g.values[column], val)
+		}
+	}
+
+	return nil
+}
+
+// statsRow statistics of a column of a group
+type statsRow struct {
+	keys   []string
+	column string
+	stats  []float64
+}
+
+// header return the header for the statistics rows
+func (s *Stats) header() []string {
+	header := append(append([]string{}, s.config.KeyColumns...), "column")
+	return append(header, Headers...)
+}
+
+// rows return a row with the statistics per group and column, sorted by the key columns
+func (s *Stats) rows() []statsRow {
+	groupKeys := make([]string, 0, len(s.groups))
+	for key := range s.groups {
+		groupKeys = append(groupKeys, key)
+	}
+	sort.Strings(groupKeys)
+
+	rows := []statsRow{}
+	for _, key := range groupKeys {
+		g := s.groups[key]
+		for _, column := range s.config.Columns {
+			values, ok := g.values[column]
+			if !ok || len(values) == 0 {
+				continue
+			}
+			rows = append(rows, statsRow{
+				keys:   g.keys,
+				column: column,
+				stats:  Calculate(values),
+			})
+		}
+	}
+
+	return rows
+}
+
+// cells return the cells of the row with the statistics formatted by the format func
+func (r statsRow) cells(format func(val float64) string) []string {
+	cells := append(append([]string{}, r.keys...), r.column)
+	for _, val := range r.stats {
+		cells = append(cells, format(val))
+	}
+	return cells
+}
+
+// Calculate return the count, min, max, mean, stddev (population), p50, p90 and p99 of the values, nil is returned
+// when there are no values
+func Calculate(values []float64) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, val := range sorted {
+		sum += val
+	}
+	count := float64(len(sorted))
+	mean := sum / count
+
+	variance := 0.0
+	for _, val := range sorted {
+		variance += (val - mean) * (val - mean)
+	}
+	stddev := math.Sqrt(variance / count)
+
+	return []float64{
+		count,
+		sorted[0],
+		sorted[len(sorted)-1],
+		mean,
+		stddev,
+		percentile(sorted, 50),
+		percentile(sorted, 90),
+		percentile(sorted, 99),
+	}
+}
+
+// percentile return the percentile of the sorted values, linearly interpolated between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func (s *Stats) writeCSV(outPath string, rows []statsRow) error {
+	buffer := bytes.NewBuffer([]byte{})
+	writer := csv.NewWriter(buffer)
+	writer.Comma = *s.config.Separator
+
+	if err := writer.Write(s.header()); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := writer.Write(r.cells(func(val float64) string {
+			return strconv.FormatFloat(val, 'f', -1, 64)
+		})); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return util.WriteNewTruncFile(outPath, buffer.Bytes())
+}
+
+func (s *Stats) printTable(rows []statsRow) error {
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\t\n", strings.ToUpper(strings.Join(s.header(), "\t")))
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t\n", strings.Join(r.cells(formatValue), "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatValue(val float64) string {
+	return strconv.FormatFloat(val, 'g', 6, 64)
+}
+
+// OutputFiles return a list of output files
+func (s *Stats) OutputFiles() []string {
+	list := []string{}
+	for file := range s.files {
+		list = append(list, file)
+	}
+	return list
+}
+
+// Close write the statistics CSV file and print the statistics table
+func (s *Stats) Close() error {
+	if s.data == nil || len(s.groups) == 0 {
+		s.logger.Warn("no data received for stats, no files written")
+		return nil
+	}
+
+	filename, err := outputs.GetFilenameFromPattern(s.config.NamePattern, "", *s.data, nil)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(s.config.FilePath.FilePath, filename)
+
+	rows := s.rows()
+
+	if err := s.writeCSV(outPath, rows); err != nil {
+		return err
+	}
+	s.files[outPath] = struct{}{}
+
+	if *s.config.PrintTable {
+		return s.printTable(rows)
+	}
+
+	return nil
+}
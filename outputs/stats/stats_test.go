@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/outputs/tests"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStats(t *testing.T) {
+	tempDir := t.TempDir()
+
+	outCfg := &config.Output{
+		Stats: &config.Stats{
+			FilePath: config.FilePath{
+				FilePath: tempDir,
+			},
+			Columns: []string{"bits_per_second", "doesnotexist"},
+			DataFilter: config.DataFilter{
+				RowFilter: config.RowFilter{
+					Filters: map[string]string{
+						"kind": "sum",
+					},
+				},
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+
+	o, err := NewStatsOutput(zap.NewNop(), nil, outCfg)
+	require.Nil(t, err)
+	out := &bytes.Buffer{}
+	o.(*Stats).out = out
+
+	require.Nil(t, o.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 100, 200)))
+	require.Nil(t, o.Do(tests.GenerateMockIPerf3Data("node1", "node2", outputs.DataTypeInterval, 300, 400)))
+	// NaN and infinite values must be skipped
+	require.Nil(t, o.Do(tests.GenerateMockIPerf3Data("node2", "node1", outputs.DataTypeInterval, 50, math.NaN(), math.Inf(1))))
+	// Summary data must be ignored with the default data type
+	require.Nil(t, o.Do(tests.GenerateMockIPerf3Data("node2", "node3", outputs.DataTypeSummary, 1000)))
+	require.Nil(t, o.Close())
+
+	csvPath := filepath.Join(tempDir, "ancientt-1000-iperf3-stats.csv")
+	assert.Equal(t, []string{csvPath}, o.OutputFiles())
+	content, err := os.ReadFile(csvPath)
+	require.Nil(t, err)
+	assert.Equal(t, `tester;server_host;client_host;column;count;min;max;mean;stddev;p50;p90;p99
+iperf3;node1;node2;bits_per_second;4;100;400;250;111.80339887498948;250;370;397
+iperf3;node2;node1;bits_per_second;1;50;50;50;0;50;50;50
+`, string(content))
+
+	assert.Contains(t, out.String(), "TESTER")
+	assert.Contains(t, out.String(), "111.803")
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+	assert.Equal(t, 10.0, percentile(sorted, 0))
+	assert.Equal(t, 30.0, percentile(sorted, 50))
+	assert.Equal(t, 46.0, percentile(sorted, 90))
+	assert.Equal(t, 50.0, percentile(sorted, 100))
+	assert.Equal(t, 7.0, percentile([]float64{7}, 99))
+}
+
+func TestCalculate(t *testing.T) {
+	assert.Nil(t, Calculate(nil))
+	assert.Nil(t, Calculate([]float64{}))
+
+	assert.Equal(t, []float64{1, 7, 7, 7, 0, 7, 7, 7}, Calculate([]float64{7}))
+	assert.Equal(t, []float64{2, 10, 30, 20, 10, 20, 28, 29.8}, Calculate([]float64{30, 10}))
+}
@@ -60,3 +60,47 @@ func GenerateMockTableData(length int) outputs.Data {
 		Data:           table,
 	}
 }
+
+// GenerateMockIPerf3Data generate IPerf3 like mock data with a `sum` row per value (`bits_per_second` column) and
+// one `stream` row, which must be ignored by outputs filtering on the `sum` rows
+func GenerateMockIPerf3Data(server string, client string, dataType outputs.DataType, values ...float64) outputs.Data {
+	table := &outputs.Table{
+		Headers: []*outputs.Row{
+			{Value: "tester"},
+			{Value: "server_host"},
+			{Value: "client_host"},
+			{Value: "round"},
+			{Value: "kind"},
+			{Value: "bits_per_second"},
+		},
+	}
+	for _, val := range values {
+		table.Rows = append(table.Rows, []*outputs.Row{
+			{Value: "iperf3"},
+			{Value: server},
+			{Value: client},
+			{Value: 0},
+			{Value: "sum"},
+			{Value: val},
+		})
+	}
+	table.Rows = append(table.Rows, []*outputs.Row{
+		{Value: "iperf3"},
+		{Value: server},
+		{Value: client},
+		{Value: 0},
+		{Value: "stream"},
+		{Value: float64(1)},
+	})
+
+	return outputs.Data{
+		Test:          "iperf3-test",
+		TestStartTime: time.Unix(1000, 0).UTC(),
+		TestTime:      time.Unix(1000, 0).UTC(),
+		Tester:        "iperf3",
+		ServerHost:    server,
+		ClientHost:    client,
+		Type:          dataType,
+		Data:          table,
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/galexrt/ancientt/outputs"
 	"github.com/galexrt/ancientt/parsers"
@@ -27,8 +28,10 @@ import (
 	"go.uber.org/zap"
 )
 
-// NameIPerf3 IPerf3 tester name
-const NameIPerf3 = "iperf3"
+const (
+	// NameIPerf3 IPerf3 tester name
+	NameIPerf3 = "iperf3"
+)
 
 func init() {
 	parsers.Factories[NameIPerf3] = NewIPerf3Tester
@@ -65,43 +68,136 @@ func (p IPerf3) Parse(doneCh chan struct{}, inCh <-chan parsers.Input, dataCh ch
 				return nil
 			}
 			if err := p.parse(input, dataCh); err != nil {
-				return err
+				// Report the failed input to the task Status and continue with the next input
+				p.logger.Error("failed to parse input", zap.String("server", input.ServerHost), zap.String("client", input.ClientHost), zap.Error(err))
+				input.AddFailedClient(err)
+				continue
 			}
+			input.AddSuccessfulClient()
 		}
 	}
 }
 
-func (p IPerf3) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
+func (p IPerf3) readResult(input parsers.Input) (*models.ClientResult, error) {
 	var logs *bytes.Buffer
 	if input.DataStream != nil {
 		logs = new(bytes.Buffer)
 		if _, err := io.Copy(logs, *input.DataStream); err != nil {
-			return fmt.Errorf("error in copy information from logs to buffer")
+			return nil, fmt.Errorf("error in copy information from logs to buffer")
 		}
 		if err := (*input.DataStream).Close(); err != nil {
-			return fmt.Errorf("error during closing input.DataStream. %+v", err)
+			return nil, fmt.Errorf("error during closing input.DataStream. %+v", err)
 		}
 	} else if len(input.Data) > 0 {
 		// Directly pump the data in the logs var
 		p.logger.Warn("received input.Data instead of input.DataStream, who wrote that runners without stream support")
 		logs = bytes.NewBuffer(input.Data)
 	} else {
-		return fmt.Errorf("no data stream nor data from Input channel")
+		return nil, fmt.Errorf("no data stream nor data from Input channel")
 	}
 
 	// Parse JSON response
 	result := &models.ClientResult{}
 	if err := json.Unmarshal(logs.Bytes(), result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (p IPerf3) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
+	result, err := p.readResult(input)
+	if err != nil {
 		return err
 	}
 
-	intervalTable := &outputs.Table{
-		Headers: []*outputs.Row{
-			{Value: "test_time"},
-			{Value: "round"},
-			{Value: "tester"},
-			{Value: "server_host"},
-			{Value: "client_host"},
+	// IPerf3 still returns JSON when the test fails, report it as a failed client instead of empty data
+	if err := checkResult(result); err != nil {
+		return err
+	}
+
+	var intervalTable *outputs.Table
+	if p.isUDP(result) {
+		intervalTable = udpIntervalTable(input, result)
+	} else {
+		intervalTable = tcpIntervalTable(input, result)
+	}
+
+	p.logger.Debug("parsed data input")
+
+	// Transform Input into outputs.Data struct
+	data := outputs.Data{
+		TestStartTime:  input.TestStartTime,
+		TestTime:       input.TestTime,
+		AdditionalInfo: input.AdditionalInfo,
+		ServerHost:     input.ServerHost,
+		ClientHost:     input.ClientHost,
+		IPFamily:       input.IPFamily,
+		Tester:         input.Tester,
+		Type:           outputs.DataTypeInterval,
+		Data:           intervalTable,
+	}
+
+	p.logger.Debug("sending parsed data to dataCh")
+
+	dataCh <- data
+
+	p.logger.Debug("sent parsed data to dataCh")
+
+	p.summary(input, result, dataCh)
+
+	return nil
+}
+
+// checkResult check the IPerf3 result for an error or missing data
+func checkResult(result *models.ClientResult) error {
+	if result.Error != "" {
+		return fmt.Errorf("iperf3 returned error. %s", result.Error)
+	}
+	if len(result.Intervals) == 0 {
+		return fmt.Errorf("iperf3 result contains no intervals")
+	}
+	return nil
+}
+
+// isUDP return true if the IPerf3 result is from a UDP test, falls back to the test config if the result
+// doesn't contain the protocol
+func (p IPerf3) isUDP(result *models.ClientResult) bool {
+	if result.Start.TestStart.Protocol != "" {
+		return strings.EqualFold(result.Start.TestStart.Protocol, models.ProtocolUDP)
+	}
+	return p.config != nil && p.config.IPerf3 != nil && p.config.IPerf3.UDP != nil && *p.config.IPerf3.UDP
+}
+
+// baseRows return the rows that each row of the IPerf3 tables begins with
+func baseRows(input parsers.Input, kind string) []*outputs.Row {
+	return []*outputs.Row{
+		{Value: input.TestTime.Format(util.TimeDateFormat)},
+		{Value: input.Round},
+		{Value: input.Tester},
+		{Value: input.ServerHost},
+		{Value: input.ClientHost},
+		{Value: string(input.IPFamily)},
+		{Value: kind},
+	}
+}
+
+// baseHeaders return the headers for the baseRows
+func baseHeaders() []*outputs.Row {
+	return []*outputs.Row{
+		{Value: "test_time"},
+		{Value: "round"},
+		{Value: "tester"},
+		{Value: "server_host"},
+		{Value: "client_host"},
+		{Value: "ip_family"},
+		{Value: config.ColumnKind},
+	}
+}
+
+func tcpIntervalTable(input parsers.Input, result *models.ClientResult) *outputs.Table {
+	table := &outputs.Table{
+		Headers: append(baseHeaders(), []*outputs.Row{
 			{Value: "socket"},
 			{Value: "start"},
 			{Value: "end"},
@@ -117,18 +213,13 @@ func (p IPerf3) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
 			{Value: "iperf3_version"},
 			{Value: "system_info"},
 			{Value: "additional_info"},
-		},
+		}...),
 		Rows: [][]*outputs.Row{},
 	}
 
 	for _, interval := range result.Intervals {
 		for _, stream := range interval.Streams {
-			intervalTable.Rows = append(intervalTable.Rows, []*outputs.Row{
-				{Value: input.TestTime.Format(util.TimeDateFormat)},
-				{Value: input.Round},
-				{Value: input.Tester},
-				{Value: input.ServerHost},
-				{Value: input.ClientHost},
+			table.Rows = append(table.Rows, append(baseRows(input, config.KindStream), []*outputs.Row{
 				{Value: stream.Socket},
 				{Value: stream.Start},
 				{Value: stream.End},
@@ -144,30 +235,298 @@ func (p IPerf3) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
 				{Value: result.Start.Version},
 				{Value: result.Start.SystemInfo},
 				{Value: input.AdditionalInfo},
-			})
+			}...))
 		}
+
+		// Add the sum of all streams of the interval, the stream specific columns are left empty (`nil`)
+		table.Rows = append(table.Rows, append(baseRows(input, config.KindSum), []*outputs.Row{
+			nil,
+			{Value: interval.Sum.Start},
+			{Value: interval.Sum.End},
+			{Value: interval.Sum.Seconds},
+			{Value: interval.Sum.Bytes},
+			{Value: interval.Sum.BitsPerSecond},
+			{Value: interval.Sum.Retransmits},
+			nil,
+			nil,
+			nil,
+			nil,
+			{Value: interval.Sum.Omitted},
+			{Value: result.Start.Version},
+			{Value: result.Start.SystemInfo},
+			{Value: input.AdditionalInfo},
+		}...))
 	}
 
-	p.logger.Debug("parsed data input")
+	return table
+}
+
+func udpIntervalTable(input parsers.Input, result *models.ClientResult) *outputs.Table {
+	table := &outputs.Table{
+		Headers: append(baseHeaders(), []*outputs.Row{
+			{Value: "socket"},
+			{Value: "start"},
+			{Value: "end"},
+			{Value: "seconds"},
+			{Value: "bytes"},
+			{Value: "bits_per_second"},
+			{Value: "packets"},
+			{Value: "jitter_ms"},
+			{Value: "lost_packets"},
+			{Value: "lost_percent"},
+			{Value: "out_of_order"},
+			{Value: "omitted"},
+			{Value: "iperf3_version"},
+			{Value: "system_info"},
+			{Value: "additional_info"},
+		}...),
+		Rows: [][]*outputs.Row{},
+	}
+
+	for _, interval := range result.Intervals {
+		for _, stream := range interval.Streams {
+			table.Rows = append(table.Rows, append(baseRows(input, config.KindStream), []*outputs.Row{
+				{Value: stream.Socket},
+				{Value: stream.Start},
+				{Value: stream.End},
+				{Value: stream.Seconds},
+				{Value: stream.Bytes},
+				{Value: stream.BitsPerSecond},
+				{Value: stream.Packets},
+				{Value: stream.JitterMs},
+				{Value: stream.LostPackets},
+				{Value: stream.LostPercent},
+				{Value: stream.OutOfOrder},
+				{Value: stream.Omitted},
+				{Value: result.Start.Version},
+				{Value: result.Start.SystemInfo},
+				{Value: input.AdditionalInfo},
+			}...))
+		}
+
+		// Add the sum of all streams of the interval, the stream specific columns are left empty (`nil`)
+		table.Rows = append(table.Rows, append(baseRows(input, config.KindSum), []*outputs.Row{
+			nil,
+			{Value: interval.Sum.Start},
+			{Value: interval.Sum.End},
+			{Value: interval.Sum.Seconds},
+			{Value: interval.Sum.Bytes},
+			{Value: interval.Sum.BitsPerSecond},
+			{Value: interval.Sum.Packets},
+			{Value: interval.Sum.JitterMs},
+			{Value: interval.Sum.LostPackets},
+			{Value: interval.Sum.LostPercent},
+			{Value: interval.Sum.OutOfOrder},
+			{Value: interval.Sum.Omitted},
+			{Value: result.Start.Version},
+			{Value: result.Start.SystemInfo},
+			{Value: input.AdditionalInfo},
+		}...))
+	}
+
+	return table
+}
+
+// summary generate the summary table from the IPerf3 end results and send it to the dataCh
+func (p IPerf3) summary(input parsers.Input, result *models.ClientResult, dataCh chan<- outputs.Data) {
+	var summaryTable *outputs.Table
+	if p.isUDP(result) {
+		summaryTable = udpSummaryTable(input, result)
+	} else {
+		summaryTable = tcpSummaryTable(input, result)
+	}
 
-	// Transform Input into outputs.Data struct
 	data := outputs.Data{
 		TestStartTime:  input.TestStartTime,
 		TestTime:       input.TestTime,
 		AdditionalInfo: input.AdditionalInfo,
 		ServerHost:     input.ServerHost,
 		ClientHost:     input.ClientHost,
+		IPFamily:       input.IPFamily,
 		Tester:         input.Tester,
-		Data:           intervalTable,
+		Type:           outputs.DataTypeSummary,
+		Data:           summaryTable,
 	}
 
-	p.logger.Debug("sending parsed data to dataCh")
+	p.logger.Debug("sending summary data to dataCh")
 
 	dataCh <- data
 
-	// TODO generate sum and / or end table and send to output
+	p.logger.Debug("sent summary data to dataCh")
+}
 
-	p.logger.Debug("sent parsed data to dataCh")
+// cpuUtilizationHeaders return the headers for the cpuUtilizationRows
+func cpuUtilizationHeaders() []*outputs.Row {
+	return []*outputs.Row{
+		{Value: "cpu_host_total"},
+		{Value: "cpu_host_user"},
+		{Value: "cpu_host_system"},
+		{Value: "cpu_remote_total"},
+		{Value: "cpu_remote_user"},
+		{Value: "cpu_remote_system"},
+	}
+}
 
-	return nil
+func cpuUtilizationRows(cpu models.CPUUtilizationPercent) []*outputs.Row {
+	return []*outputs.Row{
+		{Value: cpu.HostTotal},
+		{Value: cpu.HostUser},
+		{Value: cpu.HostSystem},
+		{Value: cpu.RemoteTotal},
+		{Value: cpu.RemoteUser},
+		{Value: cpu.RemoteSystem},
+	}
+}
+
+func tcpSummaryTable(input parsers.Input, result *models.ClientResult) *outputs.Table {
+	headers := append(baseHeaders(), []*outputs.Row{
+		{Value: "socket"},
+		{Value: "start"},
+		{Value: "end"},
+		{Value: "seconds"},
+		{Value: "sent_bytes"},
+		{Value: "sent_bits_per_second"},
+		{Value: "retransmits"},
+		{Value: "max_snd_cwnd"},
+		{Value: "max_rtt"},
+		{Value: "min_rtt"},
+		{Value: "mean_rtt"},
+		{Value: "received_bytes"},
+		{Value: "received_bits_per_second"},
+	}...)
+	headers = append(headers, cpuUtilizationHeaders()...)
+	headers = append(headers, []*outputs.Row{
+		{Value: "sender_tcp_congestion"},
+		{Value: "receiver_tcp_congestion"},
+		{Value: "iperf3_version"},
+		{Value: "additional_info"},
+	}...)
+
+	table := &outputs.Table{
+		Headers: headers,
+		Rows:    [][]*outputs.Row{},
+	}
+
+	end := result.End
+	// The values that are the same for each row of the summary table
+	tail := func() []*outputs.Row {
+		return append(cpuUtilizationRows(end.CPUUtilizationPercent), []*outputs.Row{
+			{Value: end.SenderTCPCongestion},
+			{Value: end.ReceiverTCPCongestion},
+			{Value: result.Start.Version},
+			{Value: input.AdditionalInfo},
+		}...)
+	}
+
+	for _, stream := range end.Streams {
+		row := append(baseRows(input, config.KindStream), []*outputs.Row{
+			{Value: stream.Sender.Socket},
+			{Value: stream.Sender.Start},
+			{Value: stream.Sender.End},
+			{Value: stream.Sender.Seconds},
+			{Value: stream.Sender.Bytes},
+			{Value: stream.Sender.BitsPerSecond},
+			{Value: stream.Sender.Retransmits},
+			{Value: stream.Sender.MaxSndCwnd},
+			{Value: stream.Sender.MaxRTT},
+			{Value: stream.Sender.MinRTT},
+			{Value: stream.Sender.MeanRTT},
+			{Value: stream.Receiver.Bytes},
+			{Value: stream.Receiver.BitsPerSecond},
+		}...)
+		table.Rows = append(table.Rows, append(row, tail()...))
+	}
+
+	// The sum of all streams, the stream specific columns are left empty (`nil`)
+	row := append(baseRows(input, config.KindSum), []*outputs.Row{
+		nil,
+		{Value: end.SumSent.Start},
+		{Value: end.SumSent.End},
+		{Value: end.SumSent.Seconds},
+		{Value: end.SumSent.Bytes},
+		{Value: end.SumSent.BitsPerSecond},
+		{Value: end.SumSent.Retransmits},
+		nil,
+		nil,
+		nil,
+		nil,
+		{Value: end.SumReceived.Bytes},
+		{Value: end.SumReceived.BitsPerSecond},
+	}...)
+	table.Rows = append(table.Rows, append(row, tail()...))
+
+	return table
+}
+
+func udpSummaryTable(input parsers.Input, result *models.ClientResult) *outputs.Table {
+	headers := append(baseHeaders(), []*outputs.Row{
+		{Value: "socket"},
+		{Value: "start"},
+		{Value: "end"},
+		{Value: "seconds"},
+		{Value: "bytes"},
+		{Value: "bits_per_second"},
+		{Value: "packets"},
+		{Value: "jitter_ms"},
+		{Value: "lost_packets"},
+		{Value: "lost_percent"},
+		{Value: "out_of_order"},
+	}...)
+	headers = append(headers, cpuUtilizationHeaders()...)
+	headers = append(headers, []*outputs.Row{
+		{Value: "iperf3_version"},
+		{Value: "additional_info"},
+	}...)
+
+	table := &outputs.Table{
+		Headers: headers,
+		Rows:    [][]*outputs.Row{},
+	}
+
+	end := result.End
+	// The values that are the same for each row of the summary table
+	tail := func() []*outputs.Row {
+		return append(cpuUtilizationRows(end.CPUUtilizationPercent), []*outputs.Row{
+			{Value: result.Start.Version},
+			{Value: input.AdditionalInfo},
+		}...)
+	}
+
+	for _, stream := range end.Streams {
+		if stream.UDP == nil {
+			continue
+		}
+		row := append(baseRows(input, config.KindStream), []*outputs.Row{
+			{Value: stream.UDP.Socket},
+			{Value: stream.UDP.Start},
+			{Value: stream.UDP.End},
+			{Value: stream.UDP.Seconds},
+			{Value: stream.UDP.Bytes},
+			{Value: stream.UDP.BitsPerSecond},
+			{Value: stream.UDP.Packets},
+			{Value: stream.UDP.JitterMs},
+			{Value: stream.UDP.LostPackets},
+			{Value: stream.UDP.LostPercent},
+			{Value: stream.UDP.OutOfOrder},
+		}...)
+		table.Rows = append(table.Rows, append(row, tail()...))
+	}
+
+	// The sum of all streams, the stream specific columns are left empty (`nil`)
+	row := append(baseRows(input, config.KindSum), []*outputs.Row{
+		nil,
+		{Value: end.Sum.Start},
+		{Value: end.Sum.End},
+		{Value: end.Sum.Seconds},
+		{Value: end.Sum.Bytes},
+		{Value: end.Sum.BitsPerSecond},
+		{Value: end.Sum.Packets},
+		{Value: end.Sum.JitterMs},
+		{Value: end.Sum.LostPackets},
+		{Value: end.Sum.LostPercent},
+		{Value: end.Sum.OutOfOrder},
+	}...)
+	table.Rows = append(table.Rows, append(row, tail()...))
+
+	return table
 }
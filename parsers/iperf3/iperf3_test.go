@@ -0,0 +1,271 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iperf3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const tcpResult = `{
+	"start": {
+		"version": "iperf 3.9",
+		"system_info": "Linux test",
+		"test_start": {
+			"protocol": "TCP",
+			"num_streams": 2
+		}
+	},
+	"intervals": [
+		{
+			"streams": [
+				{"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800, "retransmits": 1, "snd_cwnd": 10, "rtt": 20},
+				{"socket": 7, "start": 0, "end": 1, "seconds": 1, "bytes": 200, "bits_per_second": 1600, "retransmits": 0, "snd_cwnd": 10, "rtt": 30}
+			],
+			"sum": {"start": 0, "end": 1, "seconds": 1, "bytes": 300, "bits_per_second": 2400, "retransmits": 1}
+		}
+	],
+	"end": {
+		"streams": [
+			{
+				"sender": {"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": 800, "retransmits": 1, "max_snd_cwnd": 10, "max_rtt": 20, "min_rtt": 20, "mean_rtt": 20},
+				"receiver": {"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 90, "bits_per_second": 720}
+			},
+			{
+				"sender": {"socket": 7, "start": 0, "end": 1, "seconds": 1, "bytes": 200, "bits_per_second": 1600, "retransmits": 0, "max_snd_cwnd": 10, "max_rtt": 30, "min_rtt": 30, "mean_rtt": 30},
+				"receiver": {"socket": 7, "start": 0, "end": 1, "seconds": 1, "bytes": 190, "bits_per_second": 1520}
+			}
+		],
+		"sum_sent": {"start": 0, "end": 1, "seconds": 1, "bytes": 300, "bits_per_second": 2400, "retransmits": 1},
+		"sum_received": {"start": 0, "end": 1, "seconds": 1, "bytes": 280, "bits_per_second": 2240},
+		"cpu_utilization_percent": {"host_total": 10.5, "host_user": 2.5, "host_system": 8, "remote_total": 5, "remote_user": 1, "remote_system": 4},
+		"sender_tcp_congestion": "cubic",
+		"receiver_tcp_congestion": "bbr"
+	}
+}`
+
+func newTestInput(data string) parsers.Input {
+	return parsers.Input{
+		TestStartTime: time.Now(),
+		TestTime:      time.Now(),
+		Tester:        NameIPerf3,
+		ServerHost:    "server1",
+		ClientHost:    "client1",
+		IPFamily:      config.IPFamilyIPv6,
+		Data:          []byte(data),
+	}
+}
+
+// getColumn return the values of a column by header name from the table
+func getColumn(t *testing.T, table *outputs.Table, name string) []interface{} {
+	index, err := table.GetHeaderIndexByName(name)
+	require.Nil(t, err)
+	require.NotEqual(t, -1, index, "column %s not found", name)
+
+	values := []interface{}{}
+	for _, row := range table.Rows {
+		// Empty cells are returned as nil
+		if row[index] == nil {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, row[index].Value)
+	}
+	return values
+}
+
+func TestParseIntervalSum(t *testing.T) {
+	parser, err := NewIPerf3Tester(zap.NewNop(), nil, nil)
+	require.Nil(t, err)
+	p := parser.(IPerf3)
+
+	dataCh := make(chan outputs.Data, 2)
+	require.Nil(t, p.parse(newTestInput(tcpResult), dataCh))
+	close(dataCh)
+
+	interval := <-dataCh
+	table, ok := interval.Data.(*outputs.Table)
+	require.True(t, ok)
+	// Two stream rows and the sum row of the interval
+	require.Equal(t, 3, len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Headers), len(row))
+	}
+
+	assert.Equal(t, []interface{}{config.KindStream, config.KindStream, config.KindSum}, getColumn(t, table, "kind"))
+	assert.Equal(t, []interface{}{"ipv6", "ipv6", "ipv6"}, getColumn(t, table, "ip_family"))
+	assert.Equal(t, config.IPFamilyIPv6, interval.IPFamily)
+	// The stream specific columns are empty for the sum row
+	assert.Equal(t, []interface{}{int64(5), int64(7), nil}, getColumn(t, table, "socket"))
+	assert.Equal(t, []interface{}{int64(20), int64(30), nil}, getColumn(t, table, "rtt"))
+	for _, name := range []string{"snd_cwnd", "rttvar", "pmtu"} {
+		assert.Nil(t, getColumn(t, table, name)[2], "column %s not empty in sum row", name)
+	}
+	assert.Equal(t, []interface{}{float64(800), float64(1600), float64(2400)}, getColumn(t, table, "bits_per_second"))
+	assert.Equal(t, []interface{}{int64(1), int64(0), int64(1)}, getColumn(t, table, "retransmits"))
+}
+
+func TestParseSummary(t *testing.T) {
+	parser, err := NewIPerf3Tester(zap.NewNop(), nil, nil)
+	require.Nil(t, err)
+	p := parser.(IPerf3)
+
+	dataCh := make(chan outputs.Data, 2)
+	require.Nil(t, p.parse(newTestInput(tcpResult), dataCh))
+	close(dataCh)
+
+	interval := <-dataCh
+	assert.Equal(t, outputs.DataTypeInterval, interval.Type)
+	assert.False(t, interval.IsSummary())
+
+	summary := <-dataCh
+	assert.Equal(t, outputs.DataTypeSummary, summary.Type)
+	assert.True(t, summary.IsSummary())
+
+	table, ok := summary.Data.(*outputs.Table)
+	require.True(t, ok)
+	require.Equal(t, 3, len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Headers), len(row))
+	}
+
+	assert.Equal(t, []interface{}{config.KindStream, config.KindStream, config.KindSum}, getColumn(t, table, "kind"))
+	assert.Equal(t, []interface{}{float64(800), float64(1600), float64(2400)}, getColumn(t, table, "sent_bits_per_second"))
+	assert.Equal(t, []interface{}{float64(720), float64(1520), float64(2240)}, getColumn(t, table, "received_bits_per_second"))
+	// The stream specific columns are empty for the sum row
+	assert.Equal(t, []interface{}{int64(5), int64(7), nil}, getColumn(t, table, "socket"))
+	assert.Equal(t, []interface{}{int64(20), int64(30), nil}, getColumn(t, table, "mean_rtt"))
+	assert.Nil(t, getColumn(t, table, "max_snd_cwnd")[2])
+	assert.Nil(t, getColumn(t, table, "max_rtt")[2])
+	assert.Nil(t, getColumn(t, table, "min_rtt")[2])
+	assert.Equal(t, float64(10.5), getColumn(t, table, "cpu_host_total")[2])
+	assert.Equal(t, "cubic", getColumn(t, table, "sender_tcp_congestion")[2])
+	assert.Equal(t, "bbr", getColumn(t, table, "receiver_tcp_congestion")[2])
+}
+
+const udpResult = `{
+	"start": {
+		"version": "iperf 3.9",
+		"test_start": {
+			"protocol": "UDP",
+			"num_streams": 1
+		}
+	},
+	"intervals": [
+		{
+			"streams": [
+				{"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 131072, "bits_per_second": 1048576, "packets": 90, "omitted": false}
+			],
+			"sum": {"start": 0, "end": 1, "seconds": 1, "bytes": 131072, "bits_per_second": 1048576, "packets": 90, "omitted": false}
+		}
+	],
+	"end": {
+		"streams": [
+			{
+				"udp": {"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 131072, "bits_per_second": 1048576, "jitter_ms": 0.025, "lost_packets": 3, "packets": 90, "lost_percent": 3.33, "out_of_order": 1}
+			}
+		],
+		"sum": {"start": 0, "end": 1, "seconds": 1, "bytes": 131072, "bits_per_second": 1048576, "jitter_ms": 0.025, "lost_packets": 3, "packets": 90, "lost_percent": 3.33},
+		"cpu_utilization_percent": {"host_total": 1.5, "host_user": 0.5, "host_system": 1, "remote_total": 0.5, "remote_user": 0.25, "remote_system": 0.25}
+	}
+}`
+
+func TestParseUDP(t *testing.T) {
+	parser, err := NewIPerf3Tester(zap.NewNop(), nil, nil)
+	require.Nil(t, err)
+	p := parser.(IPerf3)
+
+	dataCh := make(chan outputs.Data, 2)
+	require.Nil(t, p.parse(newTestInput(udpResult), dataCh))
+	close(dataCh)
+
+	interval := <-dataCh
+	table, ok := interval.Data.(*outputs.Table)
+	require.True(t, ok)
+	require.Equal(t, 2, len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Headers), len(row))
+	}
+	assert.Equal(t, []interface{}{int64(90), int64(90)}, getColumn(t, table, "packets"))
+	assert.Equal(t, []interface{}{int64(5), nil}, getColumn(t, table, "socket"))
+	// TCP only columns must not be in the UDP table
+	for _, name := range []string{"snd_cwnd", "rtt", "rttvar", "retransmits"} {
+		index, err := table.GetHeaderIndexByName(name)
+		require.Nil(t, err)
+		assert.Equal(t, -1, index, "column %s found in UDP table", name)
+	}
+
+	summary := <-dataCh
+	table, ok = summary.Data.(*outputs.Table)
+	require.True(t, ok)
+	require.Equal(t, 2, len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Headers), len(row))
+	}
+	assert.Equal(t, []interface{}{config.KindStream, config.KindSum}, getColumn(t, table, "kind"))
+	assert.Equal(t, []interface{}{float64(0.025), float64(0.025)}, getColumn(t, table, "jitter_ms"))
+	assert.Equal(t, []interface{}{int64(3), int64(3)}, getColumn(t, table, "lost_packets"))
+	assert.Equal(t, []interface{}{float64(3.33), float64(3.33)}, getColumn(t, table, "lost_percent"))
+	assert.Equal(t, []interface{}{int64(1), int64(0)}, getColumn(t, table, "out_of_order"))
+	assert.Equal(t, []interface{}{int64(5), nil}, getColumn(t, table, "socket"))
+	index, err := table.GetHeaderIndexByName("sender_tcp_congestion")
+	require.Nil(t, err)
+	assert.Equal(t, -1, index)
+}
+
+func TestParseFailedResults(t *testing.T) {
+	parser, err := NewIPerf3Tester(zap.NewNop(), nil, nil)
+	require.Nil(t, err)
+
+	for name, result := range map[string]string{
+		"error":        `{"start": {"version": "iperf 3.9"}, "intervals": [], "end": {}, "error": "error - the server is busy running a test. try again later"}`,
+		"no-intervals": `{"start": {"version": "iperf 3.9"}, "intervals": [], "end": {}}`,
+	} {
+		status := testers.NewStatus()
+
+		inCh := make(chan parsers.Input)
+		dataCh := make(chan outputs.Data, 4)
+		parseErr := make(chan error, 1)
+		go func() {
+			parseErr <- parser.Parse(make(chan struct{}), inCh, dataCh)
+		}()
+
+		input := newTestInput(result)
+		input.Status = status
+		inCh <- input
+		// Without a task Status the failure is only logged, the parser must continue with the next input
+		input.Status = nil
+		inCh <- input
+		input = newTestInput(tcpResult)
+		input.Status = status
+		inCh <- input
+		close(inCh)
+		require.Nil(t, <-parseErr, name)
+
+		// Only the data of the successful result must be sent to the outputs
+		assert.Len(t, dataCh, 2, name)
+		// The parser reports the result of each client to the task Status
+		assert.Equal(t, 1, status.FailedHosts.Clients["client1"], name)
+		assert.Equal(t, 1, len(status.Errors.Clients["client1"]), name)
+		assert.Equal(t, 1, status.SuccessfulHosts.Clients["client1"], name)
+	}
+}
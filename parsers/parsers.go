@@ -19,6 +19,7 @@ import (
 
 	"github.com/galexrt/ancientt/outputs"
 	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
 	"go.uber.org/zap"
 )
 
@@ -28,10 +29,9 @@ var Factories = make(map[string]func(logger *zap.Logger, cfg *config.Config, tes
 
 // Parser is the interface a parser has to implement
 type Parser interface {
-	// Parse parse data from runners.Execute() func
+	// Parse parse data from runners.Execute() func, the (interval) data and the summary data (if the tester has one)
+	// of each Input are sent as separate outputs.Data
 	Parse(doneCh chan struct{}, inCh <-chan Input, dataCh chan<- outputs.Data) error
-	// Summary send summary of parsed data to outputs.Output
-	Summary(doneCh chan struct{}, inCh <-chan Input, dataCh chan<- outputs.Data) error
 }
 
 // Input structured parse
@@ -44,5 +44,29 @@ type Input struct {
 	Tester         string
 	ServerHost     string
 	ClientHost     string
+	IPFamily       config.IPFamily
 	AdditionalInfo string
+	// Status of the task the Input is from, used by the parsers to report the result of the client. Once the Input
+	// has been sent to the parser, the runners must not report the result of the client themselves.
+	Status *testers.Status
+}
+
+// AddFailedClient add the client host of the Input as failed with the error to the task Status, if the Input has one
+func (i Input) AddFailedClient(err error) {
+	if i.Status == nil {
+		return
+	}
+	i.Status.AddFailedClient(&testers.Host{
+		Name: i.ClientHost,
+	}, err)
+}
+
+// AddSuccessfulClient add the client host of the Input as successful to the task Status, if the Input has one
+func (i Input) AddSuccessfulClient() {
+	if i.Status == nil {
+		return
+	}
+	i.Status.AddSuccessfulClient(&testers.Host{
+		Name: i.ClientHost,
+	})
 }
@@ -65,87 +65,64 @@ func (p PingParsing) Parse(doneCh chan struct{}, inCh <-chan parsers.Input, data
 				return nil
 			}
 			if err := p.parse(input, dataCh); err != nil {
-				return err
+				// Report the failed input to the task Status and continue with the next input
+				p.logger.Error("failed to parse input", zap.String("server", input.ServerHost), zap.String("client", input.ClientHost), zap.Error(err))
+				input.AddFailedClient(err)
+				continue
 			}
+			input.AddSuccessfulClient()
 		}
 	}
 }
 
-func (p PingParsing) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
+func (p PingParsing) readResults(input parsers.Input) (models.ClientResults, error) {
 	var logs *bytes.Buffer
 	if input.DataStream != nil {
 		logs = new(bytes.Buffer)
 		if _, err := io.Copy(logs, *input.DataStream); err != nil {
-			return fmt.Errorf("error in copy information from logs to buffer")
+			return nil, fmt.Errorf("error in copy information from logs to buffer")
 		}
 		if err := (*input.DataStream).Close(); err != nil {
-			return fmt.Errorf("error during closing input.DataStream. %+v", err)
+			return nil, fmt.Errorf("error during closing input.DataStream. %+v", err)
 		}
 	} else if len(input.Data) > 0 {
 		// Directly pump the data in the logs var
 		p.logger.Warn("received input.Data instead of input.DataStream, who wrote that runners without stream support")
 		logs = bytes.NewBuffer(input.Data)
 	} else {
-		return fmt.Errorf("no data stream nor data from Input channel")
+		return nil, fmt.Errorf("no data stream nor data from Input channel")
 	}
 
 	// Parse JSON response
 	results := models.ClientResults{}
 	if err := json.Unmarshal(logs.Bytes(), &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (p PingParsing) parse(input parsers.Input, dataCh chan<- outputs.Data) error {
+	results, err := p.readResults(input)
+	if err != nil {
 		return err
 	}
 
 	table := &outputs.Table{
-		Headers: []*outputs.Row{
-			{Value: "test_time"},
-			{Value: "round"},
-			{Value: "tester"},
-			{Value: "server_host"},
-			{Value: "client_host"},
-			{Value: "target"},
-			{Value: "destination"},
-			{Value: "packet_transmit"},
-			{Value: "packet_receive"},
-			{Value: "packet_loss_rate"},
-			{Value: "packet_loss_count"},
-			{Value: "rtt_min"},
-			{Value: "rtt_avg"},
-			{Value: "rtt_max"},
-			{Value: "rtt_mdev"},
-			{Value: "packet_duplicate_rate"},
-			{Value: "packet_duplicate_count"},
+		Headers: append(baseHeaders(), []*outputs.Row{
 			{Value: "timestamp"},
 			{Value: "icmp_seq"},
 			{Value: "ttl"},
 			{Value: "time"},
 			{Value: "duplicate"},
 			{Value: "additional_info"},
-		},
+		}...),
 		Rows: [][]*outputs.Row{},
 	}
 
 	for name, r := range results {
-		base := []*outputs.Row{
-			{Value: input.TestTime.Format(util.TimeDateFormat)},
-			{Value: input.Round},
-			{Value: input.Tester},
-			{Value: input.ServerHost},
-			{Value: input.ClientHost},
-			{Value: name},
-			{Value: r.Destination},
-			{Value: r.PacketTransmit},
-			{Value: r.PacketReceive},
-			{Value: r.PacketLossRate},
-			{Value: r.PacketLossCount},
-			{Value: r.RTTMin},
-			{Value: r.RTTAvg},
-			{Value: r.RTTMax},
-			{Value: r.RTTMDev},
-			{Value: r.PacketDuplicateRate},
-			{Value: r.PacketDuplicateCount},
-		}
 		for _, e := range r.ICMPReplies {
-			table.Rows = append(table.Rows, append(base, []*outputs.Row{
+			table.Rows = append(table.Rows, append(baseRows(input, name, r), []*outputs.Row{
 				{Value: e.Timestamp},
 				{Value: e.ICMPSeq},
 				{Value: e.TTL},
@@ -165,7 +142,9 @@ func (p PingParsing) parse(input parsers.Input, dataCh chan<- outputs.Data) erro
 		AdditionalInfo: input.AdditionalInfo,
 		ServerHost:     input.ServerHost,
 		ClientHost:     input.ClientHost,
+		IPFamily:       input.IPFamily,
 		Tester:         input.Tester,
+		Type:           outputs.DataTypeInterval,
 		Data:           table,
 	}
 
@@ -173,9 +152,88 @@ func (p PingParsing) parse(input parsers.Input, dataCh chan<- outputs.Data) erro
 
 	dataCh <- data
 
-	// TODO generate sum and / or end table and send to output
-
 	p.logger.Debug("sent parsed data to dataCh")
 
+	p.summary(input, results, dataCh)
+
 	return nil
 }
+
+// baseRows return the rows that each row of the PingParsing tables begins with, the test info and the
+// statistics of the ping target
+func baseRows(input parsers.Input, name string, r models.PingResult) []*outputs.Row {
+	return []*outputs.Row{
+		{Value: input.TestTime.Format(util.TimeDateFormat)},
+		{Value: input.Round},
+		{Value: input.Tester},
+		{Value: input.ServerHost},
+		{Value: input.ClientHost},
+		{Value: string(input.IPFamily)},
+		{Value: name},
+		{Value: r.Destination},
+		{Value: r.PacketTransmit},
+		{Value: r.PacketReceive},
+		{Value: r.PacketLossRate},
+		{Value: r.PacketLossCount},
+		{Value: r.RTTMin},
+		{Value: r.RTTAvg},
+		{Value: r.RTTMax},
+		{Value: r.RTTMDev},
+		{Value: r.PacketDuplicateRate},
+		{Value: r.PacketDuplicateCount},
+	}
+}
+
+// baseHeaders return the headers for the baseRows
+func baseHeaders() []*outputs.Row {
+	return []*outputs.Row{
+		{Value: "test_time"},
+		{Value: "round"},
+		{Value: "tester"},
+		{Value: "server_host"},
+		{Value: "client_host"},
+		{Value: "ip_family"},
+		{Value: "target"},
+		{Value: "destination"},
+		{Value: "packet_transmit"},
+		{Value: "packet_receive"},
+		{Value: "packet_loss_rate"},
+		{Value: "packet_loss_count"},
+		{Value: "rtt_min"},
+		{Value: "rtt_avg"},
+		{Value: "rtt_max"},
+		{Value: "rtt_mdev"},
+		{Value: "packet_duplicate_rate"},
+		{Value: "packet_duplicate_count"},
+	}
+}
+
+// summary generate the summary table with one row per ping target and send it to the dataCh
+func (p PingParsing) summary(input parsers.Input, results models.ClientResults, dataCh chan<- outputs.Data) {
+	table := &outputs.Table{
+		Headers: append(baseHeaders(), &outputs.Row{Value: "additional_info"}),
+		Rows:    [][]*outputs.Row{},
+	}
+
+	for name, r := range results {
+		table.Rows = append(table.Rows, append(baseRows(input, name, r), &outputs.Row{Value: input.AdditionalInfo}))
+	}
+
+	data := outputs.Data{
+		TestStartTime:  input.TestStartTime,
+		TestTime:       input.TestTime,
+		AdditionalInfo: input.AdditionalInfo,
+		ServerHost:     input.ServerHost,
+		ClientHost:     input.ClientHost,
+		IPFamily:       input.IPFamily,
+		Tester:         input.Tester,
+		Type:           outputs.DataTypeSummary,
+		Data:           table,
+	}
+
+	p.logger.Debug("sending summary data to dataCh")
+
+	dataCh <- data
+
+	p.logger.Debug("sent summary data to dataCh")
+}
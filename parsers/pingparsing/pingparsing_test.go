@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pingparsing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const pingResult = `{
+	"10.0.0.1": {
+		"destination": "10.0.0.1",
+		"packet_transmit": 3,
+		"packet_receive": 2,
+		"packet_loss_rate": 33.3,
+		"packet_loss_count": 1,
+		"rtt_min": 0.1,
+		"rtt_avg": 0.2,
+		"rtt_max": 0.3,
+		"rtt_mdev": 0.05,
+		"packet_duplicate_rate": 0,
+		"packet_duplicate_count": 0,
+		"icmp_replies": [
+			{"timestamp": "2020-01-01T00:00:00", "icmp_seq": 1, "ttl": 64, "time": 0.1, "duplicate": false},
+			{"timestamp": "2020-01-01T00:00:01", "icmp_seq": 2, "ttl": 64, "time": 0.3, "duplicate": false}
+		]
+	}
+}`
+
+func newTestInput(data string) parsers.Input {
+	return parsers.Input{
+		TestStartTime: time.Now(),
+		TestTime:      time.Now(),
+		Round:         1,
+		Tester:        NamePingParsing,
+		ServerHost:    "server1",
+		ClientHost:    "client1",
+		IPFamily:      config.IPFamilyIPv4,
+		Data:          []byte(data),
+	}
+}
+
+// getColumn return the values of a column by header name from the table
+func getColumn(t *testing.T, table *outputs.Table, name string) []interface{} {
+	index, err := table.GetHeaderIndexByName(name)
+	require.Nil(t, err)
+	require.NotEqual(t, -1, index, "column %s not found", name)
+
+	values := []interface{}{}
+	for _, row := range table.Rows {
+		values = append(values, row[index].Value)
+	}
+	return values
+}
+
+func TestParseSummary(t *testing.T) {
+	parser, err := NewPingParsingTester(zap.NewNop(), nil, nil)
+	require.Nil(t, err)
+	p := parser.(PingParsing)
+
+	dataCh := make(chan outputs.Data, 2)
+	require.Nil(t, p.parse(newTestInput(pingResult), dataCh))
+	close(dataCh)
+
+	interval := <-dataCh
+	assert.False(t, interval.IsSummary())
+	table, ok := interval.Data.(*outputs.Table)
+	require.True(t, ok)
+	// One row per ICMP reply
+	require.Equal(t, 2, len(table.Rows))
+
+	summary := <-dataCh
+	assert.Equal(t, outputs.DataTypeSummary, summary.Type)
+	assert.True(t, summary.IsSummary())
+
+	table, ok = summary.Data.(*outputs.Table)
+	require.True(t, ok)
+	// One row per ping target
+	require.Equal(t, 1, len(table.Rows))
+	for _, row := range table.Rows {
+		assert.Equal(t, len(table.Headers), len(row))
+	}
+
+	assert.Equal(t, []interface{}{"10.0.0.1"}, getColumn(t, table, "target"))
+	assert.Equal(t, []interface{}{int64(3)}, getColumn(t, table, "packet_transmit"))
+	assert.Equal(t, []interface{}{int64(1)}, getColumn(t, table, "packet_loss_count"))
+	assert.Equal(t, []interface{}{float64(0.2)}, getColumn(t, table, "rtt_avg"))
+	assert.Equal(t, []interface{}{"ipv4"}, getColumn(t, table, "ip_family"))
+	// The ICMP reply columns must not be in the summary table
+	for _, name := range []string{"icmp_seq", "ttl", "time"} {
+		index, err := table.GetHeaderIndexByName(name)
+		require.Nil(t, err)
+		assert.Equal(t, -1, index, "column %s found in summary table", name)
+	}
+}
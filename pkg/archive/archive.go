@@ -0,0 +1,244 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"go.uber.org/zap"
+)
+
+const (
+	metadataFileExtension = ".json"
+	rawFileExtension      = ".raw"
+)
+
+// Metadata metadata of an archived raw result, written as sidecar JSON file next to the raw result file
+type Metadata struct {
+	Test           string          `json:"test"`
+	Sequence       int             `json:"sequence"`
+	TestStartTime  time.Time       `json:"testStartTime"`
+	TestTime       time.Time       `json:"testTime"`
+	Round          int             `json:"round"`
+	Tester         string          `json:"tester"`
+	ServerHost     string          `json:"serverHost"`
+	ClientHost     string          `json:"clientHost"`
+	IPFamily       config.IPFamily `json:"ipFamily,omitempty"`
+	AdditionalInfo string          `json:"additionalInfo,omitempty"`
+	// RawFile name of the raw result file (in the same directory as the metadata file)
+	RawFile string `json:"rawFile"`
+
+	// dir directory the metadata has been loaded from
+	dir string
+}
+
+// Input return the parsers.Input for the archived raw result, the raw result file is opened as the DataStream
+func (m *Metadata) Input() (parsers.Input, error) {
+	file, err := os.Open(filepath.Join(m.dir, m.RawFile))
+	if err != nil {
+		return parsers.Input{}, fmt.Errorf("failed to open raw result file. %+v", err)
+	}
+
+	var stream io.ReadCloser = file
+	return parsers.Input{
+		TestStartTime:  m.TestStartTime,
+		TestTime:       m.TestTime,
+		Round:          m.Round,
+		DataStream:     &stream,
+		Tester:         m.Tester,
+		ServerHost:     m.ServerHost,
+		ClientHost:     m.ClientHost,
+		IPFamily:       m.IPFamily,
+		AdditionalInfo: m.AdditionalInfo,
+	}, nil
+}
+
+// Run return the name of the run directory (`TEST_START_TIME`) the raw result has been archived in
+func (m *Metadata) Run() string {
+	return filepath.Base(m.dir)
+}
+
+// Archiver archives the raw results (parsers.Input) of a test run
+type Archiver struct {
+	logger *zap.Logger
+	dir    string
+	test   string
+
+	lock     sync.Mutex
+	sequence int
+}
+
+// NewArchiver return a new Archiver which archives the raw results of the test run in the
+// `DIR/TEST_NAME/TEST_START_TIME/` directory
+func NewArchiver(logger *zap.Logger, dir string, test string, testStartTime time.Time) (*Archiver, error) {
+	dir = filepath.Join(dir, test, testStartTime.Format(util.TimeDateFormat))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create results archive directory %s. %+v", dir, err)
+	}
+
+	return &Archiver{
+		logger: logger.With(zap.String("archive", dir)),
+		dir:    dir,
+		test:   test,
+	}, nil
+}
+
+// Archive archive the input, the returned input must be used instead of the given one as the DataStream is
+// written to the raw result file while it is read by the parser
+func (a *Archiver) Archive(input parsers.Input) (parsers.Input, error) {
+	a.lock.Lock()
+	a.sequence++
+	sequence := a.sequence
+	a.lock.Unlock()
+
+	name := fmt.Sprintf("%05d-%d-%s-%s", sequence, input.Round, input.ServerHost, input.ClientHost)
+	if input.IPFamily != "" {
+		name += "-" + string(input.IPFamily)
+	}
+
+	metadata := &Metadata{
+		Test:           a.test,
+		Sequence:       sequence,
+		TestStartTime:  input.TestStartTime,
+		TestTime:       input.TestTime,
+		Round:          input.Round,
+		Tester:         input.Tester,
+		ServerHost:     input.ServerHost,
+		ClientHost:     input.ClientHost,
+		IPFamily:       input.IPFamily,
+		AdditionalInfo: input.AdditionalInfo,
+		RawFile:        name + rawFileExtension,
+	}
+	out, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return input, fmt.Errorf("failed to marshal raw result metadata. %+v", err)
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, name+metadataFileExtension), out, 0640); err != nil {
+		return input, fmt.Errorf("failed to write raw result metadata file. %+v", err)
+	}
+
+	rawFile := filepath.Join(a.dir, metadata.RawFile)
+	if input.DataStream == nil {
+		if err := os.WriteFile(rawFile, input.Data, 0640); err != nil {
+			return input, fmt.Errorf("failed to write raw result file. %+v", err)
+		}
+		return input, nil
+	}
+
+	file, err := os.Create(rawFile)
+	if err != nil {
+		return input, fmt.Errorf("failed to create raw result file. %+v", err)
+	}
+	var stream io.ReadCloser = &teeReadCloser{
+		Reader: io.TeeReader(*input.DataStream, file),
+		stream: *input.DataStream,
+		file:   file,
+	}
+	input.DataStream = &stream
+
+	a.logger.Debug("archiving raw result", zap.String("file", rawFile))
+
+	return input, nil
+}
+
+// teeReadCloser writes the data read from the stream to the file, closing it closes the stream and the file
+type teeReadCloser struct {
+	io.Reader
+	stream io.ReadCloser
+	file   *os.File
+}
+
+// Close close the stream and the file
+func (t *teeReadCloser) Close() error {
+	err := t.stream.Close()
+	if errFile := t.file.Close(); errFile != nil && err == nil {
+		err = errFile
+	}
+	return err
+}
+
+// Load load the metadata of all archived raw results in the directory (recursively), sorted by the
+// test start time and the sequence
+func Load(dir string) ([]*Metadata, error) {
+	metadatas := []*Metadata{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), metadataFileExtension) {
+			return nil
+		}
+
+		out, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		metadata := &Metadata{}
+		if err := json.Unmarshal(out, metadata); err != nil {
+			return fmt.Errorf("failed to unmarshal raw result metadata file %s. %+v", path, err)
+		}
+		metadata.dir = filepath.Dir(path)
+		metadatas = append(metadatas, metadata)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load results archive %s. %+v", dir, err)
+	}
+
+	sort.SliceStable(metadatas, func(i, j int) bool {
+		if !metadatas[i].TestStartTime.Equal(metadatas[j].TestStartTime) {
+			return metadatas[i].TestStartTime.Before(metadatas[j].TestStartTime)
+		}
+		return metadatas[i].Sequence < metadatas[j].Sequence
+	})
+
+	return metadatas, nil
+}
+
+// FilterRun return the metadata of the given run (`TEST_START_TIME` directory name) of each test, the latest run
+// of each test is used when the run is empty. The metadata must be sorted by the test start time (see Load()).
+func FilterRun(metadatas []*Metadata, run string) []*Metadata {
+	runs := map[string]string{}
+	for _, metadata := range metadatas {
+		if run != "" {
+			runs[metadata.Test] = run
+			continue
+		}
+		// The metadata is sorted by the test start time, so the last run of a test is the latest
+		runs[metadata.Test] = metadata.Run()
+	}
+
+	filtered := []*Metadata{}
+	for _, metadata := range metadatas {
+		if metadata.Run() == runs[metadata.Test] {
+			filtered = append(filtered, metadata)
+		}
+	}
+
+	return filtered
+}
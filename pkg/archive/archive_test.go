@@ -0,0 +1,139 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestArchiveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	testStartTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	archiver, err := NewArchiver(zap.NewNop(), dir, "test1", testStartTime)
+	require.Nil(t, err)
+
+	var stream io.ReadCloser = io.NopCloser(strings.NewReader("stream result"))
+	input, err := archiver.Archive(parsers.Input{
+		TestStartTime: testStartTime,
+		TestTime:      testStartTime.Add(time.Minute),
+		Round:         1,
+		DataStream:    &stream,
+		Tester:        "iperf3",
+		ServerHost:    "server1",
+		ClientHost:    "client1",
+		IPFamily:      config.IPFamilyIPv4,
+	})
+	require.Nil(t, err)
+	// The parser reads the stream, which is archived at the same time
+	out, err := io.ReadAll(*input.DataStream)
+	require.Nil(t, err)
+	assert.Equal(t, "stream result", string(out))
+	require.Nil(t, (*input.DataStream).Close())
+
+	_, err = archiver.Archive(parsers.Input{
+		TestStartTime: testStartTime,
+		TestTime:      testStartTime.Add(2 * time.Minute),
+		Data:          []byte("data result"),
+		Tester:        "iperf3",
+		ServerHost:    "server1",
+		ClientHost:    "client2",
+	})
+	require.Nil(t, err)
+
+	metadatas, err := Load(dir)
+	require.Nil(t, err)
+	require.Len(t, metadatas, 2)
+
+	assert.Equal(t, "test1", metadatas[0].Test)
+	assert.Equal(t, 1, metadatas[0].Sequence)
+	assert.Equal(t, "client1", metadatas[0].ClientHost)
+	assert.Equal(t, 2, metadatas[1].Sequence)
+	assert.Equal(t, "client2", metadatas[1].ClientHost)
+
+	for i, expected := range []string{"stream result", "data result"} {
+		input, err := metadatas[i].Input()
+		require.Nil(t, err)
+		assert.True(t, testStartTime.Equal(input.TestStartTime))
+		assert.Equal(t, metadatas[i].Round, input.Round)
+		assert.Equal(t, metadatas[i].IPFamily, input.IPFamily)
+
+		out, err := io.ReadAll(*input.DataStream)
+		require.Nil(t, err)
+		assert.Equal(t, expected, string(out))
+		require.Nil(t, (*input.DataStream).Close())
+	}
+}
+
+func TestLoadNotExisting(t *testing.T) {
+	_, err := Load("/this/does/not/exist")
+	assert.NotNil(t, err)
+}
+
+func TestFilterRun(t *testing.T) {
+	dir := t.TempDir()
+	firstRun := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	secondRun := firstRun.Add(time.Hour)
+
+	for _, run := range []struct {
+		test          string
+		testStartTime time.Time
+	}{
+		{test: "test1", testStartTime: secondRun},
+		{test: "test1", testStartTime: firstRun},
+		{test: "test2", testStartTime: firstRun},
+	} {
+		archiver, err := NewArchiver(zap.NewNop(), dir, run.test, run.testStartTime)
+		require.Nil(t, err)
+		_, err = archiver.Archive(parsers.Input{
+			TestStartTime: run.testStartTime,
+			TestTime:      run.testStartTime,
+			Data:          []byte("data result"),
+			Tester:        "iperf3",
+			ServerHost:    "server1",
+			ClientHost:    "client1",
+		})
+		require.Nil(t, err)
+	}
+
+	metadatas, err := Load(dir)
+	require.Nil(t, err)
+	require.Len(t, metadatas, 3)
+
+	// The latest run of each test by default
+	filtered := FilterRun(metadatas, "")
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "test2", filtered[0].Test)
+	assert.True(t, firstRun.Equal(filtered[0].TestStartTime))
+	assert.Equal(t, "test1", filtered[1].Test)
+	assert.True(t, secondRun.Equal(filtered[1].TestStartTime))
+
+	filtered = FilterRun(metadatas, firstRun.Format(util.TimeDateFormat))
+	require.Len(t, filtered, 2)
+	for _, metadata := range filtered {
+		assert.True(t, firstRun.Equal(metadata.TestStartTime))
+	}
+
+	assert.Empty(t, FilterRun(metadatas, "does-not-exist"))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assertions
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+)
+
+// Pair server and client host pair the assertions are evaluated for
+type Pair struct {
+	ServerHost string
+	ClientHost string
+}
+
+// Evaluator evaluates the assertions of a test on the parsed data per server and client host pair
+type Evaluator struct {
+	assertions []*config.Assertion
+
+	lock sync.Mutex
+	// values aggregated values per assertion (same index as the assertions) and pair
+	values []map[Pair]*outputs.Aggregate
+}
+
+// Result result of an assertion for a server and client host pair
+type Result struct {
+	Assertion *config.Assertion
+	Pair
+	// Value aggregated value of the column
+	Value float64
+	// NoData no data has been found for the assertion at all (the assertion is failed)
+	NoData bool
+	Passed bool
+}
+
+// String return a human readable description of the result
+func (r Result) String() string {
+	a := r.Assertion
+	if r.NoData {
+		return fmt.Sprintf("%s %s %s %g: no data found for column (data type: %s)", a.Column, a.Aggregation, a.Operator, a.Value, a.DataType)
+	}
+	return fmt.Sprintf("%s -> %s: %s %s = %g, expected %s %g", r.ServerHost, r.ClientHost, a.Column, a.Aggregation, r.Value, a.Operator, a.Value)
+}
+
+// New return a new Evaluator for the assertions
+func New(assertions []*config.Assertion) *Evaluator {
+	e := &Evaluator{
+		assertions: assertions,
+		values:     make([]map[Pair]*outputs.Aggregate, len(assertions)),
+	}
+	for i := range e.values {
+		e.values[i] = map[Pair]*outputs.Aggregate{}
+	}
+	return e
+}
+
+// Add aggregate the values of the asserted columns of the data
+func (e *Evaluator) Add(data outputs.Data) error {
+	dataTable, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for assertions")
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	pair := Pair{
+		ServerHost: data.ServerHost,
+		ClientHost: data.ClientHost,
+	}
+	for i, assertion := range e.assertions {
+		if data.IsSummary() != (assertion.DataType == string(outputs.DataTypeSummary)) {
+			continue
+		}
+
+		values, err := dataTable.ColumnValues([]string{assertion.Column}, assertion.Filters)
+		if err != nil {
+			return err
+		}
+		if len(values[assertion.Column]) == 0 {
+			continue
+		}
+
+		agg, ok := e.values[i][pair]
+		if !ok {
+			agg = &outputs.Aggregate{}
+			e.values[i][pair] = agg
+		}
+		for _, val := range values[assertion.Column] {
+			agg.Add(val)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate evaluate the assertions per server and client host pair (sorted by the server and client host name).
+// An assertion without any data is failed, as a misconfigured assertion would otherwise always pass.
+func (e *Evaluator) Evaluate() []Result {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	results := []Result{}
+	for i, assertion := range e.assertions {
+		if len(e.values[i]) == 0 {
+			results = append(results, Result{
+				Assertion: assertion,
+				NoData:    true,
+			})
+			continue
+		}
+
+		pairs := make([]Pair, 0, len(e.values[i]))
+		for pair := range e.values[i] {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(a, b int) bool {
+			if pairs[a].ServerHost != pairs[b].ServerHost {
+				return pairs[a].ServerHost < pairs[b].ServerHost
+			}
+			return pairs[a].ClientHost < pairs[b].ClientHost
+		})
+
+		for _, pair := range pairs {
+			value := e.values[i][pair].Value(assertion.Aggregation)
+			results = append(results, Result{
+				Assertion: assertion,
+				Pair:      pair,
+				Value:     value,
+				Passed:    compare(value, assertion.Operator, assertion.Value),
+			})
+		}
+	}
+
+	return results
+}
+
+func compare(value float64, operator string, expected float64) bool {
+	switch operator {
+	case "<":
+		return value < expected
+	case "<=":
+		return value <= expected
+	case ">":
+		return value > expected
+	case ">=":
+		return value >= expected
+	case "==":
+		return value == expected
+	case "!=":
+		return value != expected
+	}
+	return false
+}
+
+// Failed return the failed results
+func Failed(results []Result) []Result {
+	failed := []Result{}
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assertions
+
+import (
+	"testing"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestData(server string, client string, dataType outputs.DataType, rows ...[]interface{}) outputs.Data {
+	table := &outputs.Table{
+		Headers: []*outputs.Row{
+			{Value: "kind"},
+			{Value: "bits_per_second"},
+			{Value: "packet_loss_rate"},
+		},
+		Rows: [][]*outputs.Row{},
+	}
+	for _, values := range rows {
+		row := []*outputs.Row{}
+		for _, val := range values {
+			row = append(row, &outputs.Row{Value: val})
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return outputs.Data{
+		ServerHost: server,
+		ClientHost: client,
+		Type:       dataType,
+		Data:       table,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	evaluator := New([]*config.Assertion{
+		{
+			Column:      "bits_per_second",
+			Aggregation: config.AggregationMean,
+			Operator:    ">=",
+			Value:       9e9,
+			DataFilter: config.DataFilter{
+				DataType:  string(outputs.DataTypeInterval),
+				RowFilter: config.RowFilter{Filters: map[string]string{"kind": "sum"}},
+			},
+		},
+		{
+			Column:      "packet_loss_rate",
+			Aggregation: config.AggregationMax,
+			Operator:    "<=",
+			Value:       0.1,
+			DataFilter:  config.DataFilter{DataType: string(outputs.DataTypeInterval)},
+		},
+		{
+			Column:      "doesnotexist",
+			Aggregation: config.AggregationMin,
+			Operator:    ">",
+			Value:       0,
+			DataFilter:  config.DataFilter{DataType: string(outputs.DataTypeInterval)},
+		},
+	})
+
+	require.Nil(t, evaluator.Add(newTestData("server1", "client2", outputs.DataTypeInterval,
+		[]interface{}{"sum", 9.5e9, 0.0},
+		[]interface{}{"sum", 8.9e9, 0.2},
+		// Filtered out for the bits_per_second assertion
+		[]interface{}{"stream", 1.0, 0.0},
+	)))
+	require.Nil(t, evaluator.Add(newTestData("server1", "client1", outputs.DataTypeInterval,
+		[]interface{}{"sum", int64(8e9), 0.05},
+	)))
+	// Summary data is ignored by the interval assertions
+	require.Nil(t, evaluator.Add(newTestData("server1", "client1", outputs.DataTypeSummary,
+		[]interface{}{"sum", 10e9, 0.0},
+	)))
+
+	results := evaluator.Evaluate()
+	require.Len(t, results, 5)
+
+	assert.Equal(t, Pair{ServerHost: "server1", ClientHost: "client1"}, results[0].Pair)
+	assert.Equal(t, 8e9, results[0].Value)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, Pair{ServerHost: "server1", ClientHost: "client2"}, results[1].Pair)
+	assert.Equal(t, 9.2e9, results[1].Value)
+	assert.True(t, results[1].Passed)
+
+	assert.Equal(t, 0.05, results[2].Value)
+	assert.True(t, results[2].Passed)
+	assert.Equal(t, 0.2, results[3].Value)
+	assert.False(t, results[3].Passed)
+
+	// No data for the column at all
+	assert.True(t, results[4].NoData)
+	assert.False(t, results[4].Passed)
+
+	failed := Failed(results)
+	require.Len(t, failed, 3)
+	assert.Equal(t, "server1 -> client1: bits_per_second mean = 8e+09, expected >= 9e+09", failed[0].String())
+	assert.Contains(t, failed[2].String(), "no data found for column")
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		value    float64
+		operator string
+		expected float64
+		result   bool
+	}{
+		{1, "<", 2, true},
+		{2, "<", 2, false},
+		{2, "<=", 2, true},
+		{3, ">", 2, true},
+		{2, ">=", 2, true},
+		{1, ">=", 2, false},
+		{2, "==", 2, true},
+		{2, "!=", 2, false},
+		{2, "unknown", 2, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.result, compare(test.value, test.operator, test.expected), "%g %s %g", test.value, test.operator, test.expected)
+	}
+}
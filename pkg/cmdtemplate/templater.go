@@ -15,8 +15,10 @@ package cmdtemplate
 
 import (
 	"bytes"
+	"fmt"
 	"text/template"
 
+	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/testers"
 )
 
@@ -29,6 +31,18 @@ type Variables struct {
 
 // Template template a given cmd and args with the given host information struct
 func Template(task *testers.Task, variables Variables) error {
+	// Fail early when there is no server address for the IP family of the task
+	switch task.IPFamily {
+	case config.IPFamilyIPv4:
+		if variables.ServerAddressV4 == "" {
+			return fmt.Errorf("no IPv4 server address available for task")
+		}
+	case config.IPFamilyIPv6:
+		if variables.ServerAddressV6 == "" {
+			return fmt.Errorf("no IPv6 server address available for task")
+		}
+	}
+
 	templatedArgs := []string{}
 
 	var err error
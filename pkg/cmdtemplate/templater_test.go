@@ -16,6 +16,7 @@ package cmdtemplate
 import (
 	"testing"
 
+	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/testers"
 	"github.com/stretchr/testify/assert"
 )
@@ -26,3 +27,28 @@ func TestTemplate(t *testing.T) {
 	err := Template(task, variables)
 	assert.Nil(t, err)
 }
+
+func TestTemplateIPFamily(t *testing.T) {
+	variables := Variables{
+		ServerAddressV4: "192.0.2.1",
+		ServerPort:      5601,
+	}
+
+	task := &testers.Task{
+		Args:     []string{"--client={{ .ServerAddressV4 }}"},
+		IPFamily: config.IPFamilyIPv4,
+	}
+	assert.Nil(t, Template(task, variables))
+	assert.Equal(t, []string{"--client=192.0.2.1"}, task.Args)
+
+	// No IPv6 address available for an IPv6 task
+	task = &testers.Task{
+		Args:     []string{"--client={{ .ServerAddressV6 }}"},
+		IPFamily: config.IPFamilyIPv6,
+	}
+	assert.NotNil(t, Template(task, variables))
+
+	variables.ServerAddressV6 = "2001:db8::1"
+	assert.Nil(t, Template(task, variables))
+	assert.Equal(t, []string{"--client=2001:db8::1"}, task.Args)
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/pkg/util"
+)
+
+// Columns of the result tables used to match the rows of two result sets
+const (
+	ColumnTester     = "tester"
+	ColumnServerHost = "server_host"
+	ColumnClientHost = "client_host"
+	ColumnRound      = "round"
+	ColumnIPFamily   = "ip_family"
+)
+
+// Options options for loading and comparing result sets
+type Options struct {
+	// Metrics columns to compare
+	Metrics []string
+	// LowerIsBetter metrics for which a lower value is better (e.g., RTT), for the others a higher value is better
+	LowerIsBetter []string
+	// Threshold regression threshold in percent, e.g., `10` for a 10% throughput drop
+	Threshold float64
+	// DataType type of the data to compare (summary or interval)
+	DataType outputs.DataType
+	// Filters only rows with the column values are compared, e.g., `kind: sum` for the IPerf3 sum rows
+	Filters map[string]string
+}
+
+// Key the values a row of a result set is matched by
+type Key struct {
+	Tester     string
+	ServerHost string
+	ClientHost string
+	Round      int
+	IPFamily   string
+}
+
+// Results result set with the aggregated (mean) values of each metric per Key
+type Results map[Key]map[string]*outputs.Aggregate
+
+// AddData add the rows of the data to the results, data of another type than the options DataType is ignored
+func (r Results) AddData(data outputs.Data, opts *Options) error {
+	if data.IsSummary() != (opts.DataType == outputs.DataTypeSummary) {
+		return nil
+	}
+	table, ok := data.Data.(*outputs.Table)
+	if !ok {
+		return fmt.Errorf("data not in data table format for compare")
+	}
+	return r.AddTable(table, opts)
+}
+
+// AddTable add the rows of the table to the results
+func (r Results) AddTable(table *outputs.Table, opts *Options) error {
+	indexes := map[string]int{}
+	for _, column := range []string{ColumnTester, ColumnServerHost, ColumnClientHost, ColumnRound, ColumnIPFamily} {
+		index, err := table.GetHeaderIndexByName(column)
+		if err != nil {
+			return err
+		}
+		if index == -1 && column != ColumnIPFamily {
+			return fmt.Errorf("column %s not found in result table", column)
+		}
+		indexes[column] = index
+	}
+
+	metrics := map[string]int{}
+	for _, metric := range opts.Metrics {
+		index, err := table.GetHeaderIndexByName(metric)
+		if err != nil {
+			return err
+		}
+		if index != -1 {
+			metrics[metric] = index
+		}
+	}
+
+	rows, err := table.FilterRows(opts.Filters)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		round, err := util.CastNumberToFloat64(getValue(row, indexes[ColumnRound]))
+		if err != nil {
+			return fmt.Errorf("failed to get round from result row. %+v", err)
+		}
+		key := Key{
+			Tester:     util.CastToString(getValue(row, indexes[ColumnTester])),
+			ServerHost: util.CastToString(getValue(row, indexes[ColumnServerHost])),
+			ClientHost: util.CastToString(getValue(row, indexes[ColumnClientHost])),
+			Round:      int(round),
+		}
+		if indexes[ColumnIPFamily] != -1 {
+			key.IPFamily = util.CastToString(getValue(row, indexes[ColumnIPFamily]))
+		}
+
+		for metric, index := range metrics {
+			val, ok, err := outputs.FloatValue(row, index)
+			if err != nil {
+				return fmt.Errorf("failed to get metric %s from result row. %+v", metric, err)
+			}
+			if !ok {
+				continue
+			}
+			if _, ok := r[key]; !ok {
+				r[key] = map[string]*outputs.Aggregate{}
+			}
+			v, ok := r[key][metric]
+			if !ok {
+				v = &outputs.Aggregate{}
+				r[key][metric] = v
+			}
+			v.Add(val)
+		}
+	}
+
+	return nil
+}
+
+func getValue(row []*outputs.Row, index int) interface{} {
+	if index < 0 || len(row) <= index || row[index] == nil {
+		return nil
+	}
+	return row[index].Value
+}
+
+// Delta comparison of a metric of a Key between the baseline and the current result set
+type Delta struct {
+	Key
+	Metric   string
+	Baseline float64
+	Current  float64
+	// Delta current minus baseline value
+	Delta float64
+	// DeltaPercent delta in percent of the baseline value, NaN when the baseline value is `0`
+	DeltaPercent float64
+	// Regression if the regression threshold has been exceeded or the metric is missing in the current result set
+	Regression bool
+	// Missing set to `baseline` or `current` when the Key metric is only in one of the result sets
+	Missing string
+}
+
+// Compare compare the metrics of the matching keys of the baseline and current result set, sorted by Key and metric
+func Compare(baseline Results, current Results, opts *Options) []Delta {
+	keys := map[Key]struct{}{}
+	for key := range baseline {
+		keys[key] = struct{}{}
+	}
+	for key := range current {
+		keys[key] = struct{}{}
+	}
+
+	deltas := []Delta{}
+	for key := range keys {
+		for _, metric := range opts.Metrics {
+			base, okBase := baseline[key][metric]
+			cur, okCur := current[key][metric]
+			if !okBase && !okCur {
+				continue
+			}
+
+			delta := Delta{
+				Key:          key,
+				Metric:       metric,
+				DeltaPercent: math.NaN(),
+			}
+			switch {
+			case !okBase:
+				delta.Missing = "baseline"
+				delta.Current = cur.Mean()
+			case !okCur:
+				// A metric which is missing in the current result set, e.g., of a failed client, is a regression
+				delta.Missing = "current"
+				delta.Baseline = base.Mean()
+				delta.Regression = true
+			default:
+				delta.Baseline = base.Mean()
+				delta.Current = cur.Mean()
+				delta.Delta = delta.Current - delta.Baseline
+				if delta.Baseline != 0 {
+					delta.DeltaPercent = delta.Delta / math.Abs(delta.Baseline) * 100
+				} else if delta.Delta == 0 {
+					delta.DeltaPercent = 0
+				}
+				delta.Regression = isRegression(delta.DeltaPercent, slices.Contains(opts.LowerIsBetter, metric), opts.Threshold)
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+
+	sort.SliceStable(deltas, func(i, j int) bool {
+		a, b := deltas[i], deltas[j]
+		if a.Key != b.Key {
+			return lessKey(a.Key, b.Key)
+		}
+		return slices.Index(opts.Metrics, a.Metric) < slices.Index(opts.Metrics, b.Metric)
+	})
+
+	return deltas
+}
+
+func isRegression(deltaPercent float64, lowerIsBetter bool, threshold float64) bool {
+	if math.IsNaN(deltaPercent) {
+		return false
+	}
+	if lowerIsBetter {
+		return deltaPercent > threshold
+	}
+	return deltaPercent < -threshold
+}
+
+func lessKey(a Key, b Key) bool {
+	if a.Tester != b.Tester {
+		return a.Tester < b.Tester
+	}
+	if a.ServerHost != b.ServerHost {
+		return a.ServerHost < b.ServerHost
+	}
+	if a.ClientHost != b.ClientHost {
+		return a.ClientHost < b.ClientHost
+	}
+	if a.Round != b.Round {
+		return a.Round < b.Round
+	}
+	return a.IPFamily < b.IPFamily
+}
+
+// Regressions return the count of deltas which are a regression
+func Regressions(deltas []Delta) int {
+	count := 0
+	for _, delta := range deltas {
+		if delta.Regression {
+			count++
+		}
+	}
+	return count
+}
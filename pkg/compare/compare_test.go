@@ -0,0 +1,153 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTable(rows ...[]interface{}) *outputs.Table {
+	table := &outputs.Table{
+		Headers: []*outputs.Row{
+			{Value: ColumnRound},
+			{Value: ColumnTester},
+			{Value: ColumnServerHost},
+			{Value: ColumnClientHost},
+			{Value: "kind"},
+			{Value: "bits_per_second"},
+			{Value: "rtt"},
+		},
+		Rows: [][]*outputs.Row{},
+	}
+	for _, values := range rows {
+		row := []*outputs.Row{}
+		for _, val := range values {
+			// nil values are empty cells
+			if val == nil {
+				row = append(row, nil)
+				continue
+			}
+			row = append(row, &outputs.Row{Value: val})
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+func TestCompare(t *testing.T) {
+	opts := &Options{
+		Metrics:       []string{"bits_per_second", "rtt"},
+		LowerIsBetter: []string{"rtt"},
+		Threshold:     10,
+		DataType:      outputs.DataTypeSummary,
+		Filters:       map[string]string{"kind": "sum"},
+	}
+
+	baseline := Results{}
+	require.Nil(t, baseline.AddTable(newTestTable(
+		[]interface{}{1, "iperf3", "server1", "client1", "sum", 1000.0, 10.0},
+		// Filtered out by the kind filter
+		[]interface{}{1, "iperf3", "server1", "client1", "stream", 1.0, 1.0},
+		[]interface{}{1, "iperf3", "server1", "client2", "sum", 1000.0, 10.0},
+		[]interface{}{1, "iperf3", "server1", "client3", "sum", 1000.0, 0.0},
+	), opts))
+
+	current := Results{}
+	require.Nil(t, current.AddTable(newTestTable(
+		// Throughput drop of 20% and RTT increase of 5%
+		[]interface{}{int64(1), "iperf3", "server1", "client1", "sum", 800.0, 10.5},
+		// Both values are averaged: 1050 and 9
+		[]interface{}{int64(1), "iperf3", "server1", "client2", "sum", 1000.0, 8.0},
+		[]interface{}{int64(1), "iperf3", "server1", "client2", "sum", 1100.0, 10.0},
+		[]interface{}{int64(1), "iperf3", "server1", "client4", "sum", 1000.0, 10.0},
+	), opts))
+
+	deltas := Compare(baseline, current, opts)
+	require.Len(t, deltas, 8)
+
+	assert.Equal(t, "client1", deltas[0].ClientHost)
+	assert.Equal(t, "bits_per_second", deltas[0].Metric)
+	assert.InDelta(t, -200.0, deltas[0].Delta, 0.001)
+	assert.InDelta(t, -20.0, deltas[0].DeltaPercent, 0.001)
+	assert.True(t, deltas[0].Regression)
+	assert.Equal(t, "rtt", deltas[1].Metric)
+	assert.InDelta(t, 5.0, deltas[1].DeltaPercent, 0.001)
+	assert.False(t, deltas[1].Regression)
+
+	assert.Equal(t, "client2", deltas[2].ClientHost)
+	assert.InDelta(t, 1050.0, deltas[2].Current, 0.001)
+	assert.False(t, deltas[2].Regression)
+	assert.InDelta(t, 9.0, deltas[3].Current, 0.001)
+	assert.False(t, deltas[3].Regression)
+
+	// Only in the baseline (e.g., a failed client) is a regression, the RTT baseline value is `0`, so there is no
+	// percentage
+	assert.Equal(t, "client3", deltas[4].ClientHost)
+	assert.Equal(t, "current", deltas[4].Missing)
+	assert.True(t, deltas[4].Regression)
+	assert.True(t, deltas[5].Regression)
+	assert.True(t, math.IsNaN(deltas[5].DeltaPercent))
+
+	// Only in the current results is no regression
+	assert.Equal(t, "client4", deltas[6].ClientHost)
+	assert.Equal(t, "baseline", deltas[6].Missing)
+	assert.False(t, deltas[6].Regression)
+
+	assert.Equal(t, 3, Regressions(deltas))
+
+	for _, format := range []Format{FormatTable, FormatMarkdown} {
+		out := &bytes.Buffer{}
+		require.Nil(t, WriteReport(out, format, deltas, opts.Threshold))
+		assert.Contains(t, out.String(), statusRegression)
+		assert.Contains(t, out.String(), "-20.00%")
+		assert.Contains(t, out.String(), statusRegression+" (missing in current)")
+		assert.Contains(t, out.String(), "missing in baseline")
+		assert.Contains(t, out.String(), "3 of 8 compared metrics are missing in the current results or exceed the regression threshold of 10.00%.")
+	}
+	assert.NotNil(t, WriteReport(&bytes.Buffer{}, "html", deltas, opts.Threshold))
+}
+
+func TestAddTableMissingColumn(t *testing.T) {
+	table := &outputs.Table{
+		Headers: []*outputs.Row{{Value: ColumnTester}},
+	}
+	assert.NotNil(t, Results{}.AddTable(table, &Options{}))
+}
+
+func TestAddTableEmptyCells(t *testing.T) {
+	opts := &Options{
+		Metrics: []string{"bits_per_second", "rtt"},
+	}
+
+	results := Results{}
+	require.Nil(t, results.AddTable(newTestTable(
+		[]interface{}{1, "iperf3", "server1", "client1", "stream", 1000.0, 10.0},
+		// The sum row has no rtt and the NaN value is skipped
+		[]interface{}{1, "iperf3", "server1", "client1", "sum", 2000.0, nil},
+		[]interface{}{1, "iperf3", "server1", "client1", "sum", math.NaN(), 20.0},
+	), opts))
+
+	key := Key{Tester: "iperf3", ServerHost: "server1", ClientHost: "client1", Round: 1}
+	require.Contains(t, results, key)
+	assert.Equal(t, 2, results[key]["bits_per_second"].Count())
+	assert.Equal(t, float64(1500), results[key]["bits_per_second"].Mean())
+	assert.Equal(t, 2, results[key]["rtt"].Count())
+	assert.Equal(t, float64(15), results[key]["rtt"].Mean())
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/galexrt/ancientt/outputs"
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/archive"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	// Include sqlite driver for loading results from the sqlite output
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Load load a result set, a directory is loaded as raw results archive and a file as SQLite output database.
+// The run selects the run of each test of a raw results archive, see LoadArchive().
+func Load(logger *zap.Logger, path string, run string, opts *Options) (Results, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LoadArchive(logger, path, run, opts)
+	}
+	return LoadSQLite(path, opts)
+}
+
+// LoadSQLite load a result set from the tables of a SQLite output database
+func LoadSQLite(file string, opts *Options) (Results, error) {
+	db, err := sqlx.Connect("sqlite3", "file:"+file+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s. %+v", file, err)
+	}
+	defer db.Close()
+
+	tableNames := []string{}
+	if err := db.Select(&tableNames, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name"); err != nil {
+		return nil, fmt.Errorf("failed to list tables of sqlite database %s. %+v", file, err)
+	}
+
+	results := Results{}
+	for _, tableName := range tableNames {
+		// Summary data is written to its own table by the sqlite output
+		isSummary := strings.HasSuffix(tableName, outputs.GetSummaryTableName(""))
+		if isSummary != (opts.DataType == outputs.DataTypeSummary) {
+			continue
+		}
+
+		table, err := loadSQLiteTable(db, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if err := results.AddTable(table, opts); err != nil {
+			return nil, fmt.Errorf("failed to add table %s of sqlite database %s. %+v", tableName, file, err)
+		}
+	}
+
+	return results, nil
+}
+
+func loadSQLiteTable(db *sqlx.DB, tableName string) (*outputs.Table, error) {
+	rows, err := db.Queryx(fmt.Sprintf("SELECT * FROM `%s`", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table %s. %+v", tableName, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &outputs.Table{
+		Headers: []*outputs.Row{},
+		Rows:    [][]*outputs.Row{},
+	}
+	for _, column := range columns {
+		table.Headers = append(table.Headers, &outputs.Row{Value: column})
+	}
+
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row of table %s. %+v", tableName, err)
+		}
+		row := make([]*outputs.Row, len(values))
+		for i, val := range values {
+			if b, ok := val.([]byte); ok {
+				val = string(b)
+			}
+			row[i] = &outputs.Row{Value: val}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, rows.Err()
+}
+
+// LoadArchive load a result set by parsing the raw results of the given run (`TEST_START_TIME` directory name) of
+// each test of a results archive again, the latest run of each test is used when the run is empty
+func LoadArchive(logger *zap.Logger, dir string, run string, opts *Options) (Results, error) {
+	metadatas, err := archive.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Only a single run of each test is loaded, as the results of different runs can't be mixed
+	metadatas = archive.FilterRun(metadatas, run)
+	if len(metadatas) == 0 {
+		return nil, fmt.Errorf("no raw results found in results archive %s (run: %q)", dir, run)
+	}
+
+	results := Results{}
+	status := testers.NewStatus()
+	for _, metadata := range metadatas {
+		parserNewFunc, ok := parsers.Factories[strings.ToLower(metadata.Tester)]
+		if !ok {
+			return nil, fmt.Errorf("parser with name %s not found", metadata.Tester)
+		}
+		parser, err := parserNewFunc(logger, config.New(), &config.Test{
+			Name: metadata.Test,
+			Type: metadata.Tester,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		input, err := metadata.Input()
+		if err != nil {
+			return nil, err
+		}
+
+		// Failed results are reported to the status by the parser and are not part of the result set
+		input.Status = status
+		inCh := make(chan parsers.Input, 1)
+		inCh <- input
+		close(inCh)
+
+		doneCh := make(chan struct{})
+		dataCh := make(chan outputs.Data)
+		go func() {
+			defer close(dataCh)
+			if err := parser.Parse(doneCh, inCh, dataCh); err != nil {
+				logger.Warn("failed to parse archived raw result", zap.String("server", input.ServerHost), zap.String("client", input.ClientHost), zap.Error(err))
+			}
+		}()
+
+		// Drain the dataCh even after an error, so the parser isn't blocked
+		var addErr error
+		for data := range dataCh {
+			if addErr == nil {
+				addErr = results.AddData(data, opts)
+			}
+		}
+		close(doneCh)
+		if addErr != nil {
+			return nil, addErr
+		}
+	}
+
+	for client, count := range status.FailedHosts.Clients {
+		logger.Warn("skipped failed archived raw results", zap.String("client", client), zap.Int("count", count))
+	}
+
+	return results, nil
+}
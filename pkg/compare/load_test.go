@@ -0,0 +1,161 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/outputs"
+	sqliteoutput "github.com/galexrt/ancientt/outputs/sqlite"
+	"github.com/galexrt/ancientt/parsers"
+	iperf3parser "github.com/galexrt/ancientt/parsers/iperf3"
+	"github.com/galexrt/ancientt/pkg/archive"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const iperf3ResultPattern = `{
+	"start": {"test_start": {"protocol": "TCP", "num_streams": 1}},
+	"intervals": [
+		{
+			"streams": [{"socket": 5, "start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": %[1]f}],
+			"sum": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": %[1]f}
+		}
+	],
+	"end": {
+		"sum_sent": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": %[1]f},
+		"sum_received": {"start": 0, "end": 1, "seconds": 1, "bytes": 100, "bits_per_second": %[1]f}
+	}
+}`
+
+func newTestInput(bitsPerSecond float64) parsers.Input {
+	now := time.Now()
+	return parsers.Input{
+		TestStartTime: now,
+		TestTime:      now,
+		Round:         0,
+		Tester:        iperf3parser.NameIPerf3,
+		ServerHost:    "server1",
+		ClientHost:    "client1",
+		IPFamily:      config.IPFamilyIPv4,
+		Data:          []byte(fmt.Sprintf(iperf3ResultPattern, bitsPerSecond)),
+	}
+}
+
+func newTestOptions() *Options {
+	return &Options{
+		Metrics:   []string{"received_bits_per_second"},
+		Threshold: 10,
+		DataType:  outputs.DataTypeSummary,
+		Filters:   map[string]string{config.ColumnKind: config.KindSum},
+	}
+}
+
+func TestLoadSQLite(t *testing.T) {
+	dir := t.TempDir()
+
+	parser, err := iperf3parser.NewIPerf3Tester(zap.NewNop(), config.New(), &config.Test{})
+	require.Nil(t, err)
+	outCfg := &config.Output{
+		SQLite: &config.SQLite{
+			FilePath: config.FilePath{
+				FilePath:    dir,
+				NamePattern: "results.sqlite3",
+			},
+		},
+	}
+	require.Nil(t, defaults.Set(outCfg))
+	output, err := sqliteoutput.NewSQLiteOutput(zap.NewNop(), config.New(), outCfg)
+	require.Nil(t, err)
+
+	inCh := make(chan parsers.Input, 1)
+	inCh <- newTestInput(1000)
+	close(inCh)
+	dataCh := make(chan outputs.Data, 2)
+	require.Nil(t, parser.Parse(make(chan struct{}), inCh, dataCh))
+	close(dataCh)
+	for data := range dataCh {
+		require.Nil(t, output.Do(data))
+	}
+	require.Nil(t, output.Close())
+
+	results, err := Load(zap.NewNop(), filepath.Join(dir, "results.sqlite3"), "", newTestOptions())
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	for key, metrics := range results {
+		assert.Equal(t, Key{Tester: "iperf3", ServerHost: "server1", ClientHost: "client1", Round: 0, IPFamily: "ipv4"}, key)
+		assert.InDelta(t, 1000.0, metrics["received_bits_per_second"].Mean(), 0.001)
+	}
+}
+
+func TestLoadArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	input := newTestInput(2000)
+	archiver, err := archive.NewArchiver(zap.NewNop(), dir, "test1", input.TestStartTime)
+	require.Nil(t, err)
+	_, err = archiver.Archive(input)
+	require.Nil(t, err)
+
+	results, err := Load(zap.NewNop(), dir, "", newTestOptions())
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	for _, metrics := range results {
+		assert.InDelta(t, 2000.0, metrics["received_bits_per_second"].Mean(), 0.001)
+	}
+
+	_, err = Load(zap.NewNop(), filepath.Join(dir, "doesnotexist"), "", newTestOptions())
+	assert.NotNil(t, err)
+}
+
+func TestLoadArchiveRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two runs of the same test, the results of the runs must not be mixed
+	runs := []string{}
+	for i, bitsPerSecond := range []float64{1000, 2000} {
+		input := newTestInput(bitsPerSecond)
+		input.TestStartTime = time.Unix(int64(1000+i*60), 0)
+		archiver, err := archive.NewArchiver(zap.NewNop(), dir, "test1", input.TestStartTime)
+		require.Nil(t, err)
+		_, err = archiver.Archive(input)
+		require.Nil(t, err)
+		runs = append(runs, input.TestStartTime.Format(util.TimeDateFormat))
+	}
+
+	for run, expected := range map[string]float64{
+		// The latest run is used by default
+		"":      2000,
+		runs[0]: 1000,
+		runs[1]: 2000,
+	} {
+		results, err := Load(zap.NewNop(), dir, run, newTestOptions())
+		require.Nil(t, err)
+		require.Len(t, results, 1)
+		for _, metrics := range results {
+			assert.Equal(t, 1, metrics["received_bits_per_second"].Count(), run)
+			assert.InDelta(t, expected, metrics["received_bits_per_second"].Mean(), 0.001, run)
+		}
+	}
+
+	_, err := Load(zap.NewNop(), dir, "doesnotexist", newTestOptions())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format format of the compare report
+type Format string
+
+const (
+	// FormatTable plain text table
+	FormatTable Format = "table"
+	// FormatMarkdown Markdown table
+	FormatMarkdown Format = "markdown"
+)
+
+const (
+	statusOK         = "ok"
+	statusRegression = "REGRESSION"
+)
+
+var reportHeaders = []string{"Tester", "Server", "Client", "Round", "IP Family", "Metric", "Baseline", "Current", "Delta", "Delta %", "Status"}
+
+// WriteReport write the deltas as report in the format to the writer
+func WriteReport(w io.Writer, format Format, deltas []Delta, threshold float64) error {
+	rows := make([][]string, 0, len(deltas))
+	for _, delta := range deltas {
+		rows = append(rows, reportRow(delta))
+	}
+	summary := fmt.Sprintf("%d of %d compared metrics are missing in the current results or exceed the regression threshold of %s%%.",
+		Regressions(deltas), len(deltas), formatFloat(threshold))
+
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(reportHeaders, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "\n%s\n", summary)
+		return err
+	case FormatMarkdown:
+		fmt.Fprintf(w, "| %s |\n", strings.Join(reportHeaders, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(reportHeaders)))
+		for _, row := range rows {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+		_, err := fmt.Fprintf(w, "\n%s\n", summary)
+		return err
+	}
+
+	return fmt.Errorf("unknown compare report format %s given", format)
+}
+
+func reportRow(delta Delta) []string {
+	row := []string{
+		delta.Tester,
+		delta.ServerHost,
+		delta.ClientHost,
+		strconv.Itoa(delta.Round),
+		delta.IPFamily,
+		delta.Metric,
+	}
+
+	if delta.Missing != "" {
+		baseline, current := formatFloat(delta.Baseline), formatFloat(delta.Current)
+		if delta.Missing == "baseline" {
+			baseline = "-"
+		} else {
+			current = "-"
+		}
+		status := "missing in " + delta.Missing
+		if delta.Regression {
+			status = statusRegression + " (" + status + ")"
+		}
+		return append(row, baseline, current, "-", "-", status)
+	}
+
+	deltaPercent := "n/a"
+	if !math.IsNaN(delta.DeltaPercent) {
+		deltaPercent = fmt.Sprintf("%+.2f%%", delta.DeltaPercent)
+	}
+	status := statusOK
+	if delta.Regression {
+		status = statusRegression
+	}
+
+	return append(row,
+		formatFloat(delta.Baseline),
+		formatFloat(delta.Current),
+		fmt.Sprintf("%+.2f", delta.Delta),
+		deltaPercent,
+		status,
+	)
+}
+
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'f', 2, 64)
+}
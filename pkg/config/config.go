@@ -14,6 +14,7 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -26,7 +27,16 @@ type Config struct {
 	// Runner Runner configuration to use.
 	Runner Runner `yaml:"runner"`
 	// Tests List of `Test`s to run.
-	Tests []*Test `yaml:"tests" validate:"required,min=1"`
+	Tests []*Test `yaml:"tests" validate:"required,min=1,dive"`
+	// Results Raw results archive options.
+	Results *Results `yaml:"results,omitempty"`
+}
+
+// Results options for archiving the raw results of the testers
+type Results struct {
+	// Directory to archive the raw results of the testers in, with their metadata as sidecar JSON files. The archived
+	// results can be replayed through the parsers and outputs with the `report` command (default: empty, no archiving).
+	Dir string `yaml:"dir"`
 }
 
 // New return a new Config object with the `Version` set by default
@@ -62,8 +72,22 @@ type Output struct {
 	Name string `yaml:"name" validate:"required,min=3"`
 	// CSV output options
 	CSV *CSV `yaml:"csv"`
+	// JSON output options
+	JSON *JSON `yaml:"json"`
 	// GoChart output options
 	GoChart *GoChart `yaml:"goChart"`
+	// Heatmap output options
+	Heatmap *Heatmap `yaml:"heatmap"`
+	// HTML output options
+	HTML *HTML `yaml:"html"`
+	// Markdown output options
+	Markdown *Markdown `yaml:"markdown"`
+	// Stats output options
+	Stats *Stats `yaml:"stats"`
+	// Prometheus output options
+	Prometheus *Prometheus `yaml:"prometheus"`
+	// InfluxDB output options
+	InfluxDB *InfluxDB `yaml:"influxdb"`
 	// Dump output options
 	Dump *Dump `yaml:"dump"`
 	// Excelize output options
@@ -146,6 +170,53 @@ type CSV struct {
 	Separator *rune `yaml:"separator"`
 }
 
+// JSONMode how the JSON output writes the rows
+type JSONMode string
+
+const (
+	// JSONModeDocument write the rows as one JSON array per file
+	JSONModeDocument JSONMode = "document"
+	// JSONModeNDJSON write the rows as newline-delimited JSON, one JSON object per line
+	JSONModeNDJSON JSONMode = "ndjson"
+)
+
+// JSON JSON Output config options.
+// Each row is written as a JSON object keyed by the header names, together with the metadata of the data (test, runner,
+// tester, hosts and times).
+type JSON struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Mode can be `document` (one JSON array per file, written on close) or `ndjson` (one JSON object per line, written as the data comes in) (default: `ndjson`)
+	Mode JSONMode `yaml:"mode,omitempty" validate:"omitempty,oneof=document ndjson"`
+}
+
+const (
+	// ColumnKind name of the column with the kind of the row, e.g., of the IPerf3 tester
+	ColumnKind = "kind"
+	// KindStream value of the `kind` column for rows with the data of a single stream
+	KindStream = "stream"
+	// KindSum value of the `kind` column for rows with the sum of all streams
+	KindSum = "sum"
+)
+
+// RowFilter filters for the rows of the (data) tables used by outputs and assertions which aggregate the values of
+// (data) columns
+type RowFilter struct {
+	// Filters only use rows where the column (key) has the given value, filters on columns the data doesn't have are ignored. Set to `{}` to use all rows (default: `kind: sum`, only the sum rows of the IPerf3 tester)
+	Filters map[string]string `yaml:"filters,omitempty"`
+}
+
+// DataFilter which data and rows of the (data) tables are used by outputs and assertions which aggregate the values
+// of (data) columns
+type DataFilter struct {
+	// DataType which data to use, can be `interval` or `summary` (default: `interval`)
+	DataType string `yaml:"dataType,omitempty" validate:"omitempty,oneof=interval summary"`
+	// RowFilter struct fields which are inherited by this struct.
+	// The fields of the RowFilter struct must be written directly to this struct.
+	RowFilter `yaml:",inline"`
+}
+
 // GoChart GoChart Output config options
 type GoChart struct {
 	// FilePath struct fields which are inherited by this struct.
@@ -169,6 +240,143 @@ type GoChartGraph struct {
 	WithSimpleMovingAverage *bool `yaml:"withSimpleMovingAverage,omitempty"`
 }
 
+// Aggregation aggregation of the values of a column, e.g., per server and client host pair
+type Aggregation string
+
+const (
+	// AggregationMean mean of the values
+	AggregationMean Aggregation = "mean"
+	// AggregationMin minimum of the values
+	AggregationMin Aggregation = "min"
+	// AggregationMax maximum of the values
+	AggregationMax Aggregation = "max"
+)
+
+// Heatmap Heatmap Output config options.
+// The heatmap (PNG) and matrix (CSV, same name with `.csv` extension) files are written on close.
+type Heatmap struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Column name of the (data) column to aggregate per server and client host pair, e.g., `bits_per_second` or `rtt_avg`
+	Column string `yaml:"column" validate:"required"`
+	// Aggregation how the values of the column are aggregated per server and client host pair, can be `mean`, `min` or `max` (default: `mean`)
+	Aggregation Aggregation `yaml:"aggregation,omitempty" validate:"omitempty,oneof=mean min max"`
+	// DataFilter struct fields which are inherited by this struct.
+	// The fields of the DataFilter struct must be written directly to this struct.
+	DataFilter `yaml:",inline"`
+	// LowerIsBetter if lower values are better, e.g., for latencies, used for the colour scale (default: `false`)
+	LowerIsBetter *bool `yaml:"lowerIsBetter,omitempty"`
+	// Separator which rune to use as a separator in the matrix CSV file (default: `;`).
+	Separator *rune `yaml:"separator"`
+}
+
+// HTML HTML Output config options.
+// On close a single, self-contained HTML report file is written with the status of the hosts, a summary table and
+// chart per column, the plan and the test definition.
+type HTML struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Columns names of the numeric (data) columns to summarize per server and client host pair, e.g., `bits_per_second` or `rtt_avg`
+	Columns []string `yaml:"columns" validate:"required,min=1"`
+	// DataFilter struct fields which are inherited by this struct.
+	// The fields of the DataFilter struct must be written directly to this struct.
+	DataFilter `yaml:",inline"`
+}
+
+// Markdown Markdown Output config options.
+// On close a Markdown file is written with a summary table (mean, min and max of the columns per server and client
+// host pair) and the failed hosts, e.g., to be pasted into pull requests or wiki pages.
+type Markdown struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Columns names of the numeric (data) columns to summarize per server and client host pair, e.g., `bits_per_second` or `rtt_avg`
+	Columns []string `yaml:"columns" validate:"required,min=1"`
+	// DataFilter struct fields which are inherited by this struct.
+	// The fields of the DataFilter struct must be written directly to this struct.
+	DataFilter `yaml:",inline"`
+	// Template Go template (`text/template`) to render the Markdown with, see the README for the available variables and functions (default: built-in template)
+	Template string `yaml:"template,omitempty"`
+}
+
+// Stats Stats Output config options.
+// The statistics (count, min, max, mean, stddev, p50, p90 and p99) are written to a CSV file and printed as a table
+// to the terminal on close.
+type Stats struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Columns names of the numeric (data) columns to compute the statistics for, e.g., `bits_per_second` or `rtt_avg`
+	Columns []string `yaml:"columns" validate:"required,min=1"`
+	// KeyColumns names of the columns to group the rows by (default: `tester`, `server_host`, `client_host`)
+	KeyColumns []string `yaml:"keyColumns,omitempty"`
+	// DataFilter struct fields which are inherited by this struct.
+	// The fields of the DataFilter struct must be written directly to this struct.
+	DataFilter `yaml:",inline"`
+	// PrintTable if the statistics should be printed as a table to the terminal (default: `true`)
+	PrintTable *bool `yaml:"printTable,omitempty"`
+	// Separator which rune to use as a separator in the CSV file (default: `;`).
+	Separator *rune `yaml:"separator"`
+}
+
+// Prometheus Prometheus Output config options.
+// The values of the columns are exposed with the labels `test`, `tester`, `server_host`, `client_host`, `ip_family` and `round`,
+// summary data as gauges and interval data as summaries. On close the metrics are written as a node_exporter
+// textfile collector file and pushed to the Pushgateway, if configured.
+type Prometheus struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	FilePath `yaml:",inline"`
+	// Columns names of the numeric (data) columns to expose as metrics, e.g., `bits_per_second` or `rtt_avg`
+	Columns []string `yaml:"columns" validate:"required,min=1"`
+	// MetricPrefix prefix for the metric names, the metric name is the prefix followed by the column name (default: `ancientt_`)
+	MetricPrefix string `yaml:"metricPrefix,omitempty"`
+	// RowFilter struct fields which are inherited by this struct.
+	// The fields of the RowFilter struct must be written directly to this struct.
+	RowFilter `yaml:",inline"`
+	// Pushgateway options, if set the metrics are pushed to the Pushgateway
+	Pushgateway *PrometheusPushgateway `yaml:"pushgateway,omitempty"`
+}
+
+// PrometheusPushgateway Prometheus Pushgateway options
+type PrometheusPushgateway struct {
+	// URL of the Pushgateway, e.g., `http://pushgateway:9091`
+	URL string `yaml:"url" validate:"required,url"`
+	// Job name the metrics are pushed with, the metrics are grouped by the test name (default: `ancientt`)
+	Job string `yaml:"job,omitempty"`
+}
+
+// InfluxDB InfluxDB Output config options.
+// Each row is converted to a line of the InfluxDB line protocol, string columns and the tag columns become tags and
+// numeric and boolean columns become fields. The lines are written to a file, when `filePath` is set, and / or written to the InfluxDB v2
+// write API in batches, when `url` is set.
+type InfluxDB struct {
+	// FilePath struct fields which are inherited by this struct.
+	// The fields of the FilePath struct must be written directly to this struct.
+	// The file is optional for the InfluxDB output, so the FilePath struct is not validated.
+	FilePath `yaml:",inline" validate:"-"`
+	// Pattern used for templating the name of the measurement, summary data is written to the measurement with the `_summary` suffix (default: `ancientt_{{ .Data.Tester }}`)
+	MeasurementPattern string `yaml:"measurementPattern,omitempty"`
+	// ExcludeColumns names of the columns which should neither be written as tags nor as fields (default: `system_info`)
+	ExcludeColumns []string `yaml:"excludeColumns,omitempty"`
+	// TagColumns names of the (non string) columns which should be written as tags instead of fields, so that the rows of the same time are written to different series (default: `socket`)
+	TagColumns []string `yaml:"tagColumns,omitempty"`
+	// URL of the InfluxDB, e.g., `http://influxdb:8086`
+	URL string `yaml:"url,omitempty" validate:"omitempty,url"`
+	// Org name of the organization to write to
+	Org string `yaml:"org,omitempty"`
+	// Bucket name of the bucket to write to
+	Bucket string `yaml:"bucket,omitempty"`
+	// Token API token with write access to the bucket
+	Token string `yaml:"token,omitempty"`
+	// BatchSize amount of lines written per request (default: `5000`)
+	BatchSize int `yaml:"batchSize,omitempty" validate:"omitempty,min=1"`
+	// Retries how often a failed write request is retried (default: `3`)
+	Retries *int `yaml:"retries,omitempty" validate:"omitempty,min=0"`
+}
+
 // Dump Dump Output config options
 type Dump struct {
 	// FilePath struct fields which are inherited by this struct.
@@ -214,6 +422,10 @@ type Runner struct {
 	Ansible *RunnerAnsible `yaml:"ansible"`
 	// Mock runner options (userd for testing purposes)
 	Mock *RunnerMock `yaml:"mock"`
+	// Local runner options
+	Local *RunnerLocal `yaml:"local"`
+	// SSH runner options
+	SSH *RunnerSSH `yaml:"ssh"`
 }
 
 // RunnerKubernetes Kubernetes Runner config options
@@ -236,6 +448,8 @@ type RunnerKubernetes struct {
 	Hosts *KubernetesHosts `yaml:"hosts,omitempty"`
 	// ServiceAccounst to use server and client Pods
 	ServiceAccounts *KubernetesServiceAccounts `yaml:"serviceaccounts,omitempty"`
+	// Reach the server Pods through a Service of the given type instead of the Pod IP, can be `ClusterIP` or `NodePort` (if empty the Pod IP is used)
+	ServiceMode corev1.ServiceType `yaml:"serviceMode,omitempty" validate:"omitempty,oneof=ClusterIP NodePort"`
 }
 
 // KubernetesTimeouts timeouts for operations with the Kubernetess API (in secconds)
@@ -298,6 +512,86 @@ type AnsibleTimeouts struct {
 	TaskCommandTimeout time.Duration `yaml:"taskCommandTimeout,omitempty"`
 }
 
+// RunnerLocal Local Runner config options
+type RunnerLocal struct {
+	// List of local hosts to run the tests on, when empty a single `localhost` host with the loopback addresses is used
+	Hosts []LocalHost `yaml:"hosts,omitempty" validate:"dive"`
+	// Path to the ip command used to run commands in a network namespace (if empty will be searched for in `PATH`; default: `ip`)
+	IPCommand string `yaml:"ipCommand,omitempty"`
+	// Timeout settings for local command runs
+	Timeouts *LocalTimeouts `yaml:"timeouts,omitempty"`
+}
+
+// LocalHost a "host" on the local machine, optionally inside a network namespace
+type LocalHost struct {
+	// Name of the host
+	Name string `yaml:"name" validate:"required"`
+	// Network namespace to run the commands for this host in (using `ip netns exec`), if empty the current network namespace is used
+	Namespace string `yaml:"namespace,omitempty"`
+	// IPv4 address of the host, a host with a namespace requires an IPv4 and / or IPv6 address (default: `127.0.0.1` when no namespace is set)
+	AddressV4 string `yaml:"addressV4,omitempty" validate:"required_without=AddressV6"`
+	// IPv6 address of the host (default: `::1` when no namespace is set)
+	AddressV6 string `yaml:"addressV6,omitempty"`
+	// Labels of the host, can be used for the hosts selection
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// LocalTimeouts timeouts for local command runs
+type LocalTimeouts struct {
+	// Timeout duration for the server (main) task to become ready (default: `10s`)
+	ServerReadyTimeout time.Duration `yaml:"serverReadyTimeout,omitempty"`
+	// Timeout duration for client Task command runs (default: `45s`)
+	TaskCommandTimeout time.Duration `yaml:"taskCommandTimeout,omitempty"`
+}
+
+// RunnerSSH SSH Runner config options
+type RunnerSSH struct {
+	// Static inventory of hosts to run the tests on
+	Hosts []SSHHost `yaml:"hosts" validate:"required,min=1"`
+	// Default user to connect as (default: `root`)
+	User string `yaml:"user,omitempty"`
+	// Default SSH port to connect to (default: `22`)
+	Port int `yaml:"port,omitempty"`
+	// Path to the private key file used for authentication
+	PrivateKeyFile string `yaml:"privateKeyFile,omitempty"`
+	// Password used for authentication (only used when set)
+	Password string `yaml:"password,omitempty"`
+	// Path to the known_hosts file used to verify the host keys (default: `$HOME/.ssh/known_hosts`)
+	KnownHostsFile string `yaml:"knownHostsFile,omitempty"`
+	// Skip the host key verification, this is insecure and should only be used for testing purposes
+	InsecureIgnoreHostKey bool `yaml:"insecureIgnoreHostKey,omitempty"`
+	// Timeout settings for SSH connections and command runs
+	Timeouts *SSHTimeouts `yaml:"timeouts,omitempty"`
+}
+
+// SSHHost a host in the SSH runner inventory
+type SSHHost struct {
+	// Name of the host
+	Name string `yaml:"name" validate:"required"`
+	// Address to connect to through SSH (default: the host name)
+	Address string `yaml:"address,omitempty"`
+	// SSH port to connect to (default: RunnerSSH.Port)
+	Port int `yaml:"port,omitempty"`
+	// User to connect as (default: RunnerSSH.User)
+	User string `yaml:"user,omitempty"`
+	// IPv4 address of the host used for the tests
+	AddressV4 string `yaml:"addressV4,omitempty"`
+	// IPv6 address of the host used for the tests
+	AddressV6 string `yaml:"addressV6,omitempty"`
+	// Labels of the host, can be used for the hosts selection
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// SSHTimeouts timeouts for SSH connections and command runs
+type SSHTimeouts struct {
+	// Timeout duration for establishing a SSH connection (default: `10s`)
+	ConnectTimeout time.Duration `yaml:"connectTimeout,omitempty"`
+	// Timeout duration for the server (main) task to become ready (default: `10s`)
+	ServerReadyTimeout time.Duration `yaml:"serverReadyTimeout,omitempty"`
+	// Timeout duration for client Task command runs (default: `45s`)
+	TaskCommandTimeout time.Duration `yaml:"taskCommandTimeout,omitempty"`
+}
+
 // RunnerMock Mock Runner config options (here for good measure)
 type RunnerMock struct {
 }
@@ -311,15 +605,78 @@ type Test struct {
 	// Options for the execution of the test
 	RunOptions RunOptions `yaml:"runOptions,omitempty"`
 	// List of Outputs to use for processing data from the testers.
-	Outputs []Output `yaml:"outputs" validate:"required,min=1"`
+	Outputs []Output `yaml:"outputs" validate:"required,min=1,dive"`
 	// Transformations transformations to be applied to Output data
 	Transformations []*Transformation `yaml:"transformations,omitempty"`
 	// Hosts selection for client and server
 	Hosts TestHosts `yaml:"hosts"`
+	// Topology of which clients are run against which servers, can be `clientsToServers`, `fullMesh`, `pairwise` or `ring` (see `Topology`, default: `clientsToServers`)
+	Topology Topology `yaml:"topology,omitempty" validate:"omitempty,oneof=clientsToServers fullMesh pairwise ring"`
+	// IP address family to run the test with, can be `ipv4`, `ipv6` or `both` (see `IPFamily`, default: `ipv4`)
+	IPFamily IPFamily `yaml:"ipFamily,omitempty" validate:"omitempty,oneof=ipv4 ipv6 both"`
 	// IPerf3 tester options
 	IPerf3 *IPerf3 `yaml:"iperf3"`
 	// PingParsing tester options
 	PingParsing *PingParsing `yaml:"pingParsing"`
+	// Assertions pass / fail assertions evaluated on the results of the test per server and client host pair
+	Assertions []*Assertion `yaml:"assertions,omitempty" validate:"omitempty,dive"`
+}
+
+// Assertion pass / fail assertion on a (data) column of the results, e.g., `bits_per_second` `mean` `>=` `9e9`.
+// The assertion is evaluated on the parsed data (before any transformations) per server and client host pair.
+type Assertion struct {
+	// Column name of the (data) column to assert, e.g., `bits_per_second` or `packet_loss_rate`
+	Column string `yaml:"column" validate:"required"`
+	// Aggregation how the values of the column are aggregated per server and client host pair, can be `mean`, `min` or `max` (default: `mean`)
+	Aggregation Aggregation `yaml:"aggregation,omitempty" validate:"omitempty,oneof=mean min max"`
+	// Operator to compare the aggregated value with the value, can be `<`, `<=`, `>`, `>=`, `==` or `!=`
+	Operator string `yaml:"operator" validate:"required,oneof=< <= > >= == !="`
+	// Value to compare the aggregated value with
+	Value float64 `yaml:"value"`
+	// DataFilter struct fields which are inherited by this struct.
+	// The fields of the DataFilter struct must be written directly to this struct.
+	DataFilter `yaml:",inline"`
+}
+
+// Topology test topology type
+type Topology string
+
+const (
+	// TopologyClientsToServers run every client against every server
+	TopologyClientsToServers Topology = "clientsToServers"
+	// TopologyFullMesh run every host (servers and clients) against every other host, skipping self-pairs
+	TopologyFullMesh Topology = "fullMesh"
+	// TopologyPairwise run the clients against the servers one to one (sorted by name), requires the same amount of clients and servers
+	TopologyPairwise Topology = "pairwise"
+	// TopologyRing run every host (servers and clients, sorted by name) as a client against the next host
+	TopologyRing Topology = "ring"
+)
+
+// IPFamily IP address family type
+type IPFamily string
+
+const (
+	// IPFamilyIPv4 run the test over IPv4
+	IPFamilyIPv4 IPFamily = "ipv4"
+	// IPFamilyIPv6 run the test over IPv6
+	IPFamilyIPv6 IPFamily = "ipv6"
+	// IPFamilyBoth run the test over IPv4 and IPv6
+	IPFamilyBoth IPFamily = "both"
+)
+
+// Families return the list of single IP address families to run the test with, empty defaults to IPv4.
+// An error is returned for an unknown IP family.
+func (f IPFamily) Families() ([]IPFamily, error) {
+	switch f {
+	case "", IPFamilyIPv4:
+		return []IPFamily{IPFamilyIPv4}, nil
+	case IPFamilyIPv6:
+		return []IPFamily{IPFamilyIPv6}, nil
+	case IPFamilyBoth:
+		return []IPFamily{IPFamilyIPv4, IPFamilyIPv6}, nil
+	default:
+		return nil, fmt.Errorf("unknown ip family %s, must be one of %s, %s or %s", f, IPFamilyIPv4, IPFamilyIPv6, IPFamilyBoth)
+	}
 }
 
 // RunMode custom run mode const type for
@@ -335,15 +692,15 @@ const (
 // RunOptions options for running the tasks
 type RunOptions struct {
 	// Continue on error during test runs (recommended to set to `true`) (default: is `true`)
-	ContinueOnError *bool `yaml:"continueOnError,omitempty"`
+	ContinueOnError *bool `yaml:"continueOnError,omitempty" json:"continueOnError,omitempty"`
 	// Amount of test rounds (repetitions) to do for a test plan (default: `1`)
-	Rounds int `yaml:"rounds,omitempty"`
+	Rounds int `yaml:"rounds,omitempty" json:"rounds,omitempty"`
 	// Time interval to sleep / wait between (default: `10s`)
-	Interval time.Duration `yaml:"interval,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
 	// Run mode can be `parallel` or `sequential` (see `RunMode`, default: is `sequential`)
-	Mode RunMode `yaml:"mode,omitempty"`
-	// **NOT IMPLEMENTED YET** amount of test tasks to run when using `RunModeParallel` (value: `parallel`).
-	ParallelCount int `yaml:"parallelCount,omitempty"`
+	Mode RunMode `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Maximum amount of client tasks to run at the same time when using `RunModeParallel` (value: `parallel`), `0` means no limit (default: `0`)
+	ParallelCount int `yaml:"parallelCount,omitempty" json:"parallelCount,omitempty" validate:"min=0"`
 }
 
 // TestHosts list of clients and servers hosts for use in the test(s)
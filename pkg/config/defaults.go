@@ -14,6 +14,8 @@ limitations under the License.
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -104,6 +106,82 @@ func (c *RunnerAnsible) SetDefaults() {
 	}
 }
 
+// SetDefaults set defaults on config part
+func (c *RunnerLocal) SetDefaults() {
+	if c.IPCommand == "" {
+		c.IPCommand = "ip"
+	}
+
+	if len(c.Hosts) == 0 {
+		c.Hosts = []LocalHost{
+			{
+				Name: "localhost",
+			},
+		}
+	}
+	for i := range c.Hosts {
+		if c.Hosts[i].Namespace != "" {
+			continue
+		}
+		if c.Hosts[i].AddressV4 == "" {
+			c.Hosts[i].AddressV4 = "127.0.0.1"
+		}
+		if c.Hosts[i].AddressV6 == "" {
+			c.Hosts[i].AddressV6 = "::1"
+		}
+	}
+
+	if c.Timeouts == nil {
+		c.Timeouts = &LocalTimeouts{}
+	}
+	if c.Timeouts.ServerReadyTimeout == 0 {
+		c.Timeouts.ServerReadyTimeout = 10 * time.Second
+	}
+	if c.Timeouts.TaskCommandTimeout == 0 {
+		c.Timeouts.TaskCommandTimeout = 45 * time.Second
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *RunnerSSH) SetDefaults() {
+	if c.User == "" {
+		c.User = "root"
+	}
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.KnownHostsFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			c.KnownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+		}
+	}
+
+	for i := range c.Hosts {
+		if c.Hosts[i].Address == "" {
+			c.Hosts[i].Address = c.Hosts[i].Name
+		}
+		if c.Hosts[i].Port == 0 {
+			c.Hosts[i].Port = c.Port
+		}
+		if c.Hosts[i].User == "" {
+			c.Hosts[i].User = c.User
+		}
+	}
+
+	if c.Timeouts == nil {
+		c.Timeouts = &SSHTimeouts{}
+	}
+	if c.Timeouts.ConnectTimeout == 0 {
+		c.Timeouts.ConnectTimeout = 10 * time.Second
+	}
+	if c.Timeouts.ServerReadyTimeout == 0 {
+		c.Timeouts.ServerReadyTimeout = 10 * time.Second
+	}
+	if c.Timeouts.TaskCommandTimeout == 0 {
+		c.Timeouts.TaskCommandTimeout = 45 * time.Second
+	}
+}
+
 // SetDefaults set defaults on config part
 func (c *RunOptions) SetDefaults() {
 	if c.ContinueOnError == nil {
@@ -170,11 +248,38 @@ func (c *AdditionalFlags) SetDefaults() {
 
 // SetDefaults set defaults on config part
 func (c *Excelize) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.xlsx"
+	}
 	if c.SaveAfterRows == 0 {
 		c.SaveAfterRows = 1
 	}
 }
 
+// SetDefaults set defaults on config part
+func (c *Dump) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.txt"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *GoChart) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}-{{ .Data.ServerHost }}_{{ .Data.ClientHost }}-{{ .Extra.Axises }}.png"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *SQLite) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.sqlite3"
+	}
+	if c.TableNamePattern == "" {
+		c.TableNamePattern = "ancientt{{ .TestStartTime }}{{ .Data.Tester }}{{ .Data.ServerHost }}{{ .Data.ClientHost }}"
+	}
+}
+
 // SetDefaults set defaults on config part
 func (c *MySQL) SetDefaults() {
 	if c.AutoCreateTables == nil {
@@ -182,14 +287,142 @@ func (c *MySQL) SetDefaults() {
 	}
 }
 
+// SetDefaults set defaults on config part
+func (c *JSON) SetDefaults() {
+	if c.Mode == "" {
+		c.Mode = JSONModeNDJSON
+	}
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.ndjson"
+		if c.Mode == JSONModeDocument {
+			c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.json"
+		}
+	}
+}
+
 // SetDefaults set defaults on config part
 func (c *CSV) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.csv"
+	}
+	if c.Separator == nil {
+		semiColon := ';'
+		c.Separator = &semiColon
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *RowFilter) SetDefaults() {
+	if c.Filters == nil {
+		c.Filters = map[string]string{
+			ColumnKind: KindSum,
+		}
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *DataFilter) SetDefaults() {
+	if c.DataType == "" {
+		c.DataType = "interval"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *Heatmap) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}-heatmap-{{ .Extra.Column }}.png"
+	}
+	if c.Aggregation == "" {
+		c.Aggregation = AggregationMean
+	}
+	if c.LowerIsBetter == nil {
+		c.LowerIsBetter = util.BoolFalsePointer()
+	}
+	if c.Separator == nil {
+		semiColon := ';'
+		c.Separator = &semiColon
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *HTML) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}-report.html"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *Markdown) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.md"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *Stats) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}-stats.csv"
+	}
+	if len(c.KeyColumns) == 0 {
+		c.KeyColumns = []string{"tester", "server_host", "client_host"}
+	}
+	if c.PrintTable == nil {
+		c.PrintTable = util.BoolTruePointer()
+	}
 	if c.Separator == nil {
 		semiColon := ';'
 		c.Separator = &semiColon
 	}
 }
 
+// SetDefaults set defaults on config part
+func (c *Prometheus) SetDefaults() {
+	if c.NamePattern == "" {
+		// The textfile collector reads all files in its directory, so the file is overwritten by each run of the test
+		c.NamePattern = "ancientt-{{ .Data.Test }}.prom"
+	}
+	if c.MetricPrefix == "" {
+		c.MetricPrefix = "ancientt_"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *InfluxDB) SetDefaults() {
+	if c.NamePattern == "" {
+		c.NamePattern = "ancientt-{{ .TestStartTime }}-{{ .Data.Tester }}.lp"
+	}
+	if c.MeasurementPattern == "" {
+		c.MeasurementPattern = "ancientt_{{ .Data.Tester }}"
+	}
+	if c.ExcludeColumns == nil {
+		c.ExcludeColumns = []string{"system_info"}
+	}
+	if c.TagColumns == nil {
+		c.TagColumns = []string{"socket"}
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 5000
+	}
+	if c.Retries == nil {
+		defVal := 3
+		c.Retries = &defVal
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *PrometheusPushgateway) SetDefaults() {
+	if c.Job == "" {
+		c.Job = "ancientt"
+	}
+}
+
+// SetDefaults set defaults on config part
+func (c *Assertion) SetDefaults() {
+	if c.Aggregation == "" {
+		c.Aggregation = AggregationMean
+	}
+}
+
 // SetDefaults set defaults on confg part
 func (c *GoChartGraph) SetDefaults() {
 	if c.WithLinearRegression == nil {
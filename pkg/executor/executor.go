@@ -16,6 +16,7 @@ package executor
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/exec"
 	"syscall"
@@ -28,6 +29,7 @@ type Executor interface {
 	ExecuteCommand(ctx context.Context, actionName string, command string, arg ...string) error
 	ExecuteCommandWithOutput(ctx context.Context, actionName string, command string, arg ...string) (string, error)
 	ExecuteCommandWithOutputByte(ctx context.Context, actionName string, command string, arg ...string) ([]byte, error)
+	ExecuteCommandWithStdout(ctx context.Context, actionName string, stdout io.Writer, command string, arg ...string) error
 	SetEnv([]string)
 }
 
@@ -90,6 +92,26 @@ func (ce CommandExecutor) ExecuteCommandWithOutputByte(ctx context.Context, acti
 	return out, nil
 }
 
+// ExecuteCommandWithStdout execute a given command with its arguments and write its stdout to the given io.Writer.
+// When the io.Writer is an *os.File (e.g., from os.Pipe()), the command writes directly to it.
+func (ce CommandExecutor) ExecuteCommandWithStdout(ctx context.Context, actionName string, stdout io.Writer, command string, arg ...string) error {
+	cmd := exec.CommandContext(ctx, command, arg...)
+	cmd.Env = os.Environ()
+	cmd.Stdout = stdout
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGTERM,
+		Setpgid:   true,
+	}
+
+	ce.logger.Info("executing command", zap.String("command", command), zap.Strings("args", arg))
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
+
 // SetEnv set env for command execution
 func (ce CommandExecutor) SetEnv(e []string) {
 	ce.env = e
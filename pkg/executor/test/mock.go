@@ -17,6 +17,7 @@ package test
 import (
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 
 	"github.com/galexrt/ancientt/pkg/executor"
@@ -31,6 +32,7 @@ type MockExecutor struct {
 	MockExecuteCommand               func(ctx context.Context, actionName string, command string, arg ...string) error
 	MockExecuteCommandWithOutput     func(ctx context.Context, actionName string, command string, arg ...string) (string, error)
 	MockExecuteCommandWithOutputByte func(ctx context.Context, actionName string, command string, arg ...string) ([]byte, error)
+	MockExecuteCommandWithStdout     func(ctx context.Context, actionName string, stdout io.Writer, command string, arg ...string) error
 	MockSetEnv                       func(e []string)
 
 	env []string
@@ -79,6 +81,20 @@ func (ce MockExecutor) ExecuteCommandWithOutputByte(ctx context.Context, actionN
 	return out, nil
 }
 
+// ExecuteCommandWithStdout execute a given command with its arguments and write its stdout to the given io.Writer
+func (ce MockExecutor) ExecuteCommandWithStdout(ctx context.Context, actionName string, stdout io.Writer, command string, arg ...string) error {
+	if ce.MockExecuteCommandWithStdout != nil {
+		return ce.MockExecuteCommandWithStdout(ctx, actionName, stdout, command, arg...)
+	}
+
+	cmd := exec.CommandContext(ctx, command, arg...)
+	cmd.Stdout = stdout
+
+	ce.Logger.With(zap.String("command", command), zap.Strings("args", arg)).Info("executing")
+
+	return cmd.Run()
+}
+
 // SetEnv set env for command execution
 func (ce MockExecutor) SetEnv(e []string) {
 	ce.Logger.Debug(fmt.Sprintf("%+v", e), zap.String("action", "setEnv()"))
@@ -18,23 +18,25 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/galexrt/ancientt/pkg/util"
 	"github.com/galexrt/ancientt/testers"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 )
 
 // PodRecreate delete Pod if it exists and create it again. If the Pod does not exist, create it.
-func PodRecreate(k8sclient kubernetes.Interface, pod *corev1.Pod, delTimeout int) error {
+func PodRecreate(ctx context.Context, k8sclient kubernetes.Interface, pod *corev1.Pod, delTimeout int) error {
 	// Delete Pod if it exists
-	if err := PodDelete(k8sclient, pod, delTimeout); err != nil {
+	if err := PodDelete(ctx, k8sclient, pod, delTimeout); err != nil {
 		return err
 	}
 
 	// Create Pod again
-	ctx := context.TODO()
 	if _, err := k8sclient.CoreV1().Pods(pod.ObjectMeta.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
 		if !errors.IsAlreadyExists(err) {
 			return err
@@ -45,12 +47,11 @@ func PodRecreate(k8sclient kubernetes.Interface, pod *corev1.Pod, delTimeout int
 }
 
 // PodDelete delete Pod if it exists, wait for it till it has been for custom amount deleted
-func PodDelete(k8sclient kubernetes.Interface, pod *corev1.Pod, timeout int) error {
+func PodDelete(ctx context.Context, k8sclient kubernetes.Interface, pod *corev1.Pod, timeout int) error {
 	namespace := pod.ObjectMeta.Namespace
 	podName := pod.ObjectMeta.Name
 
 	// Delete Pod
-	ctx := context.TODO()
 	if err := k8sclient.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{}); err != nil {
 		if errors.IsNotFound(err) {
 			return nil
@@ -60,7 +61,6 @@ func PodDelete(k8sclient kubernetes.Interface, pod *corev1.Pod, timeout int) err
 
 	for i := 0; i < timeout; i++ {
 		// Check if Pod still exists
-		ctx := context.TODO()
 		if _, err := k8sclient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{}); err != nil {
 			if errors.IsNotFound(err) {
 				return nil
@@ -68,15 +68,17 @@ func PodDelete(k8sclient kubernetes.Interface, pod *corev1.Pod, timeout int) err
 			return err
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := util.Sleep(ctx, 1*time.Second); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("pod %s/%s not deleted after 30s", namespace, podName)
 }
 
 // PodDeleteByName delete Pod by namespace and name if it exists
-func PodDeleteByName(k8sclient kubernetes.Interface, namespace string, podName string, timeout int) error {
-	return PodDelete(k8sclient, &corev1.Pod{
+func PodDeleteByName(ctx context.Context, k8sclient kubernetes.Interface, namespace string, podName string, timeout int) error {
+	return PodDelete(ctx, k8sclient, &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      podName,
@@ -85,10 +87,9 @@ func PodDeleteByName(k8sclient kubernetes.Interface, namespace string, podName s
 }
 
 // PodDeleteByLabels delete Pods by labels
-func PodDeleteByLabels(k8sclient kubernetes.Interface, namespace string, selectorLabels map[string]string) error {
+func PodDeleteByLabels(ctx context.Context, k8sclient kubernetes.Interface, namespace string, selectorLabels map[string]string) error {
 	set := labels.Set(selectorLabels)
 
-	ctx := context.TODO()
 	pods, err := k8sclient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: set.AsSelector().String(),
 	})
@@ -105,7 +106,6 @@ func PodDeleteByLabels(k8sclient kubernetes.Interface, namespace string, selecto
 
 	for _, pod := range pods.Items {
 		// Delete Pods by labels
-		ctx := context.TODO()
 		if err := k8sclient.CoreV1().Pods(namespace).Delete(ctx, pod.ObjectMeta.Name, metav1.DeleteOptions{}); err != nil {
 			if errors.IsNotFound(err) {
 				return nil
@@ -117,9 +117,8 @@ func PodDeleteByLabels(k8sclient kubernetes.Interface, namespace string, selecto
 }
 
 // WaitForPodToRun wait for a Pod to be in phase Running. In case of phase Running, return true and no error
-func WaitForPodToRun(k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
+func WaitForPodToRun(ctx context.Context, k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
 	for i := 0; i < timeout; i++ {
-		ctx := context.TODO()
 		pod, err := k8sclient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 		if err != nil {
 			if !errors.IsAlreadyExists(err) {
@@ -130,16 +129,17 @@ func WaitForPodToRun(k8sclient kubernetes.Interface, namespace string, podName s
 			return true, nil
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := util.Sleep(ctx, 1*time.Second); err != nil {
+			return false, err
+		}
 	}
 
 	return false, nil
 }
 
 // WaitForPodToSucceed wait for a Pod to be in phase Succeeded. In case of phase Succeeded, return true and no error
-func WaitForPodToSucceed(k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
+func WaitForPodToSucceed(ctx context.Context, k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
 	for i := 0; i < timeout; i++ {
-		ctx := context.TODO()
 		pod, err := k8sclient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 		if err != nil {
 			return false, err
@@ -148,16 +148,17 @@ func WaitForPodToSucceed(k8sclient kubernetes.Interface, namespace string, podNa
 			return true, nil
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := util.Sleep(ctx, 1*time.Second); err != nil {
+			return false, err
+		}
 	}
 
 	return false, nil
 }
 
 // WaitForPodToRunOrSucceed wait for a Pod to be in phase Running or Succeeded. In case of one of the phases, return true and no error
-func WaitForPodToRunOrSucceed(k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
+func WaitForPodToRunOrSucceed(ctx context.Context, k8sclient kubernetes.Interface, namespace string, podName string, timeout int) (bool, error) {
 	for i := 0; i < timeout; i++ {
-		ctx := context.TODO()
 		pod, err := k8sclient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 		if err != nil {
 			return false, err
@@ -166,7 +167,39 @@ func WaitForPodToRunOrSucceed(k8sclient kubernetes.Interface, namespace string,
 			return true, nil
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := util.Sleep(ctx, 1*time.Second); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// WaitForServiceEndpoints wait for an EndpointSlice of a Service to have a ready endpoint. In case of a ready endpoint, return true and no error
+func WaitForServiceEndpoints(ctx context.Context, k8sclient kubernetes.Interface, namespace string, serviceName string, timeout int) (bool, error) {
+	set := labels.Set{
+		discoveryv1.LabelServiceName: serviceName,
+	}
+
+	for i := 0; i < timeout; i++ {
+		endpointSlices, err := k8sclient.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: set.AsSelector().String(),
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, endpointSlice := range endpointSlices.Items {
+			for _, endpoint := range endpointSlice.Endpoints {
+				// An unknown ready condition is to be interpreted as ready
+				if len(endpoint.Addresses) > 0 && (endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready) {
+					return true, nil
+				}
+			}
+		}
+
+		if err := util.Sleep(ctx, 1*time.Second); err != nil {
+			return false, err
+		}
 	}
 
 	return false, nil
@@ -189,3 +222,69 @@ func PortsListToPorts(list testers.Ports) []corev1.ContainerPort {
 	}
 	return ports
 }
+
+// PortsListToServicePorts PortList testers.Port to Kubernetes []corev1.ServicePort conversion (for TCP and UDP)
+func PortsListToServicePorts(list testers.Ports) []corev1.ServicePort {
+	ports := []corev1.ServicePort{}
+	for _, p := range list.TCP {
+		ports = append(ports, corev1.ServicePort{
+			Name:       fmt.Sprintf("tcp-%d", p),
+			Port:       p,
+			TargetPort: intstr.FromInt32(p),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	for _, p := range list.UDP {
+		ports = append(ports, corev1.ServicePort{
+			Name:       fmt.Sprintf("udp-%d", p),
+			Port:       p,
+			TargetPort: intstr.FromInt32(p),
+			Protocol:   corev1.ProtocolUDP,
+		})
+	}
+	return ports
+}
+
+// ServiceRecreate delete Service if it exists and create it again. If the Service does not exist, create it.
+func ServiceRecreate(ctx context.Context, k8sclient kubernetes.Interface, service *corev1.Service) (*corev1.Service, error) {
+	// Delete Service if it exists
+	if err := ServiceDeleteByName(ctx, k8sclient, service.ObjectMeta.Namespace, service.ObjectMeta.Name); err != nil {
+		return nil, err
+	}
+
+	// Create Service again
+	return k8sclient.CoreV1().Services(service.ObjectMeta.Namespace).Create(ctx, service, metav1.CreateOptions{})
+}
+
+// ServiceDeleteByName delete Service by namespace and name if it exists
+func ServiceDeleteByName(ctx context.Context, k8sclient kubernetes.Interface, namespace string, serviceName string) error {
+	if err := k8sclient.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// ServiceDeleteByLabels delete Services by labels
+func ServiceDeleteByLabels(ctx context.Context, k8sclient kubernetes.Interface, namespace string, selectorLabels map[string]string) error {
+	set := labels.Set(selectorLabels)
+
+	services, err := k8sclient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: set.AsSelector().String(),
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, service := range services.Items {
+		if err := ServiceDeleteByName(ctx, k8sclient, namespace, service.ObjectMeta.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
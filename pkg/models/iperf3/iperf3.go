@@ -18,6 +18,8 @@ type ClientResult struct {
 	Start     Start      `json:"start"`
 	Intervals []Interval `json:"intervals"`
 	End       End        `json:"end"`
+	// Error is set by IPerf3 when the test failed, e.g., server busy or connection refused
+	Error string `json:"error"`
 }
 
 // Start
@@ -56,6 +58,9 @@ type ConnectingTo struct {
 	Port int32  `json:"port"`
 }
 
+// ProtocolUDP protocol name of UDP tests in the TestStart
+const ProtocolUDP = "UDP"
+
 // TestStart
 type TestStart struct {
 	Protocol   string `json:"protocol"`
@@ -89,6 +94,12 @@ type Stream struct {
 	RTTVar        int64   `json:"rttvar"`
 	PMTU          int64   `json:"pmtu"`
 	Omitted       bool    `json:"omitted"`
+	// UDP only fields
+	Packets     int64   `json:"packets"`
+	JitterMs    float64 `json:"jitter_ms"`
+	LostPackets int64   `json:"lost_packets"`
+	LostPercent float64 `json:"lost_percent"`
+	OutOfOrder  int64   `json:"out_of_order"`
 }
 
 // Sum
@@ -100,6 +111,12 @@ type Sum struct {
 	BitsPerSecond float64 `json:"bits_per_second"`
 	Retransmits   int64   `json:"retransmits"`
 	Omitted       bool    `json:"omitted"`
+	// UDP only fields
+	Packets     int64   `json:"packets"`
+	JitterMs    float64 `json:"jitter_ms"`
+	LostPackets int64   `json:"lost_packets"`
+	LostPercent float64 `json:"lost_percent"`
+	OutOfOrder  int64   `json:"out_of_order"`
 }
 
 // End
@@ -107,6 +124,7 @@ type End struct {
 	Streams               []EndStream           `json:"streams"`
 	SumSent               SumSent               `json:"sum_sent"`
 	SumReceived           SumReceived           `json:"sum_received"`
+	Sum                   UDPSum                `json:"sum"`
 	CPUUtilizationPercent CPUUtilizationPercent `json:"cpu_utilization_percent"`
 	SenderTCPCongestion   string                `json:"sender_tcp_congestion"`
 	ReceiverTCPCongestion string                `json:"receiver_tcp_congestion"`
@@ -114,8 +132,38 @@ type End struct {
 
 // EndStream
 type EndStream struct {
-	Sender   Sender   `json:"sender"`
-	Receiver Receiver `json:"receiver"`
+	Sender   Sender     `json:"sender"`
+	Receiver Receiver   `json:"receiver"`
+	UDP      *UDPStream `json:"udp,omitempty"`
+}
+
+// UDPStream end results of a UDP stream
+type UDPStream struct {
+	Socket        int64   `json:"socket"`
+	Start         float64 `json:"start"`
+	End           float64 `json:"end"`
+	Seconds       float64 `json:"seconds"`
+	Bytes         int64   `json:"bytes"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+	JitterMs      float64 `json:"jitter_ms"`
+	LostPackets   int64   `json:"lost_packets"`
+	Packets       int64   `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
+	OutOfOrder    int64   `json:"out_of_order"`
+}
+
+// UDPSum end results sum of all UDP streams
+type UDPSum struct {
+	Start         float64 `json:"start"`
+	End           float64 `json:"end"`
+	Seconds       float64 `json:"seconds"`
+	Bytes         int64   `json:"bytes"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+	JitterMs      float64 `json:"jitter_ms"`
+	LostPackets   int64   `json:"lost_packets"`
+	Packets       int64   `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
+	OutOfOrder    int64   `json:"out_of_order"`
 }
 
 // Sender
@@ -14,6 +14,7 @@ limitations under the License.
 package util
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"time"
 )
@@ -31,7 +32,8 @@ const (
 // GetPNameFromTask get a "persistent" name for a task
 // This is done by calculating the checksums of the used names.
 func GetPNameFromTask(round int, hostname string, command string, role PNameRole, testStartTime time.Time) string {
-	return fmt.Sprintf("ancientt-%s-%s-%d", role, command, testStartTime.UnixNano())
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d-%s", round, hostname)))
+	return fmt.Sprintf("ancientt-%s-%s-%d-%x", role, command, testStartTime.UnixNano(), sum[:4])
 }
 
 // GetTaskName get a task name
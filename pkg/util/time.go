@@ -13,7 +13,25 @@ limitations under the License.
 
 package util
 
+import (
+	"context"
+	"time"
+)
+
 const (
 	// TimeDateFormat used for ancientt outputs
 	TimeDateFormat = "2006-01-02T15:04:05-0700"
 )
+
+// Sleep sleep for the given duration or until the context is done, returns the context error in the latter case
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
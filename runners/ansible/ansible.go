@@ -41,6 +41,12 @@ import (
 const (
 	// Name Ansible Runner Name
 	Name = "ansible"
+
+	// pidFileDir directory on the hosts the PID files of the main tasks are written to
+	pidFileDir = "/tmp"
+	// processGroupWrapper run the main task command in its own process group and write the process group ID to the
+	// PID file (first argument), so the main task can be killed on the host, stopping the ansible command doesn't stop it
+	processGroupWrapper = `exec setsid -w sh -c 'echo "$$" > "$0"; exec "$@"'`
 )
 
 var (
@@ -60,6 +66,11 @@ type Ansible struct {
 	runOptions     config.RunOptions
 	executor       executor.Executor
 	additionalInfo string
+
+	// lock for the pidFiles, which are written to by the main tasks and read by the Cleanup
+	lock sync.Mutex
+	// pidFiles PID files of the started main tasks per host, which haven't been killed yet
+	pidFiles map[string]map[string]struct{}
 }
 
 // NewRunner return a new Ansible Runner
@@ -70,12 +81,13 @@ func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
 		logger:   logger.With(zap.String("runner", Name), zap.String("inventoryfile", cfg.Runner.Ansible.InventoryFilePath)),
 		config:   conf,
 		executor: executor.NewCommandExecutor(logger, "runner:ansible"),
+		pidFiles: map[string]map[string]struct{}{},
 	}, nil
 }
 
 // GetHostsForTest return a mocked list of hots for the given test config
-func (a *Ansible) GetHostsForTest(test *config.Test) (*testers.Hosts, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), a.config.Timeouts.CommandTimeout)
+func (a *Ansible) GetHostsForTest(bctx context.Context, test *config.Test) (*testers.Hosts, error) {
+	ctx, cancel := context.WithTimeout(bctx, a.config.Timeouts.CommandTimeout)
 	defer cancel()
 
 	out, err := a.executor.ExecuteCommandWithOutputByte(ctx, "runner:ansible: list hosts from inventory", a.config.AnsibleInventoryCommand, []string{
@@ -101,7 +113,7 @@ func (a *Ansible) GetHostsForTest(test *config.Test) (*testers.Hosts, error) {
 	inCh := make(chan string)
 	retCh := make(chan error)
 
-	cmdCtx, cmdCancel := context.WithCancel(context.Background())
+	cmdCtx, cmdCancel := context.WithCancel(bctx)
 	defer cmdCancel()
 
 	// Spanw requested amount of workers
@@ -262,10 +274,10 @@ func (a *Ansible) getHostNetworkAddress(bctx context.Context, host string) (*tes
 }
 
 // Prepare prepare Ansible runner for usage, though right now there isn't really anything in need of preparations
-func (a *Ansible) Prepare(runOpts config.RunOptions, plan *testers.Plan) error {
+func (a *Ansible) Prepare(bctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error {
 	a.runOptions = runOpts
 
-	ctx, cancel := context.WithTimeout(context.Background(), a.config.Timeouts.CommandTimeout)
+	ctx, cancel := context.WithTimeout(bctx, a.config.Timeouts.CommandTimeout)
 	defer cancel()
 
 	out, err := a.executor.ExecuteCommandWithOutput(ctx, "runner:ansible: get ansible version", a.config.AnsibleCommand, "--version")
@@ -286,19 +298,24 @@ func (a *Ansible) Prepare(runOpts config.RunOptions, plan *testers.Plan) error {
 }
 
 // Execute run the given commands and return the logs of it and / or error
-func (a *Ansible) Execute(plan *testers.Plan, parser chan<- parsers.Input) error {
+func (a *Ansible) Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error {
 	for round, tasks := range plan.Commands {
 		a.logger.Info(fmt.Sprintf("running commands round %d of %d", round+1, len(plan.Commands)))
 		for i, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			if task.Sleep != 0 {
 				a.logger.Info(fmt.Sprintf("waiting %s to pass before continuing next round", task.Sleep.String()))
-				time.Sleep(task.Sleep)
+				if err := util.Sleep(ctx, task.Sleep); err != nil {
+					return err
+				}
 				continue
 			}
 			a.logger.Info(fmt.Sprintf("running task round %d of %d", i+1, len(tasks)))
 
-			if err := a.runTasks(round, task, plan.TestStartTime, plan.Tester, util.GetTaskName(plan.Tester, plan.TestStartTime), parser); err != nil {
-				if !*plan.RunOptions.ContinueOnError {
+			if err := a.runTasks(ctx, round, task, plan.TestStartTime, plan.Tester, util.GetTaskName(plan.Tester, plan.TestStartTime), parser); err != nil {
+				if !*plan.RunOptions.ContinueOnError || ctx.Err() != nil {
 					return err
 				}
 				a.logger.Warn("continuing after err", zap.Error(err))
@@ -309,7 +326,7 @@ func (a *Ansible) Execute(plan *testers.Plan, parser chan<- parsers.Input) error
 	return nil
 }
 
-func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.Time, tester string, taskName string, parser chan<- parsers.Input) error {
+func (a *Ansible) runTasks(ctx context.Context, round int, mainTask *testers.Task, plannedTime time.Time, tester string, taskName string, parser chan<- parsers.Input) error {
 	logger := a.logger.With(zap.Int("round", round))
 
 	// Create initial cmdtemplate.Variables
@@ -330,12 +347,13 @@ func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.T
 	}
 
 	var mainWG sync.WaitGroup
-	var wg sync.WaitGroup
 
-	mainTaskStopped := false
-	mainCtx, mainCancel := context.WithCancel(context.Background())
+	mainCtx, mainCancel := context.WithCancel(ctx)
 	defer mainCancel()
 
+	pidFile := fmt.Sprintf("%s/%s-%d.pid", pidFileDir, taskName, round)
+	a.addPIDFile(mainTask.Host.Name, pidFile)
+
 	mainWG.Add(1)
 	go func() {
 		defer mainWG.Done()
@@ -343,7 +361,7 @@ func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.T
 			fmt.Sprintf("--inventory=%s", a.config.InventoryFilePath),
 			mainTask.Host.Name,
 			"--module-name=shell",
-			fmt.Sprintf("--args=%s %s", mainTask.Command, strings.Join(mainTask.Args, " ")),
+			fmt.Sprintf("--args=%s %s %s %s", processGroupWrapper, pidFile, mainTask.Command, strings.Join(mainTask.Args, " ")),
 		}...)
 		if err != nil {
 			if exiterr, ok := err.(*exec.ExitError); ok {
@@ -354,7 +372,7 @@ func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.T
 				}
 			}
 			// Ignore any error after the main task is stopped
-			if mainTaskStopped {
+			if mainCtx.Err() != nil {
 				logger.Debug("ignored error after main task was stopped", zap.Error(err))
 				return
 			}
@@ -365,10 +383,22 @@ func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.T
 		}
 	}()
 
-	time.Sleep(250 * time.Millisecond)
+	stopMainTask := func() {
+		mainCancel()
+		mainWG.Wait()
+		// The main task is killed by the Cleanup, when it can't be killed now
+		if err := a.killMainTask(context.Background(), mainTask.Host.Name, pidFile); err != nil {
+			logger.Error("failed to kill main task", zap.String("hostname", mainTask.Host.Name), zap.Error(err))
+		}
+	}
+
+	if err := util.Sleep(ctx, 250*time.Millisecond); err != nil {
+		stopMainTask()
+		return err
+	}
 
 	ready := false
-	checkCtx, checkCancel := context.WithTimeout(context.Background(), a.config.Timeouts.TaskCommandTimeout)
+	checkCtx, checkCancel := context.WithTimeout(ctx, a.config.Timeouts.TaskCommandTimeout)
 	defer checkCancel()
 
 	tries := *a.config.CommandRetries
@@ -386,90 +416,147 @@ func (a *Ansible) runTasks(round int, mainTask *testers.Task, plannedTime time.T
 		logger.Error("", zap.Error(err))
 
 		logger.Info(fmt.Sprintf("main task not running yet, sleeping 3 seconds (try: %d/%d) ...", i, tries))
-		time.Sleep(3 * time.Second)
+		if err := util.Sleep(ctx, 3*time.Second); err != nil {
+			break
+		}
 	}
 
 	if ready {
-		for i, task := range mainTask.SubTasks {
+		runners.RunSubTasks(ctx, a.runOptions, mainTask.SubTasks, func(i int, task *testers.Task) {
 			logger.Info(fmt.Sprintf("running sub task %d of %d", i+1, len(mainTask.SubTasks)), zap.String("hostname", task.Host.Name))
 
-			wg.Add(1)
-			go func(task *testers.Task) {
-				ctx, cancel := context.WithTimeout(context.Background(), a.config.Timeouts.TaskCommandTimeout)
-				defer cancel()
-
-				defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, a.config.Timeouts.TaskCommandTimeout)
+			defer cancel()
 
-				// Template command and args for each task
-				if err := cmdtemplate.Template(task, templateVars); err != nil {
-					erro := fmt.Errorf("failed to template task command and / or args. %+v", err)
-					logger.Error("error during createPodsForTasks", zap.String("hostname", task.Host.Name), zap.Error(erro))
-					mainTask.Status.AddFailedClient(task.Host, erro)
-					return
-				}
-
-				testTime := time.Now()
-
-				out, err := a.executor.ExecuteCommandWithOutputByte(ctx, "runner:ansible: run sub task command", a.config.AnsibleCommand, []string{
-					fmt.Sprintf("--inventory=%s", a.config.InventoryFilePath),
-					task.Host.Name,
-					"--module-name=shell",
-					fmt.Sprintf("--args=%s %s", task.Command, strings.Join(task.Args, " ")),
-				}...)
-				if err != nil {
-					logger.Error("client task failed", zap.String("hostname", task.Host.Name), zap.Error(err))
-					mainTask.Status.AddFailedClient(task.Host, err)
-					return
-				}
-
-				mainTask.Status.AddSuccessfulClient(task.Host)
-
-				// Clean, "transform" to io.Reader compatible interface and send logs to parsers
-				out = cleanAnsibleOutput(out)
-				r := ioutil.NopCloser(bytes.NewReader(out))
-
-				parser <- parsers.Input{
-					TestStartTime:  plannedTime,
-					TestTime:       testTime,
-					Round:          round,
-					DataStream:     &r,
-					Tester:         tester,
-					ServerHost:     mainTask.Host.Name,
-					ClientHost:     task.Host.Name,
-					AdditionalInfo: a.additionalInfo,
-				}
-			}(task)
+			// Template command and args for each task
+			if err := cmdtemplate.Template(task, templateVars); err != nil {
+				erro := fmt.Errorf("failed to template task command and / or args. %+v", err)
+				logger.Error("error during createPodsForTasks", zap.String("hostname", task.Host.Name), zap.Error(erro))
+				mainTask.Status.AddFailedClient(task.Host, erro)
+				return
+			}
 
-			if a.runOptions.Mode != config.RunModeParallel {
-				wg.Wait()
+			testTime := time.Now()
+
+			out, err := a.executor.ExecuteCommandWithOutputByte(ctx, "runner:ansible: run sub task command", a.config.AnsibleCommand, []string{
+				fmt.Sprintf("--inventory=%s", a.config.InventoryFilePath),
+				task.Host.Name,
+				"--module-name=shell",
+				fmt.Sprintf("--args=%s %s", task.Command, strings.Join(task.Args, " ")),
+			}...)
+			if err != nil {
+				logger.Error("client task failed", zap.String("hostname", task.Host.Name), zap.Error(err))
+				mainTask.Status.AddFailedClient(task.Host, err)
+				return
 			}
-		}
 
-		// When RunOptions.Mode `parallel` then we wait after all test tasks have been run
-		if a.runOptions.Mode == config.RunModeParallel {
-			wg.Wait()
-		}
+			// Clean, "transform" to io.Reader compatible interface and send logs to parsers
+			out = cleanAnsibleOutput(out)
+			r := ioutil.NopCloser(bytes.NewReader(out))
+
+			parser <- parsers.Input{
+				TestStartTime:  plannedTime,
+				TestTime:       testTime,
+				Round:          round,
+				DataStream:     &r,
+				Tester:         tester,
+				ServerHost:     mainTask.Host.Name,
+				ClientHost:     task.Host.Name,
+				IPFamily:       task.IPFamily,
+				AdditionalInfo: a.additionalInfo,
+				Status:         mainTask.Status,
+			}
+		})
 
-		mainTask.Status.AddSuccessfulServer(mainTask.Host)
-		mainTaskStopped = true
 	} else {
+		stopMainTask()
+		// The test has been aborted while waiting for the main task
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		err := fmt.Errorf("ansible main test task is not running")
 		mainTask.Status.AddFailedServer(mainTask.Host, err)
 		return err
 	}
 
 	logger.Info("stopping main task")
-	mainCancel()
-	mainWG.Wait()
+	stopMainTask()
+
+	// The test has been aborted, the sub tasks might not all have been run
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mainTask.Status.AddSuccessfulServer(mainTask.Host)
 
 	logger.Debug("done running tasks for test in ansible for plan")
 
 	return nil
 }
 
-// Cleanup remove all (left behind) Ansible resources created for the given Plan.
-func (a *Ansible) Cleanup(plan *testers.Plan) error {
-	// Nothing to do here for Ansible (yet)
+// addPIDFile add the PID file of a started main task on the host
+func (a *Ansible) addPIDFile(host string, pidFile string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.pidFiles == nil {
+		a.pidFiles = map[string]map[string]struct{}{}
+	}
+	if _, ok := a.pidFiles[host]; !ok {
+		a.pidFiles[host] = map[string]struct{}{}
+	}
+	a.pidFiles[host][pidFile] = struct{}{}
+}
+
+// killMainTask kill the process group of the main task on the host and remove its PID file
+func (a *Ansible) killMainTask(ctx context.Context, host string, pidFile string) error {
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeouts.CommandTimeout)
+	defer cancel()
+
+	// The process group might have already exited, so only the removal of the PID file decides the exit code
+	if err := a.executor.ExecuteCommand(ctx, "runner:ansible: kill main task", a.config.AnsibleCommand, []string{
+		fmt.Sprintf("--inventory=%s", a.config.InventoryFilePath),
+		host,
+		"--module-name=shell",
+		fmt.Sprintf(`--args=test -f %[1]s && kill -TERM -"$(cat %[1]s)"; rm -f %[1]s`, pidFile),
+	}...); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	delete(a.pidFiles[host], pidFile)
+	if len(a.pidFiles[host]) == 0 {
+		delete(a.pidFiles, host)
+	}
+	a.lock.Unlock()
+
+	return nil
+}
+
+// Cleanup kill all (left behind) main tasks on the hosts, e.g., when the test has been aborted.
+func (a *Ansible) Cleanup(ctx context.Context, plan *testers.Plan) error {
+	a.lock.Lock()
+	pidFiles := map[string][]string{}
+	for host, files := range a.pidFiles {
+		for pidFile := range files {
+			pidFiles[host] = append(pidFiles[host], pidFile)
+		}
+	}
+	a.lock.Unlock()
+
+	var errs []string
+	for host, files := range pidFiles {
+		for _, pidFile := range files {
+			if err := a.killMainTask(ctx, host, pidFile); err != nil {
+				errs = append(errs, fmt.Sprintf("%s (%s): %+v", host, pidFile, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to kill main tasks on hosts. %s", strings.Join(errs, ", "))
+	}
+
 	return nil
 }
 
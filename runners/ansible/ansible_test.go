@@ -92,16 +92,65 @@ func TestGetHostsForTest(t *testing.T) {
 		InventoryFilePath: "/tmp/test-ancientt-ansible-inventory",
 	}
 	conf.SetDefaults()
-	a := Ansible{
+	a := &Ansible{
 		logger:   zap.NewNop().With(zap.String("runner", Name)),
 		config:   conf,
 		executor: mockexec,
 	}
 	require.NotNil(t, a)
 
-	hosts, err := a.GetHostsForTest(&config.Test{})
+	hosts, err := a.GetHostsForTest(context.Background(), &config.Test{})
 	require.Nil(t, err)
 
 	assert.Equal(t, 2, len(hosts.Clients))
 	assert.Equal(t, 1, len(hosts.Servers))
 }
+
+func TestCleanup(t *testing.T) {
+	var lock sync.Mutex
+	killed := map[string][]string{}
+	failHost := ""
+	mockexec := &exectest.MockExecutor{
+		Logger: zap.NewNop(),
+		MockExecuteCommand: func(ctx context.Context, actionName string, command string, arg ...string) error {
+			require.Len(t, arg, 4)
+			if arg[1] == failHost {
+				return fmt.Errorf("host %s unreachable", arg[1])
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			killed[arg[1]] = append(killed[arg[1]], arg[3])
+			return nil
+		},
+	}
+
+	conf := &config.RunnerAnsible{
+		InventoryFilePath: "/tmp/test-ancientt-ansible-inventory",
+	}
+	conf.SetDefaults()
+	a := &Ansible{
+		logger:   zap.NewNop().With(zap.String("runner", Name)),
+		config:   conf,
+		executor: mockexec,
+	}
+
+	// Nothing to clean up
+	require.Nil(t, a.Cleanup(context.Background(), nil))
+	assert.Empty(t, killed)
+
+	a.addPIDFile("server1", "/tmp/ancientt-iperf3-0.pid")
+	a.addPIDFile("server2", "/tmp/ancientt-iperf3-0.pid")
+
+	// Main tasks which can't be killed are kept for the next try
+	failHost = "server2"
+	assert.NotNil(t, a.Cleanup(context.Background(), nil))
+	assert.Equal(t, map[string][]string{
+		"server1": {`--args=test -f /tmp/ancientt-iperf3-0.pid && kill -TERM -"$(cat /tmp/ancientt-iperf3-0.pid)"; rm -f /tmp/ancientt-iperf3-0.pid`},
+	}, killed)
+	assert.Len(t, a.pidFiles, 1)
+
+	failHost = ""
+	require.Nil(t, a.Cleanup(context.Background(), nil))
+	assert.Len(t, killed["server2"], 1)
+	assert.Empty(t, a.pidFiles)
+}
@@ -16,6 +16,7 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -54,6 +55,12 @@ type Kubernetes struct {
 func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
 	conf := cfg.Runner.Kubernetes
 
+	switch conf.ServiceMode {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort:
+	default:
+		return nil, fmt.Errorf("unsupported kubernetes service mode %q given", conf.ServiceMode)
+	}
+
 	clientset, err := k8sutil.NewClient(cfg.Runner.Kubernetes.InClusterConfig, cfg.Runner.Kubernetes.Kubeconfig)
 	if err != nil {
 		return nil, err
@@ -67,13 +74,13 @@ func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
 }
 
 // GetHostsForTest return a mocked list of hots for the given test config
-func (k *Kubernetes) GetHostsForTest(test *config.Test) (*testers.Hosts, error) {
+func (k *Kubernetes) GetHostsForTest(ctx context.Context, test *config.Test) (*testers.Hosts, error) {
 	hosts := &testers.Hosts{
 		Clients: map[string]*testers.Host{},
 		Servers: map[string]*testers.Host{},
 	}
 
-	k8sNodes, err := k.k8sNodesToHosts()
+	k8sNodes, err := k.k8sNodesToHosts(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -109,9 +116,8 @@ func (k *Kubernetes) GetHostsForTest(test *config.Test) (*testers.Hosts, error)
 	return hosts, nil
 }
 
-func (k *Kubernetes) k8sNodesToHosts() ([]*testers.Host, error) {
+func (k *Kubernetes) k8sNodesToHosts(ctx context.Context) ([]*testers.Host, error) {
 	hosts := []*testers.Host{}
-	ctx := context.TODO()
 	nodes, err := k.k8sclient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -145,10 +151,10 @@ func (k *Kubernetes) k8sNodesToHosts() ([]*testers.Host, error) {
 }
 
 // Prepare prepare Kubernetes for usage with ancientt, e.g., create Namespace.
-func (k *Kubernetes) Prepare(runOpts config.RunOptions, plan *testers.Plan) error {
+func (k *Kubernetes) Prepare(ctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error {
 	k.runOptions = runOpts
 
-	if err := k.prepareKubernetes(); err != nil {
+	if err := k.prepareKubernetes(ctx); err != nil {
 		return err
 	}
 
@@ -156,23 +162,26 @@ func (k *Kubernetes) Prepare(runOpts config.RunOptions, plan *testers.Plan) erro
 }
 
 // Execute run the given commands and return the logs of it and / or error
-func (k *Kubernetes) Execute(plan *testers.Plan, parser chan<- parsers.Input) error {
-	// TODO Add option to go through Service IPs instead of Pod IPs
-
+func (k *Kubernetes) Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error {
 	// Iterate over given plan.Commands to then run each task
 	for round, tasks := range plan.Commands {
 		k.logger.Info(fmt.Sprintf("running commands round %d of %d", round+1, len(plan.Commands)))
 		for i, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			if task.Sleep != 0 {
 				k.logger.Info(fmt.Sprintf("waiting %s to pass before continuing next round", task.Sleep.String()))
-				time.Sleep(task.Sleep)
+				if err := util.Sleep(ctx, task.Sleep); err != nil {
+					return err
+				}
 				continue
 			}
 			k.logger.Info(fmt.Sprintf("running task round %d of %d", i+1, len(tasks)))
 
 			// Create the Pods for the server task and client tasks
-			if err := k.createPodsForTasks(round, task, plan, parser); err != nil {
-				if !*plan.RunOptions.ContinueOnError {
+			if err := k.createPodsForTasks(ctx, round, task, plan, parser); err != nil {
+				if !*plan.RunOptions.ContinueOnError || ctx.Err() != nil {
 					return err
 				}
 				k.logger.Warn("continuing after err", zap.Error(err))
@@ -184,9 +193,8 @@ func (k *Kubernetes) Execute(plan *testers.Plan, parser chan<- parsers.Input) er
 }
 
 // prepareKubernetes prepares Kubernetes by creating the namespace if it does not exist
-func (k *Kubernetes) prepareKubernetes() error {
+func (k *Kubernetes) prepareKubernetes(ctx context.Context) error {
 	// Check if namespaces exists, if not try create it
-	ctx := context.TODO()
 	if _, err := k.k8sclient.CoreV1().Namespaces().Get(ctx, k.config.Namespace, metav1.GetOptions{}); err != nil {
 		// If namespace not found, create it
 		if errors.IsNotFound(err) {
@@ -199,7 +207,6 @@ func (k *Kubernetes) prepareKubernetes() error {
 				},
 			}
 			k.logger.Info("trying to create namespace")
-			ctx := context.TODO()
 			if _, err := k.k8sclient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
 				return fmt.Errorf("failed to create namespace %s. %w", k.config.Namespace, err)
 			}
@@ -212,11 +219,9 @@ func (k *Kubernetes) prepareKubernetes() error {
 }
 
 // createPodsForTasks create the Pods that are needed for the task(s)
-func (k *Kubernetes) createPodsForTasks(round int, mainTask *testers.Task, plan *testers.Plan, parser chan<- parsers.Input) error {
+func (k *Kubernetes) createPodsForTasks(ctx context.Context, round int, mainTask *testers.Task, plan *testers.Plan, parser chan<- parsers.Input) error {
 	logger := k.logger.With(zap.Int("round", round))
 
-	var wg sync.WaitGroup
-
 	taskName := util.GetTaskName(plan.Tester, plan.TestStartTime)
 
 	// Create server Pod first
@@ -238,14 +243,14 @@ func (k *Kubernetes) createPodsForTasks(round int, mainTask *testers.Task, plan
 
 	logger = logger.With(zap.String("pod", serverPodName))
 	logger.Debug("(re)creating server pod")
-	if err := k8sutil.PodRecreate(k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
+	if err := k8sutil.PodRecreate(ctx, k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
 		logger.Error(fmt.Sprintf("failed to create server pod %s/%s", k.config.Namespace, serverPodName), zap.Error(err))
 		mainTask.Status.AddFailedServer(mainTask.Host, err)
 		return nil
 	}
 
 	logger.Info("waiting for server pod to run")
-	running, err := k8sutil.WaitForPodToRun(k.k8sclient, k.config.Namespace, serverPodName, k.config.Timeouts.RunningTimeout)
+	running, err := k8sutil.WaitForPodToRun(ctx, k.k8sclient, k.config.Namespace, serverPodName, k.config.Timeouts.RunningTimeout)
 	if err != nil {
 		logger.Error(fmt.Sprintf("failed to wait for server pod %s/%s", k.config.Namespace, serverPodName), zap.Error(err))
 		mainTask.Status.AddFailedServer(mainTask.Host, err)
@@ -258,7 +263,6 @@ func (k *Kubernetes) createPodsForTasks(round int, mainTask *testers.Task, plan
 	}
 
 	// Get server Pod to have the server IP for each client task
-	ctx := context.TODO()
 	pod, err = k.k8sclient.CoreV1().Pods(k.config.Namespace).Get(ctx, serverPodName, metav1.GetOptions{})
 	if err != nil {
 		logger.Error(fmt.Sprintf("failed to get server pod %s/%s", k.config.Namespace, serverPodName), zap.Error(err))
@@ -271,80 +275,108 @@ func (k *Kubernetes) createPodsForTasks(round int, mainTask *testers.Task, plan
 		return nil
 	}
 
-	templateVars.ServerAddressV4 = pod.Status.PodIP
-
-	for i, task := range mainTask.SubTasks {
-		logger.Info(fmt.Sprintf("running sub task %d of %d", i+1, len(mainTask.SubTasks)))
-
-		wg.Add(1)
-		go func(task *testers.Task) {
-			defer wg.Done()
+	// Dual-stack Pods have an IP per family in the PodIPs list
+	podIPs := []string{pod.Status.PodIP}
+	for _, podIP := range pod.Status.PodIPs {
+		podIPs = append(podIPs, podIP.IP)
+	}
+	setServerAddresses(&templateVars, podIPs)
+
+	// Create a Service for the server Pod to test the Service path instead of the Pod IP
+	if k.config.ServiceMode != "" {
+		logger.Debug("(re)creating server service")
+		if err := k.createServiceForServer(ctx, serverPodName, taskName, pod, mainTask, &templateVars); err != nil {
+			logger.Error(fmt.Sprintf("failed to create server service %s/%s", k.config.Namespace, serverPodName), zap.Error(err))
+			mainTask.Status.AddFailedServer(mainTask.Host, err)
+			return nil
+		}
 
-			testTime := time.Now()
+		// The clients can only reach the server through the Service once the server Pod is a ready endpoint of it
+		logger.Debug("waiting for server service endpoints to be ready")
+		ready, err := k8sutil.WaitForServiceEndpoints(ctx, k.k8sclient, k.config.Namespace, serverPodName, k.config.Timeouts.RunningTimeout)
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to wait for server service %s/%s endpoints", k.config.Namespace, serverPodName), zap.Error(err))
+			mainTask.Status.AddFailedServer(mainTask.Host, err)
+			return nil
+		}
+		if !ready {
+			err = fmt.Errorf("server service %s/%s has no ready endpoint after runTimeout", k.config.Namespace, serverPodName)
+			logger.Error(err.Error())
+			mainTask.Status.AddFailedServer(mainTask.Host, err)
+			return nil
+		}
+	}
 
-			pName := util.GetPNameFromTask(round, task.Host.Name, task.Command, util.PNameRoleClient, plan.TestStartTime)
-			logger := logger.With(zap.String("pod", pName))
+	runners.RunSubTasks(ctx, k.runOptions, mainTask.SubTasks, func(i int, task *testers.Task) {
+		logger.Info(fmt.Sprintf("running sub task %d of %d", i+1, len(mainTask.SubTasks)))
 
-			// Template command and args for each task
-			if err := cmdtemplate.Template(task, templateVars); err != nil {
-				k.logger.Error("failed to template task command and / or args", zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
+		testTime := time.Now()
 
-			pod = k.getPodSpec(pName, taskName, task)
-			k.applyServiceAccountToPod(pod, clientsRole)
+		// The IP family is part of the name, as there can be a client task per IP family for the same host
+		pName := util.GetPNameFromTask(round, fmt.Sprintf("%s-%s", task.Host.Name, task.IPFamily), task.Command, util.PNameRoleClient, plan.TestStartTime)
+		logger := logger.With(zap.String("pod", pName))
 
-			logger.Debug("(re)creating client pod")
-			if err := k8sutil.PodRecreate(k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
-				k.logger.Error(fmt.Sprintf("failed to create pod %s/%s", k.config.Namespace, pName), zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
+		// Template command and args for each task
+		if err := cmdtemplate.Template(task, templateVars); err != nil {
+			k.logger.Error("failed to template task command and / or args", zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+			return
+		}
 
-			logger.Info("waiting for client pod to run or succeed")
-			running, err := k8sutil.WaitForPodToRunOrSucceed(k.k8sclient, k.config.Namespace, pName, k.config.Timeouts.RunningTimeout)
-			if err != nil {
-				k.logger.Error(fmt.Sprintf("failed to wait for pod %s/%s", k.config.Namespace, pName), zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
-			if !running {
-				k.logger.Error(fmt.Sprintf("pod %s/%s not running after runTimeout", k.config.Namespace, pName), zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
+		pod := k.getPodSpec(pName, taskName, task)
+		k.applyServiceAccountToPod(pod, clientsRole)
 
-			logger.Debug("about to pushLogsToParser")
-			if err := k.pushLogsToParser(parser, plan.TestStartTime, testTime, round, plan.Tester, mainTask.Host.Name, task.Host.Name, pName); err != nil {
-				k.logger.Error(fmt.Sprintf("failed to push pod %s/%s logs to parser", k.config.Namespace, pName), zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
+		logger.Debug("(re)creating client pod")
+		if err := k8sutil.PodRecreate(ctx, k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
+			k.logger.Error(fmt.Sprintf("failed to create pod %s/%s", k.config.Namespace, pName), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+			return
+		}
 
-			logger.Info("deleting client pod")
-			if err := k8sutil.PodDelete(k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
-				logger.Error(fmt.Sprintf("failed to delete client pod %s/%s", k.config.Namespace, pName), zap.Error(err))
-				mainTask.Status.AddFailedClient(task.Host, err)
-				return
-			}
+		logger.Info("waiting for client pod to run or succeed")
+		running, err := k8sutil.WaitForPodToRunOrSucceed(ctx, k.k8sclient, k.config.Namespace, pName, k.config.Timeouts.RunningTimeout)
+		if err != nil {
+			k.logger.Error(fmt.Sprintf("failed to wait for pod %s/%s", k.config.Namespace, pName), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+			return
+		}
+		if !running {
+			k.logger.Error(fmt.Sprintf("pod %s/%s not running after runTimeout", k.config.Namespace, pName), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+			return
+		}
 
-			mainTask.Status.AddSuccessfulClient(task.Host)
-		}(task)
+		logger.Debug("about to pushLogsToParser")
+		if err := k.pushLogsToParser(ctx, parser, plan.TestStartTime, testTime, round, plan.Tester, mainTask.Host.Name, task.Host.Name, task.IPFamily, pName, mainTask.Status); err != nil {
+			k.logger.Error(fmt.Sprintf("failed to push pod %s/%s logs to parser", k.config.Namespace, pName), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+			return
+		}
 
-		if k.runOptions.Mode != config.RunModeParallel {
-			wg.Wait()
+		// The result of the client is reported by the parser, a left behind client pod is removed by the Cleanup
+		logger.Info("deleting client pod")
+		if err := k8sutil.PodDelete(ctx, k.k8sclient, pod, k.config.Timeouts.DeleteTimeout); err != nil {
+			logger.Error(fmt.Sprintf("failed to delete client pod %s/%s", k.config.Namespace, pName), zap.Error(err))
 		}
+	})
+
+	// The test has been aborted, the left behind Pods and Services are removed by the Cleanup
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	// When RunOptions.Mode `parallel` then we wait after all test tasks have been run
-	if k.runOptions.Mode == config.RunModeParallel {
-		wg.Wait()
+	// Delete server service and pod
+	if k.config.ServiceMode != "" {
+		logger.Info("deleting server service")
+		if err := k8sutil.ServiceDeleteByName(ctx, k.k8sclient, k.config.Namespace, serverPodName); err != nil {
+			logger.Error("failed to delete server service", zap.Error(err))
+			mainTask.Status.AddFailedServer(mainTask.Host, err)
+			return nil
+		}
 	}
 
-	// Delete server pod
 	logger.Info("deleting server pod")
-	if err := k8sutil.PodDeleteByName(k.k8sclient, k.config.Namespace, serverPodName, k.config.Timeouts.DeleteTimeout); err != nil {
+	if err := k8sutil.PodDeleteByName(ctx, k.k8sclient, k.config.Namespace, serverPodName, k.config.Timeouts.DeleteTimeout); err != nil {
 		logger.Error("failed to delete server pod", zap.Error(err))
 		mainTask.Status.AddFailedServer(mainTask.Host, err)
 		return nil
@@ -357,9 +389,101 @@ func (k *Kubernetes) createPodsForTasks(round int, mainTask *testers.Task, plan
 	return nil
 }
 
-func (k *Kubernetes) pushLogsToParser(parserInput chan<- parsers.Input, plannedTime time.Time, testTime time.Time, round int, tester string, serverHost string, clientHost string, podName string) error {
+// createServiceForServer create the Service for the server Pod and set the Service address (and port) in the template variables
+func (k *Kubernetes) createServiceForServer(ctx context.Context, serviceName string, taskName string, pod *corev1.Pod, mainTask *testers.Task, templateVars *cmdtemplate.Variables) error {
+	service := k.getServiceSpec(serviceName, taskName, pod, mainTask)
+	if len(service.Spec.Ports) == 0 {
+		return fmt.Errorf("no ports for server service %s/%s in task", k.config.Namespace, serviceName)
+	}
+
+	service, err := k8sutil.ServiceRecreate(ctx, k.k8sclient, service)
+	if err != nil {
+		return err
+	}
+
+	if service.Spec.Type == corev1.ServiceTypeNodePort {
+		service, err = k.alignNodePorts(ctx, service)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch service.Spec.Type {
+	case corev1.ServiceTypeNodePort:
+		// The server is reached through the node the server Pod is running on
+		if pod.Status.HostIP == "" {
+			return fmt.Errorf("failed to get server pod %s/%s host IP, got '%s'", k.config.Namespace, pod.ObjectMeta.Name, pod.Status.HostIP)
+		}
+		if service.Spec.Ports[0].NodePort == 0 {
+			return fmt.Errorf("no node port allocated for server service %s/%s", k.config.Namespace, serviceName)
+		}
+		hostIPs := []string{pod.Status.HostIP}
+		for _, hostIP := range pod.Status.HostIPs {
+			hostIPs = append(hostIPs, hostIP.IP)
+		}
+		setServerAddresses(templateVars, hostIPs)
+		templateVars.ServerPort = service.Spec.Ports[0].NodePort
+	default:
+		if service.Spec.ClusterIP == "" || service.Spec.ClusterIP == corev1.ClusterIPNone {
+			return fmt.Errorf("failed to get server service %s/%s cluster IP, got '%s'", k.config.Namespace, serviceName, service.Spec.ClusterIP)
+		}
+		setServerAddresses(templateVars, append([]string{service.Spec.ClusterIP}, service.Spec.ClusterIPs...))
+	}
+
+	return nil
+}
+
+// alignNodePorts use the same node port for TCP and UDP ports with the same port number, as, e.g., iperf3 in UDP
+// mode uses the same port for its TCP control and UDP data connection
+func (k *Kubernetes) alignNodePorts(ctx context.Context, service *corev1.Service) (*corev1.Service, error) {
+	tcpNodePorts := map[int32]int32{}
+	for _, port := range service.Spec.Ports {
+		if port.Protocol == corev1.ProtocolTCP {
+			tcpNodePorts[port.Port] = port.NodePort
+		}
+	}
+
+	changed := false
+	for i, port := range service.Spec.Ports {
+		if port.Protocol != corev1.ProtocolUDP {
+			continue
+		}
+		if nodePort, ok := tcpNodePorts[port.Port]; ok && nodePort != port.NodePort {
+			service.Spec.Ports[i].NodePort = nodePort
+			changed = true
+		}
+	}
+
+	if !changed {
+		return service, nil
+	}
+
+	return k.k8sclient.CoreV1().Services(service.ObjectMeta.Namespace).Update(ctx, service, metav1.UpdateOptions{})
+}
+
+// setServerAddresses set the first IPv4 and IPv6 address of the list as server addresses, the addresses of the other
+// family are reset as they belong to a different address type (e.g., Pod IP instead of Service IP)
+func setServerAddresses(templateVars *cmdtemplate.Variables, addresses []string) {
+	templateVars.ServerAddressV4 = ""
+	templateVars.ServerAddressV6 = ""
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			if templateVars.ServerAddressV4 == "" {
+				templateVars.ServerAddressV4 = address
+			}
+		} else if templateVars.ServerAddressV6 == "" {
+			templateVars.ServerAddressV6 = address
+		}
+	}
+}
+
+func (k *Kubernetes) pushLogsToParser(ctx context.Context, parserInput chan<- parsers.Input, plannedTime time.Time, testTime time.Time, round int, tester string, serverHost string, clientHost string, ipFamily config.IPFamily, podName string, status *testers.Status) error {
 	// Wait for the Pod to succeed because that is the "sign" that the test for that Pod is done.
-	succeeded, err := k8sutil.WaitForPodToSucceed(k.k8sclient, k.config.Namespace, podName, k.config.Timeouts.SucceedTimeout)
+	succeeded, err := k8sutil.WaitForPodToSucceed(ctx, k.k8sclient, k.config.Namespace, podName, k.config.Timeouts.SucceedTimeout)
 	if err != nil {
 		return err
 	}
@@ -369,7 +493,6 @@ func (k *Kubernetes) pushLogsToParser(parserInput chan<- parsers.Input, plannedT
 		req := k.k8sclient.CoreV1().Pods(k.config.Namespace).GetLogs(podName, &corev1.PodLogOptions{})
 
 		// Start the log stream
-		ctx := context.TODO()
 		podLogs, err := req.Stream(ctx)
 		if err != nil {
 			return err
@@ -385,7 +508,9 @@ func (k *Kubernetes) pushLogsToParser(parserInput chan<- parsers.Input, plannedT
 			Tester:         tester,
 			ServerHost:     serverHost,
 			ClientHost:     clientHost,
+			IPFamily:       ipFamily,
 			AdditionalInfo: podName,
+			Status:         status,
 		}
 		return nil
 	}
@@ -394,16 +519,24 @@ func (k *Kubernetes) pushLogsToParser(parserInput chan<- parsers.Input, plannedT
 }
 
 // Cleanup remove all (left behind) Kubernetes resources created for the given Plan.
-func (k *Kubernetes) Cleanup(plan *testers.Plan) error {
+func (k *Kubernetes) Cleanup(ctx context.Context, plan *testers.Plan) error {
 	var wg sync.WaitGroup
 
 	// Delete all Pods with label XYZ
-	if err := k8sutil.PodDeleteByLabels(k.k8sclient, k.config.Namespace, map[string]string{
+	if err := k8sutil.PodDeleteByLabels(ctx, k.k8sclient, k.config.Namespace, map[string]string{
 		k8sutil.TaskIDLabel: util.GetTaskName(plan.Tester, plan.TestStartTime),
 	}); err != nil {
 		k.logger.Error("error during pod delete by labels in cleanup", zap.Error(err))
 		return err
 	}
+
+	// Delete all Services with label XYZ
+	if err := k8sutil.ServiceDeleteByLabels(ctx, k.k8sclient, k.config.Namespace, map[string]string{
+		k8sutil.TaskIDLabel: util.GetTaskName(plan.Tester, plan.TestStartTime),
+	}); err != nil {
+		k.logger.Error("error during service delete by labels in cleanup", zap.Error(err))
+		return err
+	}
 	wg.Wait()
 
 	return nil
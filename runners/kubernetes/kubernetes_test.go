@@ -14,15 +14,25 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/galexrt/ancientt/pkg/cmdtemplate"
 	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/k8sutil"
 	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/testers"
 	"github.com/galexrt/ancientt/tests/k8s"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // TODO add tests
@@ -44,21 +54,163 @@ func TestGetHostsForTest(t *testing.T) {
 	}
 
 	test := &config.Test{}
-	hosts, err := runner.GetHostsForTest(test)
+	hosts, err := runner.GetHostsForTest(context.Background(), test)
 	require.Nil(t, err)
 	assert.Equal(t, 0, len(hosts.Servers))
 	assert.Equal(t, 0, len(hosts.Clients))
 
 	test.Hosts.Servers = append(test.Hosts.Servers, config.Hosts{All: util.BoolTruePointer()})
 	test.Hosts.Clients = append(test.Hosts.Clients, config.Hosts{All: util.BoolTruePointer()})
-	hosts, err = runner.GetHostsForTest(test)
+	hosts, err = runner.GetHostsForTest(context.Background(), test)
 	require.Nil(t, err)
 	assert.Equal(t, 3, len(hosts.Servers))
 	assert.Equal(t, 3, len(hosts.Clients))
 
 	test.Hosts.Servers[0] = config.Hosts{Count: 1, Random: util.BoolTruePointer()}
-	hosts, err = runner.GetHostsForTest(test)
+	hosts, err = runner.GetHostsForTest(context.Background(), test)
 	require.Nil(t, err)
 	assert.Equal(t, 1, len(hosts.Servers))
 	assert.Equal(t, 3, len(hosts.Clients))
 }
+
+func TestCreateServiceForServer(t *testing.T) {
+	clientset, err := k8s.NewClient(1)
+	require.Nil(t, err)
+
+	// The fake clientset doesn't allocate cluster IPs and node ports, so do it on Service creation
+	nodePort := int32(30000)
+	clientset.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		service := action.(k8stesting.CreateAction).GetObject().(*corev1.Service)
+		service.Spec.ClusterIP = "10.96.0.10"
+		if service.Spec.Type == corev1.ServiceTypeNodePort {
+			for i := range service.Spec.Ports {
+				nodePort++
+				service.Spec.Ports[i].NodePort = nodePort
+			}
+		}
+		return false, nil, nil
+	})
+
+	conf := &config.RunnerKubernetes{
+		Hosts: &config.KubernetesHosts{},
+	}
+	require.Nil(t, defaults.Set(conf))
+
+	runner := &Kubernetes{
+		logger:    zap.NewNop(),
+		config:    conf,
+		k8sclient: clientset,
+	}
+
+	taskName := util.GetTaskName("iperf3", time.Unix(0, 0))
+	pod := runner.getPodSpec("server-pod", taskName, &testers.Task{
+		Host: &testers.Host{Name: "node-0"},
+	})
+	pod.Status.HostIP = "192.0.2.1"
+	task := &testers.Task{
+		Ports: testers.Ports{
+			TCP: []int32{5601},
+			UDP: []int32{5601},
+		},
+	}
+
+	// ClusterIP
+	conf.ServiceMode = corev1.ServiceTypeClusterIP
+	vars := cmdtemplate.Variables{ServerAddressV4: "10.244.0.5", ServerPort: 5601}
+	require.NoError(t, runner.createServiceForServer(context.Background(), "server-svc", taskName, pod, task, &vars))
+	assert.Equal(t, "10.96.0.10", vars.ServerAddressV4)
+	assert.Equal(t, int32(5601), vars.ServerPort)
+
+	service, err := clientset.CoreV1().Services(conf.Namespace).Get(context.TODO(), "server-svc", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, pod.ObjectMeta.Labels, service.Spec.Selector)
+	assert.Equal(t, taskName, service.ObjectMeta.Labels[k8sutil.TaskIDLabel])
+	assert.Len(t, service.Spec.Ports, 2)
+
+	// NodePort, the UDP node port must be the same as the TCP node port
+	conf.ServiceMode = corev1.ServiceTypeNodePort
+	vars = cmdtemplate.Variables{ServerAddressV4: "10.244.0.5", ServerPort: 5601}
+	require.NoError(t, runner.createServiceForServer(context.Background(), "server-svc", taskName, pod, task, &vars))
+	assert.Equal(t, "192.0.2.1", vars.ServerAddressV4)
+	assert.Equal(t, int32(30001), vars.ServerPort)
+
+	service, err = clientset.CoreV1().Services(conf.Namespace).Get(context.TODO(), "server-svc", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, service.Spec.Ports, 2)
+	assert.Equal(t, service.Spec.Ports[0].NodePort, service.Spec.Ports[1].NodePort)
+
+	// Services are removed in the cleanup
+	require.NoError(t, runner.Cleanup(context.Background(), &testers.Plan{
+		Tester:        "iperf3",
+		TestStartTime: time.Unix(0, 0),
+	}))
+	services, err := clientset.CoreV1().Services(conf.Namespace).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, services.Items)
+}
+
+func TestWaitForServiceEndpoints(t *testing.T) {
+	clientset, err := k8s.NewClient(1)
+	require.Nil(t, err)
+
+	// No EndpointSlice for the Service
+	ready, err := k8sutil.WaitForServiceEndpoints(context.Background(), clientset, "ancientt", "server-svc", 1)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	notReady := false
+	endpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "server-svc-abcde",
+			Namespace: "ancientt",
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: "server-svc",
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{
+				Addresses: []string{"10.244.0.5"},
+				Conditions: discoveryv1.EndpointConditions{
+					Ready: &notReady,
+				},
+			},
+		},
+	}
+	endpointSlice, err = clientset.DiscoveryV1().EndpointSlices("ancientt").Create(context.TODO(), endpointSlice, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// The server Pod is not ready yet
+	ready, err = k8sutil.WaitForServiceEndpoints(context.Background(), clientset, "ancientt", "server-svc", 1)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	endpointSlice.Endpoints[0].Conditions.Ready = util.BoolTruePointer()
+	_, err = clientset.DiscoveryV1().EndpointSlices("ancientt").Update(context.TODO(), endpointSlice, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	ready, err = k8sutil.WaitForServiceEndpoints(context.Background(), clientset, "ancientt", "server-svc", 1)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	// EndpointSlices of other Services must be ignored
+	ready, err = k8sutil.WaitForServiceEndpoints(context.Background(), clientset, "ancientt", "other-svc", 1)
+	require.NoError(t, err)
+	assert.False(t, ready)
+}
+
+func TestSetServerAddresses(t *testing.T) {
+	vars := cmdtemplate.Variables{
+		ServerAddressV4: "10.244.0.5",
+		ServerAddressV6: "fd00::5",
+	}
+
+	setServerAddresses(&vars, []string{"10.96.0.10", "10.96.0.10", "fd00:96::10", "invalid"})
+	assert.Equal(t, "10.96.0.10", vars.ServerAddressV4)
+	assert.Equal(t, "fd00:96::10", vars.ServerAddressV6)
+
+	// Single-stack IPv6, the IPv4 address must not be kept from a previous address type
+	setServerAddresses(&vars, []string{"fd00:96::11"})
+	assert.Equal(t, "", vars.ServerAddressV4)
+	assert.Equal(t, "fd00:96::11", vars.ServerAddressV6)
+}
@@ -62,6 +62,26 @@ func (k Kubernetes) getPodSpec(pName string, taskName string, task *testers.Task
 	return pod
 }
 
+func (k Kubernetes) getServiceSpec(sName string, taskName string, pod *corev1.Pod, task *testers.Task) *corev1.Service {
+	preferDualStack := corev1.IPFamilyPolicyPreferDualStack
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: k.config.Annotations,
+			Labels:      k8sutil.GetPodLabels(sName, taskName),
+			Name:        sName,
+			Namespace:   k.config.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: k.config.ServiceMode,
+			// Get a cluster IP per family on dual-stack clusters, single-stack clusters will assign a single cluster IP
+			IPFamilyPolicy: &preferDualStack,
+			Selector:       pod.ObjectMeta.Labels,
+			Ports:          k8sutil.PortsListToServicePorts(task.Ports),
+		},
+	}
+}
+
 func (k Kubernetes) applyServiceAccountToPod(p *corev1.Pod, role string) {
 	if k.config.ServiceAccounts != nil {
 		switch role {
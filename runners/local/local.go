@@ -0,0 +1,382 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/cmdtemplate"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/executor"
+	"github.com/galexrt/ancientt/pkg/hostsfilter"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/runners"
+	"github.com/galexrt/ancientt/testers"
+	"go.uber.org/zap"
+)
+
+const (
+	// Name Local Runner Name
+	Name = "local"
+)
+
+func init() {
+	runners.Factories[Name] = NewRunner
+}
+
+// Local Local runner struct
+type Local struct {
+	runners.Runner
+	logger     *zap.Logger
+	config     *config.RunnerLocal
+	runOptions config.RunOptions
+	executor   executor.Executor
+	hosts      map[string]config.LocalHost
+}
+
+// NewRunner return a new Local Runner
+func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
+	conf := cfg.Runner.Local
+	if conf == nil {
+		conf = &config.RunnerLocal{}
+	}
+	conf.SetDefaults()
+
+	hosts := map[string]config.LocalHost{}
+	for _, host := range conf.Hosts {
+		if _, ok := hosts[host.Name]; ok {
+			return nil, fmt.Errorf("local host %q is configured more than once", host.Name)
+		}
+		// Hosts in a network namespace don't default to the loopback addresses, without an address the server can't be reached
+		if host.Namespace != "" && host.AddressV4 == "" && host.AddressV6 == "" {
+			return nil, fmt.Errorf("local host %q in network namespace %q requires an addressV4 and / or addressV6", host.Name, host.Namespace)
+		}
+		hosts[host.Name] = host
+	}
+
+	return &Local{
+		logger:   logger.With(zap.String("runner", Name)),
+		config:   conf,
+		executor: executor.NewCommandExecutor(logger, "runner:local"),
+		hosts:    hosts,
+	}, nil
+}
+
+// GetHostsForTest return the list of local hosts for the given test config
+func (l *Local) GetHostsForTest(ctx context.Context, test *config.Test) (*testers.Hosts, error) {
+	// Pre create the structure to return
+	hosts := &testers.Hosts{
+		Clients: map[string]*testers.Host{},
+		Servers: map[string]*testers.Host{},
+	}
+
+	localHosts := []*testers.Host{}
+	for _, host := range l.config.Hosts {
+		localHosts = append(localHosts, l.toTestersHost(host))
+	}
+
+	// Go through Hosts Servers list to get the servers hosts
+	for _, servers := range test.Hosts.Servers {
+		filtered, err := l.filterHosts(localHosts, servers)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range filtered {
+			if _, ok := hosts.Servers[host.Name]; !ok {
+				hosts.Servers[host.Name] = host
+			}
+		}
+	}
+
+	// Go through Hosts Clients list to get the clients hosts
+	for _, clients := range test.Hosts.Clients {
+		filtered, err := l.filterHosts(localHosts, clients)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range filtered {
+			if _, ok := hosts.Clients[host.Name]; !ok {
+				hosts.Clients[host.Name] = host
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+// filterHosts filter the local hosts list, statically listed hosts are looked up in the local hosts list
+func (l *Local) filterHosts(localHosts []*testers.Host, filter config.Hosts) ([]*testers.Host, error) {
+	filtered, err := hostsfilter.FilterHostsList(localHosts, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, host := range filtered {
+		localHost, ok := l.hosts[host.Name]
+		if !ok {
+			return nil, fmt.Errorf("host %q not found in local hosts list", host.Name)
+		}
+		filtered[i] = l.toTestersHost(localHost)
+	}
+
+	return filtered, nil
+}
+
+func (l *Local) toTestersHost(host config.LocalHost) *testers.Host {
+	addresses := &testers.IPAddresses{}
+	if host.AddressV4 != "" {
+		addresses.IPv4 = []string{host.AddressV4}
+	}
+	if host.AddressV6 != "" {
+		addresses.IPv6 = []string{host.AddressV6}
+	}
+
+	labels := map[string]string{}
+	for k, v := range host.Labels {
+		labels[k] = v
+	}
+
+	return &testers.Host{
+		Name:      host.Name,
+		Labels:    labels,
+		Addresses: addresses,
+	}
+}
+
+// Prepare prepare Local runner for usage, there is nothing to prepare besides the run options
+func (l *Local) Prepare(ctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error {
+	l.runOptions = runOpts
+	return nil
+}
+
+// Execute run the given commands and return the logs of it and / or error
+func (l *Local) Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error {
+	for round, tasks := range plan.Commands {
+		l.logger.Info(fmt.Sprintf("running commands round %d of %d", round+1, len(plan.Commands)))
+		for i, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if task.Sleep != 0 {
+				l.logger.Info(fmt.Sprintf("waiting %s to pass before continuing next round", task.Sleep.String()))
+				if err := util.Sleep(ctx, task.Sleep); err != nil {
+					return err
+				}
+				continue
+			}
+			l.logger.Info(fmt.Sprintf("running task round %d of %d", i+1, len(tasks)))
+
+			if err := l.runTasks(ctx, round, task, plan.TestStartTime, plan.Tester, parser); err != nil {
+				if plan.RunOptions.ContinueOnError == nil || !*plan.RunOptions.ContinueOnError || ctx.Err() != nil {
+					return err
+				}
+				l.logger.Warn("continuing after err", zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Local) runTasks(ctx context.Context, round int, mainTask *testers.Task, plannedTime time.Time, tester string, parser chan<- parsers.Input) error {
+	logger := l.logger.With(zap.Int("round", round), zap.String("hostname", mainTask.Host.Name))
+
+	// Create initial cmdtemplate.Variables
+	templateVars := cmdtemplate.Variables{
+		ServerPort: 5601,
+	}
+	if len(mainTask.Host.Addresses.IPv4) > 0 {
+		templateVars.ServerAddressV4 = mainTask.Host.Addresses.IPv4[0]
+	}
+	if len(mainTask.Host.Addresses.IPv6) > 0 {
+		templateVars.ServerAddressV6 = mainTask.Host.Addresses.IPv6[0]
+	}
+
+	if err := cmdtemplate.Template(mainTask, templateVars); err != nil {
+		logger.Error("failed to template main task command and / or args", zap.Error(err))
+		mainTask.Status.AddFailedServer(mainTask.Host, err)
+		return err
+	}
+
+	mainCtx, mainCancel := context.WithCancel(ctx)
+	defer mainCancel()
+
+	mainDone := make(chan struct{})
+	go func() {
+		defer close(mainDone)
+		command, args := l.getCommand(mainTask)
+		if err := l.executor.ExecuteCommand(mainCtx, "runner:local: run main task command", command, args...); err != nil {
+			// Ignore any error after the main task is stopped
+			if mainCtx.Err() != nil {
+				logger.Debug("ignored error after main task was stopped", zap.Error(err))
+				return
+			}
+
+			logger.Error("error during main task run", zap.Error(err))
+		}
+	}()
+
+	if err := l.waitForMainTask(ctx, mainTask, templateVars, mainDone); err != nil {
+		mainTask.Status.AddFailedServer(mainTask.Host, err)
+		mainCancel()
+		<-mainDone
+		return err
+	}
+
+	runners.RunSubTasks(ctx, l.runOptions, mainTask.SubTasks, func(i int, task *testers.Task) {
+		logger.Info(fmt.Sprintf("running sub task %d of %d", i+1, len(mainTask.SubTasks)), zap.String("client", task.Host.Name))
+
+		// Template command and args for each task
+		if err := cmdtemplate.Template(task, templateVars); err != nil {
+			erro := fmt.Errorf("failed to template task command and / or args. %+v", err)
+			logger.Error("error during runTasks", zap.String("client", task.Host.Name), zap.Error(erro))
+			mainTask.Status.AddFailedClient(task.Host, erro)
+			return
+		}
+
+		// The result of the client is reported by the parser, once the output has been sent to it
+		if err := l.runSubTask(ctx, round, mainTask, task, plannedTime, tester, parser); err != nil {
+			logger.Error("client task failed", zap.String("client", task.Host.Name), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+		}
+	})
+
+	logger.Info("stopping main task")
+	mainCancel()
+	<-mainDone
+
+	// The test has been aborted, the sub tasks might not all have been run
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mainTask.Status.AddSuccessfulServer(mainTask.Host)
+
+	logger.Debug("done running tasks for test locally for plan")
+
+	return nil
+}
+
+// runSubTask run the client task and stream its stdout to the parser
+func (l *Local) runSubTask(ctx context.Context, round int, mainTask *testers.Task, task *testers.Task, plannedTime time.Time, tester string, parser chan<- parsers.Input) error {
+	ctx, cancel := context.WithTimeout(ctx, l.config.Timeouts.TaskCommandTimeout)
+	defer cancel()
+
+	// The command writes directly into the pipe, the read end of it is handed to the parser
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe for client task. %+v", err)
+	}
+
+	testTime := time.Now()
+
+	cmdErr := make(chan error, 1)
+	go func() {
+		command, args := l.getCommand(task)
+		cmdErr <- l.executor.ExecuteCommandWithStdout(ctx, "runner:local: run sub task command", w, command, args...)
+		// Close the write end so the parser reaches the end of the stream
+		w.Close()
+	}()
+
+	// Don't close the stream here, that is the responsibility of the parser!
+	var stream io.ReadCloser = r
+	parser <- parsers.Input{
+		TestStartTime: plannedTime,
+		TestTime:      testTime,
+		Round:         round,
+		DataStream:    &stream,
+		Tester:        tester,
+		ServerHost:    mainTask.Host.Name,
+		ClientHost:    task.Host.Name,
+		IPFamily:      task.IPFamily,
+		Status:        mainTask.Status,
+	}
+
+	// A failed command results in a failed result in the parser, so it is only logged here
+	if err := <-cmdErr; err != nil {
+		l.logger.Error("client task command failed", zap.String("client", task.Host.Name), zap.Error(err))
+	}
+
+	return nil
+}
+
+// waitForMainTask wait for the main task to be ready. When the main task has TCP ports, they are probed until they
+// accept connections, otherwise it is only checked that the main task has not exited
+func (l *Local) waitForMainTask(ctx context.Context, mainTask *testers.Task, templateVars cmdtemplate.Variables, mainDone <-chan struct{}) error {
+	if err := util.Sleep(ctx, 250*time.Millisecond); err != nil {
+		return err
+	}
+
+	select {
+	case <-mainDone:
+		return fmt.Errorf("local main task exited before becoming ready")
+	default:
+	}
+
+	if len(mainTask.Ports.TCP) == 0 {
+		return nil
+	}
+
+	address := templateVars.ServerAddressV4
+	if address == "" {
+		address = templateVars.ServerAddressV6
+	}
+
+	timeout := time.After(l.config.Timeouts.ServerReadyTimeout)
+	for _, port := range mainTask.Ports.TCP {
+		target := net.JoinHostPort(address, strconv.Itoa(int(port)))
+		for {
+			conn, err := net.DialTimeout("tcp", target, time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-mainDone:
+				return fmt.Errorf("local main task exited before becoming ready")
+			case <-timeout:
+				return fmt.Errorf("local main task not ready after %s, port %s not reachable. %+v", l.config.Timeouts.ServerReadyTimeout, target, err)
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+
+	return nil
+}
+
+// getCommand return the command and args for the task, wrapped in `ip netns exec` if the host is in a network namespace
+func (l *Local) getCommand(task *testers.Task) (string, []string) {
+	host, ok := l.hosts[task.Host.Name]
+	if !ok || host.Namespace == "" {
+		return task.Command, task.Args
+	}
+
+	return l.config.IPCommand, append([]string{"netns", "exec", host.Namespace, task.Command}, task.Args...)
+}
+
+// Cleanup NOOP because all processes are stopped at the end of each task run.
+func (l *Local) Cleanup(ctx context.Context, plan *testers.Plan) error {
+	// Nothing to do here for Local
+	return nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/cmdtemplate"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetHostsForTest(t *testing.T) {
+	cfg := &config.Config{
+		Runner: config.Runner{
+			Local: &config.RunnerLocal{
+				Hosts: []config.LocalHost{
+					{
+						Name: "localhost",
+					},
+					{
+						Name:      "ns1",
+						Namespace: "ns1",
+						AddressV4: "10.0.0.1",
+					},
+				},
+			},
+		},
+	}
+	r, err := NewRunner(zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	hosts, err := r.GetHostsForTest(context.Background(), &config.Test{
+		Hosts: config.TestHosts{
+			Servers: []config.Hosts{
+				{
+					Hosts: []string{"ns1"},
+				},
+			},
+			Clients: []config.Hosts{
+				{
+					All: util.BoolTruePointer(),
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, hosts.Servers, 1)
+	require.Contains(t, hosts.Servers, "ns1")
+	// Statically listed hosts must keep their addresses
+	assert.Equal(t, []string{"10.0.0.1"}, hosts.Servers["ns1"].Addresses.IPv4)
+	assert.Empty(t, hosts.Servers["ns1"].Addresses.IPv6)
+
+	require.Len(t, hosts.Clients, 2)
+	assert.Equal(t, []string{"127.0.0.1"}, hosts.Clients["localhost"].Addresses.IPv4)
+	assert.Equal(t, []string{"::1"}, hosts.Clients["localhost"].Addresses.IPv6)
+
+	_, err = r.GetHostsForTest(context.Background(), &config.Test{
+		Hosts: config.TestHosts{
+			Servers: []config.Hosts{
+				{
+					Hosts: []string{"does-not-exist"},
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewRunnerNamespaceWithoutAddress(t *testing.T) {
+	cfg := &config.Config{
+		Runner: config.Runner{
+			Local: &config.RunnerLocal{
+				Hosts: []config.LocalHost{
+					{
+						Name:      "ns1",
+						Namespace: "ns1",
+					},
+				},
+			},
+		},
+	}
+	_, err := NewRunner(zap.NewNop(), cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires an addressV4 and / or addressV6")
+
+	cfg.Runner.Local.Hosts[0].AddressV6 = "fd00::1"
+	_, err = NewRunner(zap.NewNop(), cfg)
+	require.NoError(t, err)
+}
+
+func TestExecute(t *testing.T) {
+	r, err := NewRunner(zap.NewNop(), &config.Config{})
+	require.NoError(t, err)
+
+	l := r.(*Local)
+	host := l.toTestersHost(l.config.Hosts[0])
+
+	status := testers.NewStatus()
+	plan := &testers.Plan{
+		TestStartTime: time.Now(),
+		Tester:        "mock",
+		RunOptions: config.RunOptions{
+			ContinueOnError: util.BoolTruePointer(),
+		},
+		Commands: [][]*testers.Task{
+			{
+				{
+					Host:    host,
+					Command: "sleep",
+					Args:    []string{"30"},
+					Status:  status,
+					SubTasks: []*testers.Task{
+						{
+							Host:    host,
+							Command: "echo",
+							Args:    []string{"{{ .ServerAddressV4 }}:{{ .ServerPort }}"},
+						},
+						{
+							Host:    host,
+							Command: "false",
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, r.Prepare(context.Background(), plan.RunOptions, plan))
+
+	parserCh := make(chan parsers.Input)
+	outs := make(chan string)
+	go func() {
+		for input := range parserCh {
+			out, err := io.ReadAll(*input.DataStream)
+			assert.NoError(t, err)
+			assert.NoError(t, (*input.DataStream).Close())
+			assert.Equal(t, "localhost", input.ServerHost)
+			assert.Equal(t, status, input.Status)
+			outs <- string(out)
+		}
+		close(outs)
+	}()
+
+	go func() {
+		assert.NoError(t, r.Execute(context.Background(), plan, parserCh))
+		close(parserCh)
+	}()
+
+	results := []string{}
+	for out := range outs {
+		results = append(results, out)
+	}
+
+	assert.Equal(t, []string{"127.0.0.1:5601\n", ""}, results)
+	assert.Equal(t, 1, status.SuccessfulHosts.Servers["localhost"])
+	// The results of the clients are reported by the parser, not by the runner
+	assert.Empty(t, status.SuccessfulHosts.Clients)
+	assert.Empty(t, status.FailedHosts.Clients)
+}
+
+func TestWaitForMainTask(t *testing.T) {
+	r, err := NewRunner(zap.NewNop(), &config.Config{
+		Runner: config.Runner{
+			Local: &config.RunnerLocal{
+				Timeouts: &config.LocalTimeouts{
+					ServerReadyTimeout: time.Second,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	l := r.(*Local)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	task := &testers.Task{
+		Ports: testers.Ports{
+			TCP: []int32{int32(listener.Addr().(*net.TCPAddr).Port)},
+		},
+	}
+	vars := cmdtemplate.Variables{
+		ServerAddressV4: "127.0.0.1",
+	}
+
+	mainDone := make(chan struct{})
+	assert.NoError(t, l.waitForMainTask(context.Background(), task, vars, mainDone))
+
+	listener.Close()
+	assert.Error(t, l.waitForMainTask(context.Background(), task, vars, mainDone))
+
+	close(mainDone)
+	assert.Error(t, l.waitForMainTask(context.Background(), &testers.Task{}, vars, mainDone))
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	r, err := NewRunner(zap.NewNop(), &config.Config{})
+	require.NoError(t, err)
+
+	l := r.(*Local)
+	host := l.toTestersHost(l.config.Hosts[0])
+
+	status := testers.NewStatus()
+	plan := &testers.Plan{
+		TestStartTime: time.Now(),
+		Tester:        "mock",
+		RunOptions: config.RunOptions{
+			ContinueOnError: util.BoolTruePointer(),
+		},
+		Commands: [][]*testers.Task{
+			{
+				{
+					Host:    host,
+					Command: "sleep",
+					Args:    []string{"30"},
+					Status:  status,
+					SubTasks: []*testers.Task{
+						{
+							Host:    host,
+							Command: "sleep",
+							Args:    []string{"30"},
+						},
+					},
+				},
+				{
+					Sleep: time.Minute,
+				},
+			},
+		},
+	}
+	require.NoError(t, r.Prepare(context.Background(), plan.RunOptions, plan))
+
+	parserCh := make(chan parsers.Input)
+	go func() {
+		for input := range parserCh {
+			_, _ = io.ReadAll(*input.DataStream)
+			(*input.DataStream).Close()
+		}
+	}()
+	defer close(parserCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	start := time.Now()
+	assert.ErrorIs(t, r.Execute(ctx, plan, parserCh), context.Canceled)
+	// Neither the running task commands nor the sleep task must be waited for
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, 0, status.SuccessfulHosts.Servers["localhost"])
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package mock
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/galexrt/ancientt/parsers"
@@ -48,7 +49,7 @@ func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
 }
 
 // GetHostsForTest return a mocked list of hots for the given test config
-func (m Mock) GetHostsForTest(test *config.Test) (*testers.Hosts, error) {
+func (m Mock) GetHostsForTest(ctx context.Context, test *config.Test) (*testers.Hosts, error) {
 	// Pre create the structure to return
 	hosts := &testers.Hosts{
 		Clients: map[string]*testers.Host{},
@@ -102,20 +103,20 @@ func generateMockServers() []*testers.Host {
 }
 
 // Prepare NOOP because there is nothing to prepare because this is Mock.
-func (m Mock) Prepare(runOpts config.RunOptions, plan *testers.Plan) error {
+func (m Mock) Prepare(ctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error {
 	m.logger.Info("Mock.Prepare() called")
 	return nil
 }
 
 // Execute run the given testers.Plan and return the logs of each step and / or error
-func (m Mock) Execute(plan *testers.Plan, parser chan<- parsers.Input) error {
+func (m Mock) Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error {
 	m.logger.Info("Mock.Execute() called")
 	// Return nothing because we don't do anything in the Mock
 	return nil
 }
 
 // Cleanup NOOP because Mock doesn't create any resource nor connection or so to any hosts.
-func (m Mock) Cleanup(plan *testers.Plan) error {
+func (m Mock) Cleanup(ctx context.Context, plan *testers.Plan) error {
 	m.logger.Info("Mock.Cleanup() called")
 	// Return nothing because we don't do anything in the Mock
 	return nil
@@ -14,6 +14,9 @@ limitations under the License.
 package runners
 
 import (
+	"context"
+	"sync"
+
 	"github.com/galexrt/ancientt/parsers"
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/testers"
@@ -25,13 +28,59 @@ import (
 var Factories = make(map[string]func(logger *zap.Logger, cfg *config.Config) (Runner, error))
 
 // Runner is the interface a runner has to implement.
+// When the given context is cancelled, e.g., on SIGINT / SIGTERM, the runner must stop the in-flight tasks and return.
 type Runner interface {
 	// GetHostsForTest return a list of hots from the Runner
-	GetHostsForTest(test *config.Test) (*testers.Hosts, error)
+	GetHostsForTest(ctx context.Context, test *config.Test) (*testers.Hosts, error)
 	// Prepare run steps to prepare the Runner and / or itself to things.
-	Prepare(runOpts config.RunOptions, plan *testers.Plan) error
+	Prepare(ctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error
 	// Execute run / execute certain commands and so that are in the testers.Plan
-	Execute(plan *testers.Plan, parser chan<- parsers.Input) error
+	Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error
 	// Cleanup cleanup resources and other things after the commands from the testers.Plan ran.
-	Cleanup(plan *testers.Plan) error
+	// Cleanup is called with a context that is not cancelled by the signals, so it is also run after an abort.
+	Cleanup(ctx context.Context, plan *testers.Plan) error
+}
+
+// RunSubTasks run the given func for each sub task. With RunModeParallel the sub tasks are run concurrently, limited
+// to RunOptions.ParallelCount sub tasks at a time (no limit when `0`), otherwise they are run one after another.
+// No further sub tasks are started once the context is done.
+// Returns after all started sub tasks have been run.
+func RunSubTasks(ctx context.Context, runOpts config.RunOptions, subTasks []*testers.Task, run func(i int, task *testers.Task)) {
+	if runOpts.Mode != config.RunModeParallel {
+		for i, task := range subTasks {
+			if ctx.Err() != nil {
+				return
+			}
+			run(i, task)
+		}
+		return
+	}
+
+	limit := runOpts.ParallelCount
+	if limit <= 0 || limit > len(subTasks) {
+		limit = len(subTasks)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i, task := range subTasks {
+		// Blocks when the limit of concurrently running sub tasks is reached
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, task *testers.Task) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			run(i, task)
+		}(i, task)
+	}
+	wg.Wait()
 }
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runners
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/testers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSubTasks(count int) []*testers.Task {
+	tasks := []*testers.Task{}
+	for i := 0; i < count; i++ {
+		tasks = append(tasks, &testers.Task{})
+	}
+	return tasks
+}
+
+// runAndCountConcurrency run the sub tasks and return the maximum of concurrently running sub tasks
+func runAndCountConcurrency(runOpts config.RunOptions, subTasks []*testers.Task) (int32, []int) {
+	var running, max int32
+	var lock sync.Mutex
+	order := []int{}
+
+	RunSubTasks(context.Background(), runOpts, subTasks, func(i int, task *testers.Task) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&max)
+			if current <= old || atomic.CompareAndSwapInt32(&max, old, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		order = append(order, i)
+		lock.Unlock()
+		atomic.AddInt32(&running, -1)
+	})
+
+	return max, order
+}
+
+func TestRunSubTasksSequential(t *testing.T) {
+	max, order := runAndCountConcurrency(config.RunOptions{
+		Mode:          config.RunModeSequential,
+		ParallelCount: 5,
+	}, newSubTasks(4))
+
+	assert.Equal(t, int32(1), max)
+	assert.Equal(t, []int{0, 1, 2, 3}, order)
+}
+
+func TestRunSubTasksParallelCount(t *testing.T) {
+	max, order := runAndCountConcurrency(config.RunOptions{
+		Mode:          config.RunModeParallel,
+		ParallelCount: 3,
+	}, newSubTasks(10))
+
+	assert.Equal(t, int32(3), max)
+	assert.Len(t, order, 10)
+}
+
+func TestRunSubTasksParallelUnlimited(t *testing.T) {
+	max, order := runAndCountConcurrency(config.RunOptions{
+		Mode: config.RunModeParallel,
+	}, newSubTasks(6))
+
+	assert.Equal(t, int32(6), max)
+	assert.Len(t, order, 6)
+
+	// No sub tasks must not block
+	max, order = runAndCountConcurrency(config.RunOptions{
+		Mode: config.RunModeParallel,
+	}, newSubTasks(0))
+	assert.Equal(t, int32(0), max)
+	assert.Empty(t, order)
+}
+
+func TestRunSubTasksCancelled(t *testing.T) {
+	for _, mode := range []config.RunMode{config.RunModeSequential, config.RunModeParallel} {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var count int32
+		RunSubTasks(ctx, config.RunOptions{
+			Mode:          mode,
+			ParallelCount: 1,
+		}, newSubTasks(5), func(i int, task *testers.Task) {
+			atomic.AddInt32(&count, 1)
+			// Cancel during the first sub task, no other sub task must be started afterwards
+			cancel()
+		})
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&count), mode)
+	}
+}
@@ -0,0 +1,638 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/cmdtemplate"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/hostsfilter"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/runners"
+	"github.com/galexrt/ancientt/testers"
+)
+
+const (
+	// Name SSH Runner Name
+	Name = "ssh"
+
+	// processGroupWrapper runs the command in a new session (and with that process group) and prints the process
+	// group ID as the first line of stdout, so the command and all its child processes can be killed
+	processGroupWrapper = `exec setsid -w sh -c 'echo "$$"; exec "$0" "$@"'`
+	// killTimeout how long to wait for a killed process to exit before closing its session
+	killTimeout = 5 * time.Second
+)
+
+func init() {
+	runners.Factories[Name] = NewRunner
+}
+
+// SSH SSH runner struct
+type SSH struct {
+	runners.Runner
+	logger          *zap.Logger
+	config          *config.RunnerSSH
+	runOptions      config.RunOptions
+	hosts           map[string]config.SSHHost
+	auth            []ssh.AuthMethod
+	hostKeyCallback ssh.HostKeyCallback
+
+	lock      sync.Mutex
+	clients   map[string]*ssh.Client
+	processes map[string]map[int]struct{}
+}
+
+// process a command started on a host
+type process struct {
+	host    string
+	pgid    int
+	session *ssh.Session
+	stdout  *bufio.Reader
+}
+
+// NewRunner return a new SSH Runner
+func NewRunner(logger *zap.Logger, cfg *config.Config) (runners.Runner, error) {
+	conf := cfg.Runner.SSH
+	if conf == nil {
+		return nil, fmt.Errorf("no ssh runner config given")
+	}
+	conf.SetDefaults()
+
+	hosts := map[string]config.SSHHost{}
+	for _, host := range conf.Hosts {
+		if _, ok := hosts[host.Name]; ok {
+			return nil, fmt.Errorf("ssh host %q is configured more than once", host.Name)
+		}
+		hosts[host.Name] = host
+	}
+
+	auth := []ssh.AuthMethod{}
+	if conf.PrivateKeyFile != "" {
+		key, err := os.ReadFile(conf.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh private key file. %+v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh private key. %+v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if conf.Password != "" {
+		auth = append(auth, ssh.Password(conf.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no ssh authentication method given, set a private key file and / or password")
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !conf.InsecureIgnoreHostKey {
+		var err error
+		hostKeyCallback, err = knownhosts.New(conf.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ssh known hosts file. %+v", err)
+		}
+	}
+
+	return &SSH{
+		logger:          logger.With(zap.String("runner", Name)),
+		config:          conf,
+		hosts:           hosts,
+		auth:            auth,
+		hostKeyCallback: hostKeyCallback,
+		clients:         map[string]*ssh.Client{},
+		processes:       map[string]map[int]struct{}{},
+	}, nil
+}
+
+// GetHostsForTest return the list of hosts from the static inventory for the given test config
+func (s *SSH) GetHostsForTest(ctx context.Context, test *config.Test) (*testers.Hosts, error) {
+	// Pre create the structure to return
+	hosts := &testers.Hosts{
+		Clients: map[string]*testers.Host{},
+		Servers: map[string]*testers.Host{},
+	}
+
+	inventory := []*testers.Host{}
+	for _, host := range s.config.Hosts {
+		inventory = append(inventory, toTestersHost(host))
+	}
+
+	// Go through Hosts Servers list to get the servers hosts
+	for _, servers := range test.Hosts.Servers {
+		filtered, err := s.filterHosts(inventory, servers)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range filtered {
+			if _, ok := hosts.Servers[host.Name]; !ok {
+				hosts.Servers[host.Name] = host
+			}
+		}
+	}
+
+	// Go through Hosts Clients list to get the clients hosts
+	for _, clients := range test.Hosts.Clients {
+		filtered, err := s.filterHosts(inventory, clients)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range filtered {
+			if _, ok := hosts.Clients[host.Name]; !ok {
+				hosts.Clients[host.Name] = host
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+// filterHosts filter the inventory, statically listed hosts are looked up in the inventory
+func (s *SSH) filterHosts(inventory []*testers.Host, filter config.Hosts) ([]*testers.Host, error) {
+	filtered, err := hostsfilter.FilterHostsList(inventory, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, host := range filtered {
+		inventoryHost, ok := s.hosts[host.Name]
+		if !ok {
+			return nil, fmt.Errorf("host %q not found in ssh hosts inventory", host.Name)
+		}
+		filtered[i] = toTestersHost(inventoryHost)
+	}
+
+	return filtered, nil
+}
+
+func toTestersHost(host config.SSHHost) *testers.Host {
+	addresses := &testers.IPAddresses{}
+	if host.AddressV4 != "" {
+		addresses.IPv4 = []string{host.AddressV4}
+	}
+	if host.AddressV6 != "" {
+		addresses.IPv6 = []string{host.AddressV6}
+	}
+
+	labels := map[string]string{}
+	for k, v := range host.Labels {
+		labels[k] = v
+	}
+
+	return &testers.Host{
+		Name:      host.Name,
+		Labels:    labels,
+		Addresses: addresses,
+	}
+}
+
+// Prepare connect to all hosts of the plan to fail early on connection and authentication issues
+func (s *SSH) Prepare(ctx context.Context, runOpts config.RunOptions, plan *testers.Plan) error {
+	s.runOptions = runOpts
+
+	for name := range plan.AffectedServers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.getClient(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getClient return the (cached) SSH client connection for the host
+func (s *SSH) getClient(name string) (*ssh.Client, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if client, ok := s.clients[name]; ok {
+		return client, nil
+	}
+
+	host, ok := s.hosts[name]
+	if !ok {
+		return nil, fmt.Errorf("host %q not found in ssh hosts inventory", name)
+	}
+
+	s.logger.Debug("connecting to host", zap.String("hostname", name), zap.String("address", host.Address))
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host.Address, strconv.Itoa(host.Port)), &ssh.ClientConfig{
+		User:            host.User,
+		Auth:            s.auth,
+		HostKeyCallback: s.hostKeyCallback,
+		Timeout:         s.config.Timeouts.ConnectTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to host %s. %+v", name, err)
+	}
+	s.clients[name] = client
+
+	return client, nil
+}
+
+// Execute run the given commands and return the logs of it and / or error
+func (s *SSH) Execute(ctx context.Context, plan *testers.Plan, parser chan<- parsers.Input) error {
+	for round, tasks := range plan.Commands {
+		s.logger.Info(fmt.Sprintf("running commands round %d of %d", round+1, len(plan.Commands)))
+		for i, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if task.Sleep != 0 {
+				s.logger.Info(fmt.Sprintf("waiting %s to pass before continuing next round", task.Sleep.String()))
+				if err := util.Sleep(ctx, task.Sleep); err != nil {
+					return err
+				}
+				continue
+			}
+			s.logger.Info(fmt.Sprintf("running task round %d of %d", i+1, len(tasks)))
+
+			if err := s.runTasks(ctx, round, task, plan.TestStartTime, plan.Tester, parser); err != nil {
+				if plan.RunOptions.ContinueOnError == nil || !*plan.RunOptions.ContinueOnError || ctx.Err() != nil {
+					return err
+				}
+				s.logger.Warn("continuing after err", zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *SSH) runTasks(ctx context.Context, round int, mainTask *testers.Task, plannedTime time.Time, tester string, parser chan<- parsers.Input) error {
+	logger := s.logger.With(zap.Int("round", round), zap.String("hostname", mainTask.Host.Name))
+
+	// Create initial cmdtemplate.Variables
+	templateVars := cmdtemplate.Variables{
+		ServerPort: 5601,
+	}
+	if len(mainTask.Host.Addresses.IPv4) > 0 {
+		templateVars.ServerAddressV4 = mainTask.Host.Addresses.IPv4[0]
+	}
+	if len(mainTask.Host.Addresses.IPv6) > 0 {
+		templateVars.ServerAddressV6 = mainTask.Host.Addresses.IPv6[0]
+	}
+
+	if err := cmdtemplate.Template(mainTask, templateVars); err != nil {
+		logger.Error("failed to template main task command and / or args", zap.Error(err))
+		mainTask.Status.AddFailedServer(mainTask.Host, err)
+		return err
+	}
+
+	mainProc, err := s.startProcess(mainTask)
+	if err != nil {
+		logger.Error("failed to start main task", zap.Error(err))
+		mainTask.Status.AddFailedServer(mainTask.Host, err)
+		return err
+	}
+
+	mainStopped := make(chan struct{})
+	mainDone := make(chan struct{})
+	go func() {
+		defer close(mainDone)
+		// The server output is not used, but must be read for the command to not block on writing it
+		go io.Copy(io.Discard, mainProc.stdout)
+
+		if err := s.waitProcess(mainProc); err != nil {
+			select {
+			case <-mainStopped:
+				// Ignore any error after the main task is stopped
+				logger.Debug("ignored error after main task was stopped", zap.Error(err))
+			default:
+				logger.Error("error during main task run", zap.Error(err))
+			}
+		}
+	}()
+
+	stopMainTask := func() {
+		close(mainStopped)
+		s.killProcess(mainProc, mainDone)
+	}
+
+	if err := s.waitForMainTask(ctx, mainTask, templateVars, mainDone); err != nil {
+		mainTask.Status.AddFailedServer(mainTask.Host, err)
+		stopMainTask()
+		return err
+	}
+
+	runners.RunSubTasks(ctx, s.runOptions, mainTask.SubTasks, func(i int, task *testers.Task) {
+		logger.Info(fmt.Sprintf("running sub task %d of %d", i+1, len(mainTask.SubTasks)), zap.String("client", task.Host.Name))
+
+		// Template command and args for each task
+		if err := cmdtemplate.Template(task, templateVars); err != nil {
+			erro := fmt.Errorf("failed to template task command and / or args. %+v", err)
+			logger.Error("error during runTasks", zap.String("client", task.Host.Name), zap.Error(erro))
+			mainTask.Status.AddFailedClient(task.Host, erro)
+			return
+		}
+
+		// The result of the client is reported by the parser, once the output has been sent to it
+		if err := s.runSubTask(ctx, round, mainTask, task, plannedTime, tester, parser); err != nil {
+			logger.Error("client task failed", zap.String("client", task.Host.Name), zap.Error(err))
+			mainTask.Status.AddFailedClient(task.Host, err)
+		}
+	})
+
+	logger.Info("stopping main task")
+	stopMainTask()
+
+	// The test has been aborted, the sub tasks might not all have been run
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mainTask.Status.AddSuccessfulServer(mainTask.Host)
+
+	logger.Debug("done running tasks for test through ssh for plan")
+
+	return nil
+}
+
+// runSubTask run the client task and stream its stdout to the parser
+func (s *SSH) runSubTask(ctx context.Context, round int, mainTask *testers.Task, task *testers.Task, plannedTime time.Time, tester string, parser chan<- parsers.Input) error {
+	taskCtx, cancel := context.WithTimeout(ctx, s.config.Timeouts.TaskCommandTimeout)
+	defer cancel()
+
+	testTime := time.Now()
+
+	proc, err := s.startProcess(task)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	waitErr := make(chan error, 1)
+	go func() {
+		defer close(done)
+		waitErr <- s.waitProcess(proc)
+	}()
+
+	// The session is closed after the command has exited, the remaining output can still be read by the parser
+	stream := io.NopCloser(proc.stdout)
+	parser <- parsers.Input{
+		TestStartTime: plannedTime,
+		TestTime:      testTime,
+		Round:         round,
+		DataStream:    &stream,
+		Tester:        tester,
+		ServerHost:    mainTask.Host.Name,
+		ClientHost:    task.Host.Name,
+		IPFamily:      task.IPFamily,
+		Status:        mainTask.Status,
+	}
+
+	// A failed or timed out command results in a failed result in the parser, so it is only logged here
+	select {
+	case err := <-waitErr:
+		if err != nil {
+			s.logger.Error("client task command failed", zap.String("client", task.Host.Name), zap.Error(err))
+		}
+	case <-taskCtx.Done():
+		s.killProcess(proc, done)
+		if ctx.Err() == nil {
+			s.logger.Error(fmt.Sprintf("client task timed out after %s", s.config.Timeouts.TaskCommandTimeout), zap.String("client", task.Host.Name))
+		}
+	}
+
+	return nil
+}
+
+// startProcess start the task command in its own process group on the task host
+func (s *SSH) startProcess(task *testers.Task) (*process, error) {
+	client, err := s.getClient(task.Host.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ssh session on host %s. %+v", task.Host.Name, err)
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	command := buildCommand(task.Command, task.Args)
+	s.logger.Info("executing command", zap.String("hostname", task.Host.Name), zap.String("command", command))
+
+	if err := session.Start(command); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start command on host %s. %+v", task.Host.Name, err)
+	}
+
+	// The first line of the output is the process group ID printed by the processGroupWrapper
+	reader := bufio.NewReader(stdout)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to read process group id from host %s. %+v", task.Host.Name, err)
+	}
+	pgid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to parse process group id from host %s. %+v", task.Host.Name, err)
+	}
+
+	s.lock.Lock()
+	if _, ok := s.processes[task.Host.Name]; !ok {
+		s.processes[task.Host.Name] = map[int]struct{}{}
+	}
+	s.processes[task.Host.Name][pgid] = struct{}{}
+	s.lock.Unlock()
+
+	return &process{
+		host:    task.Host.Name,
+		pgid:    pgid,
+		session: session,
+		stdout:  reader,
+	}, nil
+}
+
+// waitProcess wait for the process to exit and close its session. When the session ended without an exit status,
+// the process might still be running and is kept for the Cleanup to kill it
+func (s *SSH) waitProcess(proc *process) error {
+	err := proc.session.Wait()
+	proc.session.Close()
+
+	if _, ok := err.(*ssh.ExitMissingError); !ok {
+		s.lock.Lock()
+		delete(s.processes[proc.host], proc.pgid)
+		s.lock.Unlock()
+	}
+
+	return err
+}
+
+// killProcess kill the process group of the process and wait for it to exit (done channel to be closed)
+func (s *SSH) killProcess(proc *process, done <-chan struct{}) {
+	if err := s.killProcessGroup(proc.host, proc.pgid); err != nil {
+		s.logger.Error("failed to kill process group", zap.String("hostname", proc.host), zap.Int("pgid", proc.pgid), zap.Error(err))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(killTimeout):
+		// Closing the session will cause the wait for the process to return
+		proc.session.Close()
+		<-done
+	}
+}
+
+// killProcessGroup kill the process group on the host
+func (s *SSH) killProcessGroup(host string, pgid int) error {
+	client, err := s.getClient(host)
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	s.logger.Debug("killing process group", zap.String("hostname", host), zap.Int("pgid", pgid))
+
+	// The process group might have already exited, so the exit code is not checked
+	if err := session.Run(fmt.Sprintf("kill -TERM -%d", pgid)); err != nil {
+		if _, ok := err.(*ssh.ExitError); !ok {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForMainTask wait for the main task to be ready. When the main task has TCP ports, they are probed from the
+// server host itself until they accept connections, otherwise it is only checked that the main task has not exited
+func (s *SSH) waitForMainTask(ctx context.Context, mainTask *testers.Task, templateVars cmdtemplate.Variables, mainDone <-chan struct{}) error {
+	if err := util.Sleep(ctx, 250*time.Millisecond); err != nil {
+		return err
+	}
+
+	select {
+	case <-mainDone:
+		return fmt.Errorf("ssh main task exited before becoming ready")
+	default:
+	}
+
+	if len(mainTask.Ports.TCP) == 0 {
+		return nil
+	}
+
+	client, err := s.getClient(mainTask.Host.Name)
+	if err != nil {
+		return err
+	}
+
+	address := templateVars.ServerAddressV4
+	if address == "" {
+		address = templateVars.ServerAddressV6
+	}
+	if address == "" {
+		address = "127.0.0.1"
+	}
+
+	timeout := time.After(s.config.Timeouts.ServerReadyTimeout)
+	for _, port := range mainTask.Ports.TCP {
+		target := net.JoinHostPort(address, strconv.Itoa(int(port)))
+		for {
+			conn, err := client.Dial("tcp", target)
+			if err == nil {
+				conn.Close()
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-mainDone:
+				return fmt.Errorf("ssh main task exited before becoming ready")
+			case <-timeout:
+				return fmt.Errorf("ssh main task not ready after %s, port %s not reachable. %+v", s.config.Timeouts.ServerReadyTimeout, target, err)
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+
+	return nil
+}
+
+// Cleanup kill all (left behind) process groups and close the SSH connections.
+func (s *SSH) Cleanup(ctx context.Context, plan *testers.Plan) error {
+	s.lock.Lock()
+	processes := map[string][]int{}
+	for host, pgids := range s.processes {
+		for pgid := range pgids {
+			processes[host] = append(processes[host], pgid)
+		}
+	}
+	s.lock.Unlock()
+
+	var errs []string
+	for host, pgids := range processes {
+		for _, pgid := range pgids {
+			if err := s.killProcessGroup(host, pgid); err != nil {
+				errs = append(errs, fmt.Sprintf("%s (pgid %d): %+v", host, pgid, err))
+			}
+		}
+	}
+
+	s.lock.Lock()
+	for name, client := range s.clients {
+		client.Close()
+		delete(s.clients, name)
+	}
+	s.lock.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to kill process groups on hosts. %s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// buildCommand build the shell command line for the command and its args, wrapped in the processGroupWrapper
+func buildCommand(command string, args []string) string {
+	parts := []string{processGroupWrapper, shellQuote(command)}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quote the string for the use as a single argument in a POSIX shell
+func shellQuote(in string) string {
+	return "'" + strings.ReplaceAll(in, "'", `'\''`) + "'"
+}
@@ -0,0 +1,479 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/galexrt/ancientt/parsers"
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/galexrt/ancientt/pkg/util"
+	"github.com/galexrt/ancientt/testers"
+)
+
+const (
+	testUser     = "ancientt"
+	testPassword = "secret"
+)
+
+// testServer in-process SSH server executing the commands with `sh -c` on the local machine
+type testServer struct {
+	t        *testing.T
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.PublicKey
+	wg       sync.WaitGroup
+}
+
+func newTestServer(t *testing.T) *testServer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == testUser && string(password) == testPassword {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &testServer{
+		t:        t,
+		listener: listener,
+		config:   serverConfig,
+		hostKey:  signer.PublicKey(),
+	}
+	srv.wg.Add(1)
+	go srv.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		srv.wg.Wait()
+	})
+
+	return srv
+}
+
+func (srv *testServer) port() int {
+	return srv.listener.Addr().(*net.TCPAddr).Port
+}
+
+// writeKnownHosts write a known_hosts file containing the server host key
+func (srv *testServer) writeKnownHosts(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{srv.listener.Addr().String()}, srv.hostKey)
+	require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0600))
+	return path
+}
+
+func (srv *testServer) serve() {
+	defer srv.wg.Done()
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handleConn(conn)
+	}
+}
+
+func (srv *testServer) handleConn(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, srv.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		switch newCh.ChannelType() {
+		case "session":
+			go srv.handleSession(newCh)
+		case "direct-tcpip":
+			go srv.handleDirectTCPIP(newCh)
+		default:
+			newCh.Reject(ssh.UnknownChannelType, "unknown channel type")
+		}
+	}
+}
+
+func (srv *testServer) handleSession(newCh ssh.NewChannel) {
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+
+	for req := range reqs {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		// Payload is a SSH string, uint32 length followed by the command
+		command := string(req.Payload[4:])
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdout = ch
+		cmd.Stderr = ch.Stderr()
+
+		status := uint32(0)
+		if err := cmd.Run(); err != nil {
+			status = 1
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+				status = uint32(exitErr.ExitCode())
+			}
+		}
+
+		payload := make([]byte, 4)
+		binary.BigEndian.PutUint32(payload, status)
+		ch.SendRequest("exit-status", false, payload)
+		return
+	}
+}
+
+func (srv *testServer) handleDirectTCPIP(newCh ssh.NewChannel) {
+	var payload struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newCh.ExtraData(), &payload); err != nil {
+		newCh.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		newCh.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+
+	go io.Copy(conn, ch)
+	io.Copy(ch, conn)
+}
+
+func newTestRunner(t *testing.T, srv *testServer) *SSH {
+	r, err := NewRunner(zap.NewNop(), &config.Config{
+		Runner: config.Runner{
+			SSH: &config.RunnerSSH{
+				User:           testUser,
+				Password:       testPassword,
+				Port:           srv.port(),
+				KnownHostsFile: srv.writeKnownHosts(t),
+				Hosts: []config.SSHHost{
+					{
+						Name:      "server1",
+						Address:   "127.0.0.1",
+						AddressV4: "127.0.0.1",
+						Labels: map[string]string{
+							"role": "server",
+						},
+					},
+					{
+						Name:      "client1",
+						Address:   "127.0.0.1",
+						AddressV4: "127.0.0.1",
+					},
+				},
+				Timeouts: &config.SSHTimeouts{
+					ServerReadyTimeout: 2 * time.Second,
+					TaskCommandTimeout: 5 * time.Second,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return r.(*SSH)
+}
+
+func TestGetHostsForTest(t *testing.T) {
+	r, err := NewRunner(zap.NewNop(), &config.Config{
+		Runner: config.Runner{
+			SSH: &config.RunnerSSH{
+				Password:              testPassword,
+				InsecureIgnoreHostKey: true,
+				Hosts: []config.SSHHost{
+					{
+						Name:      "server1",
+						AddressV4: "192.0.2.1",
+						Labels: map[string]string{
+							"role": "server",
+						},
+					},
+					{
+						Name:      "client1",
+						AddressV6: "2001:db8::2",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	hosts, err := r.GetHostsForTest(context.Background(), &config.Test{
+		Hosts: config.TestHosts{
+			Servers: []config.Hosts{
+				{
+					HostSelector: map[string]string{
+						"role": "server",
+					},
+				},
+			},
+			Clients: []config.Hosts{
+				{
+					Hosts: []string{"client1"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, hosts.Servers, 1)
+	assert.Equal(t, []string{"192.0.2.1"}, hosts.Servers["server1"].Addresses.IPv4)
+	require.Len(t, hosts.Clients, 1)
+	// Statically listed hosts must keep their addresses
+	assert.Equal(t, []string{"2001:db8::2"}, hosts.Clients["client1"].Addresses.IPv6)
+
+	_, err = r.GetHostsForTest(context.Background(), &config.Test{
+		Hosts: config.TestHosts{
+			Clients: []config.Hosts{
+				{
+					Hosts: []string{"does-not-exist"},
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewRunnerUnknownHostKey(t *testing.T) {
+	srv := newTestServer(t)
+	r := newTestRunner(t, srv)
+
+	// Replace the known hosts with an empty file, the connection must be refused
+	emptyKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(emptyKnownHosts, []byte{}, 0600))
+	callback, err := knownhosts.New(emptyKnownHosts)
+	require.NoError(t, err)
+	r.hostKeyCallback = callback
+
+	_, err = r.getClient("server1")
+	assert.Error(t, err)
+}
+
+func TestExecute(t *testing.T) {
+	srv := newTestServer(t)
+	r := newTestRunner(t, srv)
+
+	// The listener stands in for the port of the server task, it is probed through the SSH connection
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	server := toTestersHost(r.hosts["server1"])
+	client := toTestersHost(r.hosts["client1"])
+
+	status := testers.NewStatus()
+	plan := &testers.Plan{
+		TestStartTime: time.Now(),
+		Tester:        "mock",
+		AffectedServers: map[string]*testers.Host{
+			server.Name: server,
+			client.Name: client,
+		},
+		RunOptions: config.RunOptions{
+			ContinueOnError: util.BoolTruePointer(),
+		},
+		Commands: [][]*testers.Task{
+			{
+				{
+					Host:    server,
+					Command: "sleep",
+					Args:    []string{"30"},
+					Ports: testers.Ports{
+						TCP: []int32{int32(listener.Addr().(*net.TCPAddr).Port)},
+					},
+					Status: status,
+					SubTasks: []*testers.Task{
+						{
+							Host:    client,
+							Command: "echo",
+							Args:    []string{"{{ .ServerAddressV4 }}:{{ .ServerPort }}", "it's quoted"},
+						},
+						{
+							Host:    client,
+							Command: "false",
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, r.Prepare(context.Background(), plan.RunOptions, plan))
+
+	parserCh := make(chan parsers.Input)
+	outs := make(chan string)
+	go func() {
+		for input := range parserCh {
+			out, err := io.ReadAll(*input.DataStream)
+			assert.NoError(t, err)
+			assert.NoError(t, (*input.DataStream).Close())
+			assert.Equal(t, "server1", input.ServerHost)
+			assert.Equal(t, "client1", input.ClientHost)
+			outs <- string(out)
+		}
+		close(outs)
+	}()
+
+	go func() {
+		assert.NoError(t, r.Execute(context.Background(), plan, parserCh))
+		close(parserCh)
+	}()
+
+	results := []string{}
+	for out := range outs {
+		results = append(results, out)
+	}
+
+	assert.Equal(t, []string{"127.0.0.1:5601 it's quoted\n", ""}, results)
+	assert.Equal(t, 1, status.SuccessfulHosts.Servers["server1"])
+	// The results of the clients are reported by the parser, not by the runner
+	assert.Empty(t, status.SuccessfulHosts.Clients)
+	assert.Empty(t, status.FailedHosts.Clients)
+
+	// The server task must have been stopped
+	assert.Empty(t, r.processes["server1"])
+
+	assert.NoError(t, r.Cleanup(context.Background(), plan))
+}
+
+func TestCleanup(t *testing.T) {
+	srv := newTestServer(t)
+	r := newTestRunner(t, srv)
+
+	proc, err := r.startProcess(&testers.Task{
+		Host:    toTestersHost(r.hosts["server1"]),
+		Command: "sleep",
+		Args:    []string{"30"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, r.processes["server1"], proc.pgid)
+
+	done := make(chan error)
+	go func() {
+		done <- r.waitProcess(proc)
+	}()
+
+	// Cleanup must kill the left behind process group
+	require.NoError(t, r.Cleanup(context.Background(), &testers.Plan{}))
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("process has not been killed by cleanup")
+	}
+	assert.Empty(t, r.clients)
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	srv := newTestServer(t)
+	r := newTestRunner(t, srv)
+
+	server := toTestersHost(r.hosts["server1"])
+	client := toTestersHost(r.hosts["client1"])
+
+	status := testers.NewStatus()
+	plan := &testers.Plan{
+		TestStartTime: time.Now(),
+		Tester:        "mock",
+		RunOptions: config.RunOptions{
+			ContinueOnError: util.BoolTruePointer(),
+		},
+		Commands: [][]*testers.Task{
+			{
+				{
+					Host:    server,
+					Command: "sleep",
+					Args:    []string{"30"},
+					Status:  status,
+					SubTasks: []*testers.Task{
+						{
+							Host:    client,
+							Command: "sleep",
+							Args:    []string{"30"},
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, r.Prepare(context.Background(), plan.RunOptions, plan))
+
+	parserCh := make(chan parsers.Input)
+	go func() {
+		for input := range parserCh {
+			_, _ = io.ReadAll(*input.DataStream)
+		}
+	}()
+	defer close(parserCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Second, cancel)
+
+	start := time.Now()
+	assert.ErrorIs(t, r.Execute(ctx, plan, parserCh), context.Canceled)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, 0, status.SuccessfulHosts.Servers["server1"])
+
+	// The server and client tasks must have been stopped
+	assert.Empty(t, r.processes["server1"])
+	assert.Empty(t, r.processes["client1"])
+
+	assert.NoError(t, r.Cleanup(context.Background(), plan))
+}
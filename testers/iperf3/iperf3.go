@@ -59,7 +59,9 @@ func (t IPerf3) Plan(env *testers.Environment, test *config.Test) (*testers.Plan
 
 	var ports testers.Ports
 	if t.config.UDP != nil && *t.config.UDP {
+		// iperf3 uses a TCP connection for the control channel in UDP mode as well
 		ports = testers.Ports{
+			TCP: []int32{5601},
 			UDP: []int32{5601},
 		}
 	} else {
@@ -68,20 +70,21 @@ func (t IPerf3) Plan(env *testers.Environment, test *config.Test) (*testers.Plan
 		}
 	}
 
+	serverClients, err := testers.GetServerClients(env.Hosts, test.Topology)
+	if err != nil {
+		return nil, err
+	}
+
+	families, err := test.IPFamily.Families()
+	if err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < test.RunOptions.Rounds; i++ {
-		for _, server := range env.Hosts.Servers {
+		for _, entry := range serverClients {
+			server := entry.Server
 			round := &testers.Task{
-				Status: &testers.Status{
-					SuccessfulHosts: testers.StatusHosts{
-						Servers: map[string]int{},
-						Clients: map[string]int{},
-					},
-					FailedHosts: testers.StatusHosts{
-						Servers: map[string]int{},
-						Clients: map[string]int{},
-					},
-					Errors: map[string][]error{},
-				},
+				Status: testers.NewStatus(),
 			}
 			// Add server host to AffectedServers list
 			if _, ok := plan.AffectedServers[server.Name]; !ok {
@@ -94,20 +97,23 @@ func (t IPerf3) Plan(env *testers.Environment, test *config.Test) (*testers.Plan
 			round.Ports = ports
 
 			// Now go over each client and generate their Task
-			for _, client := range env.Hosts.Clients {
+			for _, client := range entry.Clients {
 				// Add client host to AffectedServers list
 				if _, ok := plan.AffectedServers[client.Name]; !ok {
 					plan.AffectedServers[client.Name] = client
 				}
 
-				// Build the IPerf3 command
-				cmd, args := t.buildIPerf3ClientCommand(server, client)
-				round.SubTasks = append(round.SubTasks, &testers.Task{
-					Host:    client,
-					Command: cmd,
-					Args:    args,
-					Ports:   ports,
-				})
+				// Build the IPerf3 command for each IP family
+				for _, family := range families {
+					cmd, args := t.buildIPerf3ClientCommand(server, client, family)
+					round.SubTasks = append(round.SubTasks, &testers.Task{
+						Host:     client,
+						Command:  cmd,
+						Args:     args,
+						Ports:    ports,
+						IPFamily: family,
+					})
+				}
 			}
 			plan.Commands[i] = append(plan.Commands[i], round)
 
@@ -145,7 +151,7 @@ func (t IPerf3) buildIPerf3ServerCommand(server *testers.Host) (string, []string
 }
 
 // buildIPerf3ClientCommand generate IPer3 client command
-func (t IPerf3) buildIPerf3ClientCommand(server *testers.Host, client *testers.Host) (string, []string) {
+func (t IPerf3) buildIPerf3ClientCommand(server *testers.Host, client *testers.Host, family config.IPFamily) (string, []string) {
 	// Base command and args
 	cmd := "iperf3"
 	args := []string{
@@ -153,7 +159,12 @@ func (t IPerf3) buildIPerf3ClientCommand(server *testers.Host, client *testers.H
 		fmt.Sprintf("--interval=%d", *t.config.Interval),
 		"--json",
 		"--port={{ .ServerPort }}",
-		"--client={{ .ServerAddressV4 }}",
+	}
+
+	if family == config.IPFamilyIPv6 {
+		args = append(args, "--client={{ .ServerAddressV6 }}", "--version6")
+	} else {
+		args = append(args, "--client={{ .ServerAddressV4 }}")
 	}
 
 	// Add --udp flag when UDP should be used
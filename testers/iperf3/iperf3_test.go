@@ -16,6 +16,7 @@ package iperf3
 import (
 	"testing"
 
+	"github.com/creasty/defaults"
 	"github.com/galexrt/ancientt/pkg/config"
 	"github.com/galexrt/ancientt/testers"
 	"github.com/stretchr/testify/assert"
@@ -45,3 +46,48 @@ func TestIPerf3Plan(t *testing.T) {
 	assert.Equal(t, 0, len(plan.AffectedServers))
 	assert.Equal(t, 0, len(plan.Commands))
 }
+
+func TestIPerf3PlanIPFamily(t *testing.T) {
+	test := &config.Test{
+		Type:     "iperf3",
+		IPFamily: config.IPFamilyBoth,
+		RunOptions: config.RunOptions{
+			Rounds: 1,
+		},
+		IPerf3: &config.IPerf3{},
+	}
+	require.Nil(t, defaults.Set(test.IPerf3))
+
+	tester, err := NewIPerf3Tester(zap.NewNop(), nil, test)
+	require.Nil(t, err)
+
+	env := &testers.Environment{
+		Hosts: &testers.Hosts{
+			Clients: map[string]*testers.Host{
+				"client1": {Name: "client1"},
+			},
+			Servers: map[string]*testers.Host{
+				"server1": {Name: "server1"},
+			},
+		},
+	}
+
+	plan, err := tester.Plan(env, test)
+	require.Nil(t, err)
+	require.Len(t, plan.Commands, 1)
+	require.Len(t, plan.Commands[0], 1)
+
+	// One client task per IP family
+	subTasks := plan.Commands[0][0].SubTasks
+	require.Len(t, subTasks, 2)
+	assert.Equal(t, config.IPFamilyIPv4, subTasks[0].IPFamily)
+	assert.Contains(t, subTasks[0].Args, "--client={{ .ServerAddressV4 }}")
+	assert.Equal(t, config.IPFamilyIPv6, subTasks[1].IPFamily)
+	assert.Contains(t, subTasks[1].Args, "--client={{ .ServerAddressV6 }}")
+	assert.Contains(t, subTasks[1].Args, "--version6")
+
+	// Unknown IP families must not fall back to IPv4
+	test.IPFamily = "IPv6"
+	_, err = tester.Plan(env, test)
+	assert.NotNil(t, err)
+}
@@ -57,20 +57,21 @@ func (t PingParsing) Plan(env *testers.Environment, test *config.Test) (*testers
 		Commands:        make([][]*testers.Task, test.RunOptions.Rounds),
 	}
 
+	serverClients, err := testers.GetServerClients(env.Hosts, test.Topology)
+	if err != nil {
+		return nil, err
+	}
+
+	families, err := test.IPFamily.Families()
+	if err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < test.RunOptions.Rounds; i++ {
-		for _, server := range env.Hosts.Servers {
+		for _, entry := range serverClients {
+			server := entry.Server
 			round := &testers.Task{
-				Status: &testers.Status{
-					SuccessfulHosts: testers.StatusHosts{
-						Servers: map[string]int{},
-						Clients: map[string]int{},
-					},
-					FailedHosts: testers.StatusHosts{
-						Servers: map[string]int{},
-						Clients: map[string]int{},
-					},
-					Errors: map[string][]error{},
-				},
+				Status: testers.NewStatus(),
 			}
 			// Add server host to AffectedServers list
 			if _, ok := plan.AffectedServers[server.Name]; !ok {
@@ -84,19 +85,22 @@ func (t PingParsing) Plan(env *testers.Environment, test *config.Test) (*testers
 			round.Command, round.Args = t.buildPingParsingServerCommand(server)
 
 			// Now go over each client and generate their Task
-			for _, client := range env.Hosts.Clients {
+			for _, client := range entry.Clients {
 				// Add client host to AffectedServers list
 				if _, ok := plan.AffectedServers[client.Name]; !ok {
 					plan.AffectedServers[client.Name] = client
 				}
 
-				// Build the PingParsing command
-				cmd, args := t.buildPingParsingClientCommand(server, client)
-				round.SubTasks = append(round.SubTasks, &testers.Task{
-					Host:    client,
-					Command: cmd,
-					Args:    args,
-				})
+				// Build the PingParsing command for each IP family
+				for _, family := range families {
+					cmd, args := t.buildPingParsingClientCommand(server, client, family)
+					round.SubTasks = append(round.SubTasks, &testers.Task{
+						Host:     client,
+						Command:  cmd,
+						Args:     args,
+						IPFamily: family,
+					})
+				}
 			}
 			plan.Commands[i] = append(plan.Commands[i], round)
 
@@ -118,7 +122,7 @@ func (t PingParsing) buildPingParsingServerCommand(server *testers.Host) (string
 }
 
 // buildPingParsingClientCommand
-func (t PingParsing) buildPingParsingClientCommand(server *testers.Host, client *testers.Host) (string, []string) {
+func (t PingParsing) buildPingParsingClientCommand(server *testers.Host, client *testers.Host, family config.IPFamily) (string, []string) {
 	// Base command and args
 	cmd := "pingparsing"
 	args := []string{
@@ -128,7 +132,12 @@ func (t PingParsing) buildPingParsingClientCommand(server *testers.Host, client
 		fmt.Sprintf("-w=%s", *t.config.Deadline),
 		fmt.Sprintf("--timeout=%s", *t.config.Timeout),
 		fmt.Sprintf("-I=%s", t.config.Interface),
-		"{{ .ServerAddressV4 }}",
+	}
+
+	if family == config.IPFamilyIPv6 {
+		args = append(args, "--ipv6", "{{ .ServerAddressV6 }}")
+	} else {
+		args = append(args, "{{ .ServerAddressV4 }}")
 	}
 
 	return cmd, args
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PlanFormat format to export plans in
+type PlanFormat string
+
+const (
+	// PlanFormatText "pretty" printed text, see `Plan.PrettyPrint()`
+	PlanFormatText PlanFormat = "text"
+	// PlanFormatJSON JSON format
+	PlanFormatJSON PlanFormat = "json"
+	// PlanFormatYAML YAML format
+	PlanFormatYAML PlanFormat = "yaml"
+)
+
+// TestPlan the plan for a test, used to export and import plans
+type TestPlan struct {
+	Test string `json:"test"`
+	Plan *Plan  `json:"plan"`
+}
+
+// WritePlans write the plans in the given format (JSON or YAML) to the writer
+func WritePlans(w io.Writer, format PlanFormat, plans []TestPlan) error {
+	var out []byte
+	var err error
+	switch format {
+	case PlanFormatJSON:
+		out, err = json.MarshalIndent(plans, "", "  ")
+		out = append(out, '\n')
+	case PlanFormatYAML:
+		out, err = yaml.Marshal(plans)
+	default:
+		return fmt.Errorf("unknown plan format %s given", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal plans. %+v", err)
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// ReadPlans read plans from a JSON or YAML file, the tasks status is initialized for a run of the plans
+func ReadPlans(file string) (map[string]*Plan, error) {
+	out, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file %s. %+v", file, err)
+	}
+
+	// YAML is a superset of JSON, so this works for both formats
+	plans := []TestPlan{}
+	if err := yaml.UnmarshalStrict(out, &plans); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plan file %s. %+v", file, err)
+	}
+
+	result := map[string]*Plan{}
+	for _, entry := range plans {
+		if entry.Plan == nil {
+			return nil, fmt.Errorf("no plan given for test %s in plan file %s", entry.Test, file)
+		}
+		if _, ok := result[entry.Test]; ok {
+			return nil, fmt.Errorf("duplicate plan for test %s in plan file %s", entry.Test, file)
+		}
+
+		for _, round := range entry.Plan.Commands {
+			for _, task := range round {
+				if task.Status == nil {
+					task.Status = NewStatus()
+				}
+			}
+		}
+		result[entry.Test] = entry.Plan
+	}
+
+	return result, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlan() *Plan {
+	server := &Host{
+		Name: "server1",
+		Addresses: &IPAddresses{
+			IPv4: []string{"192.0.2.1"},
+		},
+	}
+	client := &Host{
+		Name:   "client1",
+		Labels: map[string]string{"role": "client"},
+	}
+
+	return &Plan{
+		TestStartTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tester:        "iperf3",
+		AffectedServers: map[string]*Host{
+			server.Name: server,
+			client.Name: client,
+		},
+		Commands: [][]*Task{
+			{
+				{
+					Host:    server,
+					Command: "iperf3",
+					Args:    []string{"--server", "--port={{ .ServerPort }}"},
+					Ports:   Ports{TCP: []int32{5601}},
+					SubTasks: []*Task{
+						{
+							Host:    client,
+							Command: "iperf3",
+							Args:    []string{"--client={{ .ServerAddressV4 }}"},
+						},
+					},
+					Status: NewStatus(),
+				},
+				{
+					Sleep: 10 * time.Second,
+				},
+			},
+		},
+	}
+}
+
+func TestWriteAndReadPlans(t *testing.T) {
+	for _, format := range []PlanFormat{PlanFormatJSON, PlanFormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			plan := newTestPlan()
+
+			buf := &bytes.Buffer{}
+			require.Nil(t, WritePlans(buf, format, []TestPlan{{Test: "test1", Plan: plan}}))
+
+			file := filepath.Join(t.TempDir(), "plan."+string(format))
+			require.Nil(t, os.WriteFile(file, buf.Bytes(), 0640))
+
+			plans, err := ReadPlans(file)
+			require.Nil(t, err)
+			require.Contains(t, plans, "test1")
+
+			read := plans["test1"]
+			assert.True(t, plan.TestStartTime.Equal(read.TestStartTime))
+			assert.Equal(t, plan.AffectedServers, read.AffectedServers)
+			assert.Equal(t, plan.Tester, read.Tester)
+			require.Len(t, read.Commands, 1)
+			require.Len(t, read.Commands[0], 2)
+
+			task := read.Commands[0][0]
+			assert.Equal(t, plan.Commands[0][0].Host, task.Host)
+			assert.Equal(t, plan.Commands[0][0].Args, task.Args)
+			assert.Equal(t, plan.Commands[0][0].Ports, task.Ports)
+			assert.Equal(t, plan.Commands[0][0].SubTasks, task.SubTasks)
+			assert.Equal(t, plan.Commands[0][1].Sleep, read.Commands[0][1].Sleep)
+			// The status must be initialized for each task to be able to run the plan
+			assert.NotNil(t, task.Status)
+			assert.NotNil(t, read.Commands[0][1].Status)
+		})
+	}
+
+	assert.NotNil(t, WritePlans(&bytes.Buffer{}, PlanFormatText, nil))
+}
+
+func TestReadPlansErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadPlans(filepath.Join(dir, "doesnotexist.json"))
+	assert.NotNil(t, err)
+
+	file := filepath.Join(dir, "duplicate.yaml")
+	require.Nil(t, os.WriteFile(file, []byte("- test: test1\n  plan: {}\n- test: test1\n  plan: {}\n"), 0640))
+	_, err = ReadPlans(file)
+	assert.NotNil(t, err)
+
+	file = filepath.Join(dir, "unknownfield.yaml")
+	require.Nil(t, os.WriteFile(file, []byte("- test: test1\n  plan:\n    doesnotexist: true\n"), 0640))
+	_, err = ReadPlans(file)
+	assert.NotNil(t, err)
+}
+
+func TestPlanHostsStatus(t *testing.T) {
+	status := NewStatus()
+	status.AddSuccessfulServer(&Host{Name: "server1"})
+	status.AddSuccessfulClient(&Host{Name: "client1"})
+	status.AddFailedClient(&Host{Name: "client2"}, fmt.Errorf("connection refused"))
+	status.AddFailedClient(&Host{Name: "client2"}, fmt.Errorf("timeout"))
+	status.AddFailedServer(&Host{Name: "client2"}, fmt.Errorf("address already in use"))
+	otherStatus := NewStatus()
+	otherStatus.AddSuccessfulServer(&Host{Name: "server1"})
+	// The errors of every status must be listed
+	otherStatus.AddFailedClient(&Host{Name: "client2"}, fmt.Errorf("no route to host"))
+
+	plan := &Plan{
+		Commands: [][]*Task{
+			// The shared status must only be counted once
+			{{Status: status}, {Status: status}, {Sleep: time.Second}},
+			{{Status: otherStatus}},
+		},
+	}
+
+	failed, successful := plan.HostsStatus()
+	assert.Equal(t, []HostStatus{
+		{Role: "client", Host: "client2", Count: 3, Errors: []string{"connection refused", "timeout", "no route to host"}},
+		// The errors are recorded per role of the host
+		{Role: "server", Host: "client2", Count: 1, Errors: []string{"address already in use"}},
+	}, failed)
+	assert.Equal(t, []HostStatus{
+		{Role: "client", Host: "client1", Count: 1},
+		{Role: "server", Host: "server1", Count: 2},
+	}, successful)
+}
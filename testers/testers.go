@@ -15,6 +15,8 @@ package testers
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/galexrt/ancientt/pkg/config"
@@ -96,26 +98,31 @@ func (p Plan) PrettyPrint() {
 
 // Task information for the task to execute
 type Task struct {
-	Host     *Host         `json:"host"`
-	Command  string        `json:"command"`
-	Args     []string      `json:"args"`
-	Sleep    time.Duration `json:"sleep"`
-	Ports    Ports         `json:"ports"`
-	SubTasks []*Task       `json:"subTasks"`
-	Status   *Status       `yaml:"status"`
+	Host     *Host           `json:"host"`
+	Command  string          `json:"command"`
+	Args     []string        `json:"args"`
+	Sleep    time.Duration   `json:"sleep"`
+	Ports    Ports           `json:"ports"`
+	IPFamily config.IPFamily `json:"ipFamily,omitempty"`
+	SubTasks []*Task         `json:"subTasks"`
+	// Status is only used during the run of the plan
+	Status *Status `json:"-"`
 }
 
 // Ports TCP and UDP ports list
 type Ports struct {
-	TCP []int32
-	UDP []int32
+	TCP []int32 `json:"tcp"`
+	UDP []int32 `json:"udp"`
 }
 
 // Status status info for a task
 type Status struct {
-	SuccessfulHosts StatusHosts        `json:"successfulHosts"`
-	FailedHosts     StatusHosts        `json:"failedHosts"`
-	Errors          map[string][]error `json:"errors"`
+	SuccessfulHosts StatusHosts  `json:"successfulHosts"`
+	FailedHosts     StatusHosts  `json:"failedHosts"`
+	Errors          StatusErrors `json:"errors"`
+
+	// lock the Status is written to by the runners and parsers at the same time
+	lock sync.Mutex
 }
 
 // StatusHosts status per servers and clients list with counter
@@ -124,12 +131,36 @@ type StatusHosts struct {
 	Clients map[string]int `json:"clients"`
 }
 
+// StatusErrors errors per servers and clients list
+type StatusErrors struct {
+	Servers map[string][]error `json:"servers"`
+	Clients map[string][]error `json:"clients"`
+}
+
+// NewStatus return a new empty Status
+func NewStatus() *Status {
+	return &Status{
+		SuccessfulHosts: StatusHosts{
+			Servers: map[string]int{},
+			Clients: map[string]int{},
+		},
+		FailedHosts: StatusHosts{
+			Servers: map[string]int{},
+			Clients: map[string]int{},
+		},
+		Errors: StatusErrors{
+			Servers: map[string][]error{},
+			Clients: map[string][]error{},
+		},
+	}
+}
+
 // AddFailedServer add a server host that failed with error to the Status list
 func (st *Status) AddFailedServer(host *Host, err error) {
-	if _, ok := st.Errors[host.Name]; !ok {
-		st.Errors[host.Name] = []error{}
-	}
-	st.Errors[host.Name] = append(st.Errors[host.Name], err)
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	st.Errors.Servers[host.Name] = append(st.Errors.Servers[host.Name], err)
 
 	// Increase failed host counter
 	if _, ok := st.FailedHosts.Servers[host.Name]; !ok {
@@ -141,10 +172,10 @@ func (st *Status) AddFailedServer(host *Host, err error) {
 
 // AddFailedClient add a client host that failed with error to the Status list
 func (st *Status) AddFailedClient(host *Host, err error) {
-	if _, ok := st.Errors[host.Name]; !ok {
-		st.Errors[host.Name] = []error{}
-	}
-	st.Errors[host.Name] = append(st.Errors[host.Name], err)
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	st.Errors.Clients[host.Name] = append(st.Errors.Clients[host.Name], err)
 
 	// Increase failed host counter
 	if _, ok := st.FailedHosts.Clients[host.Name]; !ok {
@@ -156,6 +187,9 @@ func (st *Status) AddFailedClient(host *Host, err error) {
 
 // AddSuccessfulServer add a successful server host to the list
 func (st *Status) AddSuccessfulServer(host *Host) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
 	// Increase successful host counter
 	if _, ok := st.SuccessfulHosts.Servers[host.Name]; !ok {
 		st.SuccessfulHosts.Servers[host.Name] = 1
@@ -166,6 +200,9 @@ func (st *Status) AddSuccessfulServer(host *Host) {
 
 // AddSuccessfulClient add a successful client host to the list
 func (st *Status) AddSuccessfulClient(host *Host) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
 	// Increase successful host counter
 	if _, ok := st.SuccessfulHosts.Clients[host.Name]; !ok {
 		st.SuccessfulHosts.Clients[host.Name] = 1
@@ -173,3 +210,68 @@ func (st *Status) AddSuccessfulClient(host *Host) {
 		st.SuccessfulHosts.Clients[host.Name]++
 	}
 }
+
+// HostStatus status of a host over all tasks of a plan
+type HostStatus struct {
+	// Role of the host, `server` or `client`
+	Role   string
+	Host   string
+	Count  int
+	Errors []string
+}
+
+// HostsStatus return the failed and successful hosts of all tasks of the plan, sorted by role and host
+func (p Plan) HostsStatus() ([]HostStatus, []HostStatus) {
+	failed := map[string]*HostStatus{}
+	successful := map[string]*HostStatus{}
+
+	add := func(list map[string]*HostStatus, role string, hosts map[string]int, errs map[string][]error) {
+		for host, count := range hosts {
+			key := role + "/" + host
+			if _, ok := list[key]; !ok {
+				list[key] = &HostStatus{Role: role, Host: host}
+			}
+			list[key].Count += count
+			for _, err := range errs[host] {
+				list[key].Errors = append(list[key].Errors, err.Error())
+			}
+		}
+	}
+
+	// Tasks can share the same status, each status must only be counted once
+	seen := map[*Status]struct{}{}
+	for _, command := range p.Commands {
+		for _, task := range command {
+			if task == nil || task.Status == nil {
+				continue
+			}
+			if _, ok := seen[task.Status]; ok {
+				continue
+			}
+			seen[task.Status] = struct{}{}
+
+			task.Status.lock.Lock()
+			add(failed, "server", task.Status.FailedHosts.Servers, task.Status.Errors.Servers)
+			add(failed, "client", task.Status.FailedHosts.Clients, task.Status.Errors.Clients)
+			add(successful, "server", task.Status.SuccessfulHosts.Servers, nil)
+			add(successful, "client", task.Status.SuccessfulHosts.Clients, nil)
+			task.Status.lock.Unlock()
+		}
+	}
+
+	return sortedHostsStatus(failed), sortedHostsStatus(successful)
+}
+
+func sortedHostsStatus(list map[string]*HostStatus) []HostStatus {
+	keys := make([]string, 0, len(list))
+	for key := range list {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]HostStatus, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, *list[key])
+	}
+	return sorted
+}
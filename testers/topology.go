@@ -0,0 +1,116 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/galexrt/ancientt/pkg/config"
+)
+
+// ServerClients a server host and the client hosts to run against it
+type ServerClients struct {
+	Server  *Host
+	Clients []*Host
+}
+
+// GetServerClients return the list of servers with their clients for the given topology, sorted by server name.
+// An empty topology defaults to config.TopologyClientsToServers.
+func GetServerClients(hosts *Hosts, topology config.Topology) ([]ServerClients, error) {
+	servers := sortedHosts(hosts.Servers)
+	clients := sortedHosts(hosts.Clients)
+
+	list := []ServerClients{}
+	switch topology {
+	case "", config.TopologyClientsToServers:
+		for _, server := range servers {
+			if len(clients) == 0 {
+				continue
+			}
+			list = append(list, ServerClients{
+				Server:  server,
+				Clients: clients,
+			})
+		}
+
+	case config.TopologyFullMesh:
+		nodes := mergeHosts(servers, clients)
+		for _, server := range nodes {
+			entry := ServerClients{
+				Server: server,
+			}
+			for _, client := range nodes {
+				if client.Name != server.Name {
+					entry.Clients = append(entry.Clients, client)
+				}
+			}
+			if len(entry.Clients) > 0 {
+				list = append(list, entry)
+			}
+		}
+
+	case config.TopologyPairwise:
+		if len(servers) != len(clients) {
+			return nil, fmt.Errorf("pairwise topology requires the same amount of servers and clients, got %d servers and %d clients", len(servers), len(clients))
+		}
+		for i, server := range servers {
+			list = append(list, ServerClients{
+				Server:  server,
+				Clients: []*Host{clients[i]},
+			})
+		}
+
+	case config.TopologyRing:
+		nodes := mergeHosts(servers, clients)
+		if len(nodes) < 2 {
+			break
+		}
+		// Each host is the server for the host before it in the ring
+		for i, server := range nodes {
+			list = append(list, ServerClients{
+				Server:  server,
+				Clients: []*Host{nodes[(i+len(nodes)-1)%len(nodes)]},
+			})
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown topology %q given", topology)
+	}
+
+	return list, nil
+}
+
+// sortedHosts return the hosts of the map sorted by name
+func sortedHosts(hosts map[string]*Host) []*Host {
+	list := make([]*Host, 0, len(hosts))
+	for _, host := range hosts {
+		list = append(list, host)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// mergeHosts return the unique hosts of both lists sorted by name
+func mergeHosts(a []*Host, b []*Host) []*Host {
+	merged := map[string]*Host{}
+	for _, host := range append(append([]*Host{}, a...), b...) {
+		if _, ok := merged[host.Name]; !ok {
+			merged[host.Name] = host
+		}
+	}
+	return sortedHosts(merged)
+}
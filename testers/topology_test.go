@@ -0,0 +1,102 @@
+/*
+Copyright 2019 Cloudical Deutschland GmbH. All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testers
+
+import (
+	"testing"
+
+	"github.com/galexrt/ancientt/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHosts(servers []string, clients []string) *Hosts {
+	hosts := &Hosts{
+		Servers: map[string]*Host{},
+		Clients: map[string]*Host{},
+	}
+	for _, name := range servers {
+		hosts.Servers[name] = &Host{Name: name}
+	}
+	for _, name := range clients {
+		hosts.Clients[name] = &Host{Name: name}
+	}
+	return hosts
+}
+
+// getPairs return the server and client names for each pair of the list
+func getPairs(list []ServerClients) [][2]string {
+	pairs := [][2]string{}
+	for _, entry := range list {
+		for _, client := range entry.Clients {
+			pairs = append(pairs, [2]string{entry.Server.Name, client.Name})
+		}
+	}
+	return pairs
+}
+
+func TestGetServerClients(t *testing.T) {
+	hosts := newTestHosts([]string{"node2", "node1", "node3"}, []string{"node3", "node1", "node2"})
+
+	tests := map[config.Topology][][2]string{
+		"": {
+			{"node1", "node1"}, {"node1", "node2"}, {"node1", "node3"},
+			{"node2", "node1"}, {"node2", "node2"}, {"node2", "node3"},
+			{"node3", "node1"}, {"node3", "node2"}, {"node3", "node3"},
+		},
+		config.TopologyFullMesh: {
+			{"node1", "node2"}, {"node1", "node3"},
+			{"node2", "node1"}, {"node2", "node3"},
+			{"node3", "node1"}, {"node3", "node2"},
+		},
+		config.TopologyPairwise: {
+			{"node1", "node1"}, {"node2", "node2"}, {"node3", "node3"},
+		},
+		config.TopologyRing: {
+			{"node1", "node3"}, {"node2", "node1"}, {"node3", "node2"},
+		},
+	}
+	for topology, expected := range tests {
+		list, err := GetServerClients(hosts, topology)
+		require.Nil(t, err, topology)
+		assert.Equal(t, expected, getPairs(list), topology)
+	}
+}
+
+func TestGetServerClientsFullMeshMergesHosts(t *testing.T) {
+	hosts := newTestHosts([]string{"server1"}, []string{"client1", "client2"})
+
+	list, err := GetServerClients(hosts, config.TopologyFullMesh)
+	require.Nil(t, err)
+	assert.Equal(t, [][2]string{
+		{"client1", "client2"}, {"client1", "server1"},
+		{"client2", "client1"}, {"client2", "server1"},
+		{"server1", "client1"}, {"server1", "client2"},
+	}, getPairs(list))
+}
+
+func TestGetServerClientsErrors(t *testing.T) {
+	hosts := newTestHosts([]string{"server1"}, []string{"client1", "client2"})
+
+	_, err := GetServerClients(hosts, config.TopologyPairwise)
+	assert.NotNil(t, err)
+
+	_, err = GetServerClients(hosts, config.Topology("star"))
+	assert.NotNil(t, err)
+
+	// A ring needs at least two hosts
+	list, err := GetServerClients(newTestHosts([]string{"node1"}, []string{"node1"}), config.TopologyRing)
+	require.Nil(t, err)
+	assert.Len(t, list, 0)
+}